@@ -0,0 +1,63 @@
+package sqltestutil
+
+import "context"
+
+// PendingContainer is a Postgres container that is starting in the
+// background, see StartPostgresContainerAsync.
+type PendingContainer struct {
+	done chan struct{}
+	pg   *PostgresContainer
+	err  error
+}
+
+// StartPostgresContainerAsync starts a Postgres container like
+// StartPostgresContainer, but returns right away, so a suite can compile its
+// fixtures or do other setup while the container starts, and only block at
+// first use:
+//
+//	pending := sqltestutil.StartPostgresContainerAsync(ctx, "16")
+//	fixtures := compileFixtures()
+//	pg, err := pending.Wait(ctx)
+//	// handle err
+//	defer pg.Shutdown(ctx)
+//
+// Canceling ctx aborts the startup. Once the startup succeeded, the container
+// must be shut down as usual, even if it's never waited for.
+func StartPostgresContainerAsync(ctx context.Context, version string, options ...Option) *PendingContainer {
+	return startAsync(func() (*PostgresContainer, error) {
+		return StartPostgresContainer(ctx, version, options...)
+	})
+}
+
+func startAsync(start func() (*PostgresContainer, error)) *PendingContainer {
+	p := &PendingContainer{done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		p.pg, p.err = start()
+	}()
+	return p
+}
+
+// Ready returns a channel that receives nil once the container is healthy, or
+// the error of the startup. Every call returns a new channel, so any number of
+// goroutines can wait for the container.
+func (p *PendingContainer) Ready() <-chan error {
+	ready := make(chan error, 1)
+	go func() {
+		<-p.done
+		ready <- p.err
+	}()
+	return ready
+}
+
+// Wait blocks until the container is healthy and returns it, or returns the
+// error of the startup. If ctx is done first, ctx.Err() is returned and the
+// startup carries on.
+func (p *PendingContainer) Wait(ctx context.Context) (*PostgresContainer, error) {
+	select {
+	case <-p.done:
+		return p.pg, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
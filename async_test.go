@@ -0,0 +1,59 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPendingContainer(t *testing.T) {
+	t.Parallel()
+
+	errStart := errors.New("start failed")
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "healthy"},
+		{name: "failed", err: errStart, wantErr: errStart},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			release := make(chan struct{})
+			pending := startAsync(func() (*PostgresContainer, error) {
+				<-release
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &PostgresContainer{external: true}, nil
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := pending.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Wait() before ready error = %v, want %v", err, context.DeadlineExceeded)
+			}
+
+			first, second := pending.Ready(), pending.Ready()
+			close(release)
+			for _, ready := range []<-chan error{first, second} {
+				if err := <-ready; !errors.Is(err, tt.wantErr) {
+					t.Errorf("Ready() = %v, want %v", err, tt.wantErr)
+				}
+			}
+			pg, err := pending.Wait(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait() error = %v, want %v", err, tt.wantErr)
+			}
+			if (pg != nil) != (tt.wantErr == nil) {
+				t.Errorf("Wait() = %v, want a container: %v", pg, tt.wantErr == nil)
+			}
+		})
+	}
+}
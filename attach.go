@@ -0,0 +1,163 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// AttachPostgresContainer returns a PostgresContainer for a Postgres container
+// that was started by other means, e.g. by testcontainers-go or an
+// ory/dockertest pool, so that the helpers of this package (PrepareDatabase,
+// CopyToContainer, Maintain, etc.) can be used with it. The container must run
+// on the Docker daemon this package connects to and publish port 5432 on the
+// host.
+//
+// The user, password and database are read from the POSTGRES_USER,
+// POSTGRES_PASSWORD and POSTGRES_DB environment variables of the container,
+// with the defaults of the postgres image. WithDBUser, WithDBPassword and
+// WithDBName override them, e.g. for images configured differently.
+// WithExtensions and WithOnHealthy apply as well, the other container options
+// don't. For example, with testcontainers-go:
+//
+//	c, err := postgres.Run(ctx, "postgres:16",
+//	    postgres.WithUsername("app"),
+//	    postgres.WithPassword("secret"),
+//	    postgres.WithDatabase("app"),
+//	    postgres.BasicWaitStrategies(),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	pg, err := sqltestutil.AttachPostgresContainer(ctx, c.GetContainerID(),
+//	    sqltestutil.WithDBUser("app"),
+//	    sqltestutil.WithDBPassword("secret"),
+//	    sqltestutil.WithDBName("app"),
+//	)
+//
+// Or with ory/dockertest, where the credentials come from the environment:
+//
+//	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+//	    Repository: "postgres",
+//	    Tag:        "16",
+//	    Env:        []string{"POSTGRES_PASSWORD=secret"},
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	pg, err := sqltestutil.AttachPostgresContainer(ctx, resource.Container.ID)
+//
+// The container stays owned by whatever started it, which keeps cleaning it up
+// (e.g. through testcontainers' Ryuk reaper or pool.Purge), so Shutdown leaves
+// it running unless WithRemoveReused is passed.
+//
+// Attaching is how this package integrates with testcontainers-go: the
+// containers it starts itself, e.g. with StartPostgresContainer, are always
+// managed through the Docker API directly, as testcontainers-go isn't a
+// dependency of this module.
+func AttachPostgresContainer(ctx context.Context, containerID string, options ...Option) (*PostgresContainer, error) {
+	config := &PostgresContainerConfig{
+		TimeZone: "UTC",
+		SSLMode:  "disable",
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting container: %w", err)
+	}
+	var env []string
+	if inspect.Config != nil {
+		env = inspect.Config.Env
+	}
+	postgresEnvDefaults(config, env)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if inspect.NetworkSettings == nil {
+		return nil, fmt.Errorf("container %s has no network settings", containerID)
+	}
+	port, err := publishedPort(inspect.NetworkSettings.Ports, "5432/tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(waitTimeout))
+	defer cancel()
+
+	connStr := config.connectionString(containerHost(cli, config.HostOverride), port)
+	if err := waitUntilConnectable(waitCtx, connStr); err != nil {
+		return nil, err
+	}
+	serverVersion, err := queryServerVersion(waitCtx, connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	pg := &PostgresContainer{
+		id:       inspect.ID,
+		user:     config.DBUser,
+		password: config.DBPassword,
+		dbName:   config.DBName,
+		port:     port,
+		connStr:  connStr,
+		reused:   true,
+
+		serverVersion: serverVersion,
+		docker:        config.docker(),
+		profile:       config.Profile,
+		logger:        config.Logger,
+	}
+	if err := config.provision(ctx, waitCtx, pg); err != nil {
+		return nil, err
+	}
+	return pg, nil
+}
+
+// postgresEnvDefaults fills in the user, password and database that aren't
+// set in config from the environment variables a postgres container was
+// started with, falling back to the defaults of the postgres image.
+func postgresEnvDefaults(config *PostgresContainerConfig, env []string) {
+	vars := map[string]string{}
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	if config.DBUser == "" {
+		config.DBUser = vars["POSTGRES_USER"]
+	}
+	if config.DBUser == "" {
+		config.DBUser = "postgres"
+	}
+	if config.DBPassword == "" {
+		config.DBPassword = vars["POSTGRES_PASSWORD"]
+	}
+	if config.DBName == "" {
+		config.DBName = vars["POSTGRES_DB"]
+	}
+	if config.DBName == "" {
+		// the postgres image names the database after the user by default
+		config.DBName = config.DBUser
+	}
+}
+
+// publishedPort returns the host port that port is published on.
+func publishedPort(ports nat.PortMap, port nat.Port) (string, error) {
+	for _, binding := range ports[port] {
+		if binding.HostPort != "" {
+			return binding.HostPort, nil
+		}
+	}
+	return "", fmt.Errorf("port %s is not published on the host", port)
+}
@@ -0,0 +1,95 @@
+package sqltestutil
+
+import (
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func TestPublishedPort(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		ports   nat.PortMap
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "published",
+			ports: nat.PortMap{
+				"5432/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}, {HostIP: "::", HostPort: "49153"}},
+			},
+			want: "49153",
+		},
+		{
+			name:    "exposed only",
+			ports:   nat.PortMap{"5432/tcp": nil},
+			wantErr: true,
+		},
+		{
+			name:    "other port",
+			ports:   nat.PortMap{"8080/tcp": []nat.PortBinding{{HostPort: "8080"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := publishedPort(tt.ports, "5432/tcp")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publishedPort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("publishedPort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresEnvDefaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config PostgresContainerConfig
+		env    []string
+		want   PostgresContainerConfig
+	}{
+		{
+			name: "image defaults",
+			env:  []string{"POSTGRES_PASSWORD=secret", "PATH=/usr/bin"},
+			want: PostgresContainerConfig{DBUser: "postgres", DBPassword: "secret", DBName: "postgres"},
+		},
+		{
+			name: "database named after user",
+			env:  []string{"POSTGRES_USER=app", "POSTGRES_PASSWORD=secret"},
+			want: PostgresContainerConfig{DBUser: "app", DBPassword: "secret", DBName: "app"},
+		},
+		{
+			name: "all set",
+			env:  []string{"POSTGRES_USER=app", "POSTGRES_PASSWORD=secret", "POSTGRES_DB=appdb"},
+			want: PostgresContainerConfig{DBUser: "app", DBPassword: "secret", DBName: "appdb"},
+		},
+		{
+			name:   "options win",
+			config: PostgresContainerConfig{DBUser: "admin", DBPassword: "hunter2"},
+			env:    []string{"POSTGRES_USER=app", "POSTGRES_PASSWORD=secret", "POSTGRES_DB=appdb"},
+			want:   PostgresContainerConfig{DBUser: "admin", DBPassword: "hunter2", DBName: "appdb"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := tt.config
+			postgresEnvDefaults(&config, tt.env)
+			if config.DBUser != tt.want.DBUser || config.DBPassword != tt.want.DBPassword || config.DBName != tt.want.DBName {
+				t.Errorf("postgresEnvDefaults() = %q/%q/%q, want %q/%q/%q",
+					config.DBUser, config.DBPassword, config.DBName,
+					tt.want.DBUser, tt.want.DBPassword, tt.want.DBName)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Open returns a connection pool to the container's database using the pgx
+// driver. Unlike opening ConnectionString directly, it authenticates with the
+// PasswordFunc the server was connected to with, if any, so it also works for
+// servers that use IAM or Kerberos tokens instead of a fixed password.
+func (c *PostgresContainer) Open() (*sql.DB, error) {
+	return c.open(c.ConnectionString())
+}
+
+// open returns a connection pool for connStr, a connection string of the
+// container's server, authenticating with c.passwordFunc if set.
+func (c *PostgresContainer) open(connStr string) (*sql.DB, error) {
+	return openPostgres(connStr, c.passwordFunc)
+}
+
+// openPostgres returns a connection pool for connStr. If passwordFunc is set,
+// it's called before every new connection and the password it returns
+// replaces the one in connStr.
+func openPostgres(connStr string, passwordFunc func(ctx context.Context) (string, error)) (*sql.DB, error) {
+	if passwordFunc == nil {
+		return sql.Open("pgx", connStr)
+	}
+	config, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse connection string error: %w", err)
+	}
+	return stdlib.OpenDB(*config, stdlib.OptionBeforeConnect(passwordBeforeConnect(passwordFunc))), nil
+}
+
+// passwordBeforeConnect returns a pgx BeforeConnect hook that sets the
+// password of each new connection from passwordFunc.
+func passwordBeforeConnect(passwordFunc func(ctx context.Context) (string, error)) func(context.Context, *pgx.ConnConfig) error {
+	return func(ctx context.Context, config *pgx.ConnConfig) error {
+		password, err := passwordFunc(ctx)
+		if err != nil {
+			return fmt.Errorf("password func error: %w", err)
+		}
+		config.Password = password
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// The labels every container started by the package carries.
+const (
+	// packageLabel marks the container as started by this package.
+	packageLabel = "sqltestutil"
+	// sessionLabel holds the ID of the test process, see WithReaper.
+	sessionLabel = "sqltestutil.session"
+	// startedLabel holds the time the container was created, in RFC 3339
+	// format.
+	startedLabel = "sqltestutil.started"
+)
+
+// containerLabels returns the labels of a container this process creates now.
+func containerLabels() map[string]string {
+	return map[string]string{
+		packageLabel: "true",
+		sessionLabel: sessionID(),
+		startedLabel: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// CleanupOrphans force-removes the containers started by this package, by any
+// test process, that were created more than olderThan ago, together with
+// their anonymous volumes, and returns how many it removed. Containers of the
+// calling process and those started with WithReuse are left alone. It sweeps
+// up after runs that crashed before calling Shutdown, and is meant to be
+// called from TestMain or a scheduled CI job:
+//
+//	if _, err := sqltestutil.CleanupOrphans(ctx, time.Hour); err != nil {
+//	    fmt.Println("could not clean up orphaned containers:", err)
+//	}
+//
+// olderThan should exceed the longest test run that may share the Docker
+// daemon, or the containers of a concurrent run are removed. Of the options
+// only WithDockerClientOpts and WithDockerClient apply.
+func CleanupOrphans(ctx context.Context, olderThan time.Duration, options ...Option) (int, error) {
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	start := time.Now()
+	removed, err := cleanupOrphans(ctx, cli, olderThan)
+	emit(Event{Type: EventCleanup, Count: removed, Duration: time.Since(start), Err: err})
+	return removed, err
+}
+
+func cleanupOrphans(ctx context.Context, cli *client.Client, olderThan time.Duration) (int, error) {
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", packageLabel)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list containers error: %w", err)
+	}
+
+	removed := 0
+	var errs []error
+	for _, id := range orphans(containers, sessionID(), time.Now().Add(-olderThan)) {
+		err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("remove container %s error: %w", id, err))
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// orphans returns the IDs of the containers that belong to another session
+// than session and were created before cutoff.
+func orphans(containers []types.Container, session string, cutoff time.Time) []string {
+	var ids []string
+	for _, c := range containers {
+		if c.Labels[sessionLabel] == session || c.Labels[reuseLabel] != "" {
+			continue
+		}
+		if containerStarted(c).Before(cutoff) {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// containerStarted returns the time a container was created according to its
+// label, or to Docker for containers without one.
+func containerStarted(c types.Container) time.Time {
+	if started, err := time.Parse(time.RFC3339, c.Labels[startedLabel]); err == nil {
+		return started
+	}
+	return time.Unix(c.Created, 0)
+}
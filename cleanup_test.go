@@ -0,0 +1,31 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestOrphans(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	recent := now.Add(-time.Minute).Format(time.RFC3339)
+	containers := []types.Container{
+		{ID: "old", Labels: map[string]string{packageLabel: "true", sessionLabel: "a", startedLabel: old}},
+		{ID: "recent", Labels: map[string]string{packageLabel: "true", sessionLabel: "a", startedLabel: recent}},
+		{ID: "own", Labels: map[string]string{packageLabel: "true", sessionLabel: "self", startedLabel: old}},
+		{ID: "unlabeled old", Labels: map[string]string{packageLabel: "true"}, Created: now.Add(-3 * time.Hour).Unix()},
+		{ID: "unlabeled recent", Labels: map[string]string{packageLabel: "true"}, Created: now.Unix()},
+		{ID: "reused", Labels: map[string]string{packageLabel: "true", reuseLabel: "dev", startedLabel: old}},
+	}
+
+	got := orphans(containers, "self", now.Add(-time.Hour))
+	want := []string{"old", "unlabeled old"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans() = %v, want %v", got, want)
+	}
+}
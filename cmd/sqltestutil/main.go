@@ -0,0 +1,218 @@
+// Command sqltestutil provides command line access to the tooling of the
+// sqltestutil package, so it can be used from CI scripts and Makefiles.
+//
+// Usage:
+//
+//	sqltestutil lint (-dsn <connection string> | -schema <snapshot>) <scenario file>...
+//	sqltestutil snapshot -dsn <connection string> -o <snapshot>
+//	sqltestutil graph [-dsn <connection string> | -schema <snapshot>] <scenario file>
+//	sqltestutil migrations -dsn <connection string>
+//	sqltestutil gen (-dsn <connection string> | -schema <snapshot>) [-pkg <name>] [-o <file>]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/buildpeak/sqltestutil"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// errFindings is returned by a command whose checks failed. The findings have
+// already been reported, so only the exit code is affected.
+var errFindings = errors.New("findings reported")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "lint":
+		err = lint(context.Background(), os.Args[2:])
+	case "snapshot":
+		err = snapshot(context.Background(), os.Args[2:])
+	case "graph":
+		err = graph(context.Background(), os.Args[2:])
+	case "migrations":
+		err = migrations(context.Background(), os.Args[2:])
+	case "gen":
+		err = gen(context.Background(), os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if errors.Is(err, errFindings) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: sqltestutil <command> [flags] [args]
+
+commands:
+  lint        check scenario files against a database schema
+  snapshot    save a database schema snapshot for offline linting
+  graph       print a Graphviz graph of the rows in a scenario file
+  gen         generate Go fixture factories from a database schema
+  migrations  list the migrations applied to a database`)
+}
+
+func lint(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	dsn := flags.String("dsn", "", "connection string of a database with the current schema")
+	schemaPath := flags.String("schema", "", "schema snapshot to use instead of a database")
+	_ = flags.Parse(args)
+
+	if (*dsn == "") == (*schemaPath == "") || flags.NArg() == 0 {
+		return errors.New("lint requires one of -dsn or -schema and at least one scenario file")
+	}
+
+	schema, err := loadSchema(ctx, *dsn, *schemaPath)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, filename := range flags.Args() {
+		for _, finding := range sqltestutil.LintScenario(filename, schema) {
+			fmt.Printf("%s: %s\n", filename, finding)
+			failed = true
+		}
+	}
+	if failed {
+		return errFindings
+	}
+	return nil
+}
+
+func snapshot(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dsn := flags.String("dsn", "", "connection string of a database with the current schema")
+	out := flags.String("o", "schema.json", "path of the snapshot to write")
+	_ = flags.Parse(args)
+
+	if *dsn == "" {
+		return errors.New("snapshot requires -dsn")
+	}
+
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return sqltestutil.SaveSchemaSnapshot(ctx, db, *out)
+}
+
+func graph(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	dsn := flags.String("dsn", "", "connection string of a database to take foreign keys from")
+	schemaPath := flags.String("schema", "", "schema snapshot to take foreign keys from")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("graph requires exactly one scenario file")
+	}
+
+	var options []sqltestutil.GraphOption
+	if *dsn != "" || *schemaPath != "" {
+		schema, err := loadSchema(ctx, *dsn, *schemaPath)
+		if err != nil {
+			return err
+		}
+		options = append(options, sqltestutil.WithGraphSchema(schema))
+	}
+
+	dot, err := sqltestutil.ScenarioGraph(flags.Arg(0), options...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(dot)
+	return nil
+}
+
+func migrations(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrations", flag.ExitOnError)
+	dsn := flags.String("dsn", "", "connection string of the database")
+	_ = flags.Parse(args)
+
+	if *dsn == "" {
+		return errors.New("migrations requires -dsn")
+	}
+
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := sqltestutil.AppliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tFILENAME\tAPPLIED AT\tDURATION\tCHECKSUM")
+	for _, m := range applied {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.12s\n", m.Version, m.Filename, m.AppliedAt.Format(time.RFC3339), m.Duration, m.Checksum)
+	}
+	return w.Flush()
+}
+
+func gen(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	dsn := flags.String("dsn", "", "connection string of a database with the current schema")
+	schemaPath := flags.String("schema", "", "schema snapshot to use instead of a database")
+	pkg := flags.String("pkg", "fixtures", "package name of the generated file")
+	out := flags.String("o", "", "path of the file to write, standard output if empty")
+	_ = flags.Parse(args)
+
+	if (*dsn == "") == (*schemaPath == "") {
+		return errors.New("gen requires one of -dsn or -schema")
+	}
+
+	schema, err := loadSchema(ctx, *dsn, *schemaPath)
+	if err != nil {
+		return err
+	}
+	src, err := sqltestutil.GenerateFactories(schema, *pkg)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+// loadSchema reads the schema from the snapshot at schemaPath if given, and
+// otherwise inspects the database at dsn.
+func loadSchema(ctx context.Context, dsn, schemaPath string) (*sqltestutil.Schema, error) {
+	if schemaPath != "" {
+		return sqltestutil.LoadSchemaSnapshot(schemaPath)
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return sqltestutil.InspectSchema(ctx, db)
+}
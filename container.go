@@ -0,0 +1,390 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+)
+
+// containerSpec describes a database container other than Postgres, which is
+// handled by PostgresContainerBuilder.
+type containerSpec struct {
+	image string
+	env   []string
+	cmd   []string
+	// port is the container port to publish on the host, e.g. "3306/tcp".
+	port        nat.Port
+	healthcheck *container.HealthConfig
+	// timeout bounds the wait for the healthcheck.
+	timeout time.Duration
+	pull    imagePull
+	// reaper starts the reaper first, see WithReaper.
+	reaper bool
+}
+
+// defaultDockerSocket is where a rootful Docker daemon listens.
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// newDockerClient returns a Docker client configured from the environment that
+// negotiates the API version with the daemon, so that older daemons work too,
+// followed by opts. When DOCKER_HOST isn't set and there's no daemon at the
+// default socket, the socket of a rootless daemon or of Podman is used if there
+// is one.
+func newDockerClient(opts []client.Opt) (*client.Client, error) {
+	defaults := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	}
+	if socket := detectDockerSocket(os.Getenv, fileExists); socket != "" {
+		defaults = append(defaults, client.WithHost("unix://"+socket))
+	}
+	return client.NewClientWithOpts(append(defaults, opts...)...)
+}
+
+// dockerConfig is how the Docker clients for a container are obtained: the
+// client injected with WithDockerClient, or new ones configured with opts.
+type dockerConfig struct {
+	client *client.Client
+	opts   []client.Opt
+}
+
+// open returns a Docker client and a function that releases it when done.
+// An injected client is returned as is and not closed on release, since it
+// belongs to the caller.
+func (d dockerConfig) open() (*client.Client, func() error, error) {
+	if d.client != nil {
+		return d.client, func() error { return nil }, nil
+	}
+	cli, err := newDockerClient(d.opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create docker client error: %w", err)
+	}
+	return cli, cli.Close, nil
+}
+
+// detectDockerSocket returns the first of dockerSocketCandidates that exists,
+// if DOCKER_HOST isn't set and the default socket doesn't exist. It returns an
+// empty string to keep the client's default.
+func detectDockerSocket(getenv func(string) string, exists func(string) bool) string {
+	if getenv("DOCKER_HOST") != "" || exists(defaultDockerSocket) {
+		return ""
+	}
+	for _, candidate := range dockerSocketCandidates(getenv) {
+		if exists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// dockerSocketCandidates returns the sockets a Docker compatible daemon
+// commonly listens on besides the default one, in order of preference: a
+// rootless Docker daemon, Docker Desktop, the macOS VMs (Colima, Rancher
+// Desktop, Lima) and finally Podman.
+func dockerSocketCandidates(getenv func(string) string) []string {
+	var candidates []string
+	runtimeDir := getenv("XDG_RUNTIME_DIR")
+	home := getenv("HOME")
+	uid := os.Getuid()
+
+	if runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "docker.sock"))
+	}
+	if uid >= 0 {
+		candidates = append(candidates, fmt.Sprintf("/run/user/%d/docker.sock", uid))
+	}
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".colima", "default", "docker.sock"),
+			filepath.Join(home, ".colima", "docker.sock"),
+			filepath.Join(home, ".rd", "docker.sock"),
+			filepath.Join(home, ".lima", "default", "sock", "docker.sock"),
+			filepath.Join(home, ".lima", "docker", "sock", "docker.sock"),
+		)
+	}
+	// Podman serves a Docker compatible API on its own socket.
+	if runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	if uid >= 0 {
+		candidates = append(candidates, fmt.Sprintf("/run/user/%d/podman/podman.sock", uid))
+	}
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "podman.sock"),
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "qemu", "podman.sock"),
+		)
+	}
+	candidates = append(candidates, "/run/podman/podman.sock")
+	return candidates
+}
+
+// daemonHint explains how to make the Docker daemon reachable, for errors
+// from a client that couldn't connect to it.
+func daemonHint(getenv func(string) string) string {
+	if host := getenv("DOCKER_HOST"); host != "" {
+		return fmt.Sprintf("check that DOCKER_HOST=%s points to a running daemon", host)
+	}
+	return fmt.Sprintf(
+		"no Docker socket found at %s or %s; start Docker, Colima, Rancher Desktop or Podman, or set DOCKER_HOST",
+		defaultDockerSocket,
+		strings.Join(dockerSocketCandidates(getenv), ", "),
+	)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ensureImage pulls the image as the pull policy requires: if it isn't
+// already cached locally by default, always for PullAlways, and never for
+// PullNever, which fails if the image is missing.
+func ensureImage(ctx context.Context, cli *client.Client, image string, pull imagePull) error {
+	platform, err := parsePlatform(pull.platform)
+	if err != nil {
+		return err
+	}
+	if pull.policy != PullAlways {
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+		if err == nil && matchesPlatform(platform, inspect.Os, inspect.Architecture) {
+			return nil
+		}
+		if client.IsErrConnectionFailed(err) {
+			return fmt.Errorf("%w; %s", err, daemonHint(os.Getenv))
+		}
+		_, notFound := err.(interface {
+			NotFound()
+		})
+		if err != nil && !notFound {
+			return err
+		}
+		if pull.policy == PullNever {
+			if err == nil {
+				return fmt.Errorf("image %s is not present locally for platform %s and the pull policy is Never", image, pull.platform)
+			}
+			return fmt.Errorf("image %s is not present locally and the pull policy is Never", image)
+		}
+	}
+	auth, err := pull.registryAuth(image)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = pullImage(ctx, cli, image, auth, pull)
+	emitTo(pull.logger, Event{Type: EventImagePulled, Image: image, Duration: time.Since(start), Err: err})
+	return err
+}
+
+func pullImage(ctx context.Context, cli *client.Client, image, auth string, pull imagePull) error {
+	pullReader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{
+		RegistryAuth: auth,
+		Platform:     pull.platform,
+	})
+	if err != nil {
+		return err
+	}
+	defer pullReader.Close()
+	return readPullProgress(pullReader, pull.progress)
+}
+
+// runContainer pulls the image of spec if needed, then creates and starts a
+// container from it with spec.port published on a host port Docker assigns,
+// which it returns. If spec has a healthcheck, it waits until the container is
+// healthy. The container is stopped and removed again if anything goes wrong.
+func runContainer(ctx context.Context, cli *client.Client, spec containerSpec) (containerID, hostPort string, err error) {
+	if err := ensureImage(ctx, cli, spec.image, spec.pull); err != nil {
+		return "", "", err
+	}
+	if spec.reaper {
+		if err := ensureReaper(ctx, cli, containerHost(cli, "")); err != nil {
+			return "", "", err
+		}
+	}
+	start := time.Now()
+
+	createResp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:       spec.image,
+		Env:         spec.env,
+		Cmd:         spec.cmd,
+		Healthcheck: spec.healthcheck,
+		Labels:      containerLabels(),
+		ExposedPorts: nat.PortSet{
+			spec.port: struct{}{},
+		},
+	}, &container.HostConfig{
+		PortBindings: nat.PortMap{
+			// an empty host port makes Docker assign a free one
+			spec.port: []nat.PortBinding{
+				{},
+			},
+		},
+	}, nil, nil, "")
+	emit(Event{Type: EventContainerCreated, ContainerID: createResp.ID, Image: spec.image, Duration: time.Since(start), Err: err})
+	if err != nil {
+		return "", "", err
+	}
+
+	defer func() {
+		// remove the container if there's an error
+		if err != nil {
+			removeErr := cli.ContainerRemove(ctx, createResp.ID, types.ContainerRemoveOptions{Force: true})
+			if removeErr != nil {
+				slog.Default().Error("could not remove container", "container_id", createResp.ID, "err", removeErr)
+			}
+		}
+	}()
+
+	start = time.Now()
+	err = cli.ContainerStart(ctx, createResp.ID, types.ContainerStartOptions{})
+	emit(Event{Type: EventContainerStarted, ContainerID: createResp.ID, Image: spec.image, Duration: time.Since(start), Err: err})
+	if err != nil {
+		return "", "", err
+	}
+
+	if spec.healthcheck != nil {
+		waitCtx, cancel := context.WithTimeout(ctx, spec.timeout)
+		defer cancel()
+
+		start = time.Now()
+		err = waitUntilHealthy(waitCtx, cli, createResp.ID, nil)
+		emit(Event{Type: EventContainerReady, ContainerID: createResp.ID, Image: spec.image, Duration: time.Since(start), Err: err})
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	hostPort, err = publishedHostPort(ctx, cli, createResp.ID, spec.port)
+	if err != nil {
+		return "", "", err
+	}
+	return createResp.ID, hostPort, nil
+}
+
+// publishedHostPort returns the host port Docker published port of the
+// container on.
+func publishedHostPort(ctx context.Context, cli *client.Client, containerID string, port nat.Port) (string, error) {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("inspect container error: %w", err)
+	}
+	if inspect.NetworkSettings == nil {
+		return "", fmt.Errorf("container %s has no network settings", containerID)
+	}
+	return publishedPort(inspect.NetworkSettings.Ports, port)
+}
+
+type shutdownConfig struct {
+	removeVolumes bool
+	force         bool
+	timeout       *time.Duration
+	removeReused  bool
+	logger        *slog.Logger
+}
+
+func shutdownOptions(options []ShutdownOption) *shutdownConfig {
+	config := &shutdownConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// ShutdownOption configures Shutdown.
+type ShutdownOption func(*shutdownConfig)
+
+// WithRemoveVolumes makes Shutdown also remove the anonymous volumes of the
+// container, such as the one the postgres image declares for its data
+// directory. Without it they're left behind in Docker.
+func WithRemoveVolumes() ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.removeVolumes = true
+	}
+}
+
+// WithForce makes Shutdown kill and remove the container right away instead of
+// stopping it gracefully first.
+func WithForce() ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.force = true
+	}
+}
+
+// WithStopTimeout sets how long Shutdown waits for the container to stop
+// gracefully before it's killed. The default is Docker's own, 10 seconds.
+func WithStopTimeout(timeout time.Duration) ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.timeout = &timeout
+	}
+}
+
+// WithRemoveReused makes Shutdown remove a container started with WithReuse or
+// attached to with AttachPostgresContainer, which it leaves running otherwise.
+func WithRemoveReused() ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.removeReused = true
+	}
+}
+
+// withShutdownLogger makes Shutdown log with logger, see WithLogger.
+func withShutdownLogger(logger *slog.Logger) ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.logger = logger
+	}
+}
+
+// shutdownContainer stops and removes a container.
+func shutdownContainer(ctx context.Context, docker dockerConfig, containerID string, options ...ShutdownOption) error {
+	start := time.Now()
+	config := shutdownOptions(options)
+	err := stopAndRemove(ctx, docker, containerID, config)
+	emitTo(config.logger, Event{Type: EventContainerShutdown, ContainerID: containerID, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// stopAndRemove stops the container, unless forced, and removes it.
+func stopAndRemove(ctx context.Context, docker dockerConfig, containerID string, config *shutdownConfig) error {
+	cli, release, err := docker.open()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if !config.force {
+		err = cli.ContainerStop(ctx, containerID, config.timeout)
+		if err != nil {
+			return ignoreRemoved(err)
+		}
+	}
+	err = cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
+		RemoveVolumes: config.removeVolumes,
+		Force:         config.force,
+	})
+	if err != nil {
+		return ignoreRemoved(err)
+	}
+	return nil
+}
+
+// ignoreRemoved returns nil for the errors Docker answers with when the
+// container is already gone or being removed, as happens with WithAutoRemove,
+// and err otherwise.
+func ignoreRemoved(err error) error {
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	if errdefs.IsConflict(err) && strings.Contains(err.Error(), "already in progress") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package sqltestutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+)
+
+func TestDetectSocket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		existing []string
+		want     string
+	}{
+		{
+			name:     "rootful",
+			env:      map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			existing: []string{defaultDockerSocket, "/run/user/1000/docker.sock"},
+		},
+		{
+			name:     "DOCKER_HOST set",
+			env:      map[string]string{"DOCKER_HOST": "tcp://127.0.0.1:2375", "XDG_RUNTIME_DIR": "/run/user/1000"},
+			existing: []string{"/run/user/1000/docker.sock"},
+		},
+		{
+			name:     "rootless",
+			env:      map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			existing: []string{"/run/user/1000/docker.sock"},
+			want:     "/run/user/1000/docker.sock",
+		},
+		{
+			name:     "docker desktop",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.docker/run/docker.sock"},
+			want:     "/Users/dev/.docker/run/docker.sock",
+		},
+		{
+			name:     "colima",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.colima/default/docker.sock"},
+			want:     "/Users/dev/.colima/default/docker.sock",
+		},
+		{
+			name:     "rancher desktop",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.rd/docker.sock"},
+			want:     "/Users/dev/.rd/docker.sock",
+		},
+		{
+			name:     "lima",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.lima/docker/sock/docker.sock"},
+			want:     "/Users/dev/.lima/docker/sock/docker.sock",
+		},
+		{
+			name:     "podman machine",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.local/share/containers/podman/machine/podman.sock"},
+			want:     "/Users/dev/.local/share/containers/podman/machine/podman.sock",
+		},
+		{
+			name:     "docker desktop preferred over colima",
+			env:      map[string]string{"HOME": "/Users/dev"},
+			existing: []string{"/Users/dev/.colima/default/docker.sock", "/Users/dev/.docker/run/docker.sock"},
+			want:     "/Users/dev/.docker/run/docker.sock",
+		},
+		{
+			name:     "podman",
+			env:      map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			existing: []string{"/run/user/1000/podman/podman.sock"},
+			want:     "/run/user/1000/podman/podman.sock",
+		},
+		{
+			name:     "docker preferred over podman",
+			env:      map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			existing: []string{"/run/user/1000/podman/podman.sock", "/run/user/1000/docker.sock"},
+			want:     "/run/user/1000/docker.sock",
+		},
+		{
+			name: "nothing found",
+			env:  map[string]string{"HOME": "/home/dev"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			exists := func(path string) bool {
+				for _, e := range tt.existing {
+					if e == path {
+						return true
+					}
+				}
+				return false
+			}
+			getenv := func(key string) string {
+				return tt.env[key]
+			}
+			if got := detectDockerSocket(getenv, exists); got != tt.want {
+				t.Errorf("detectDockerSocket() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonHint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "DOCKER_HOST set",
+			env:  map[string]string{"DOCKER_HOST": "tcp://10.0.0.5:2375"},
+			want: "DOCKER_HOST=tcp://10.0.0.5:2375",
+		},
+		{
+			name: "lists sockets",
+			env:  map[string]string{"HOME": "/Users/dev"},
+			want: "/Users/dev/.colima/default/docker.sock",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			getenv := func(key string) string {
+				return tt.env[key]
+			}
+			if got := daemonHint(getenv); !strings.Contains(got, tt.want) {
+				t.Errorf("daemonHint() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreRemoved(t *testing.T) {
+	t.Parallel()
+
+	other := errors.New("connection refused")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "nil"},
+		{name: "not found", err: errdefs.NotFound(fmt.Errorf("No such container: abc"))},
+		{name: "removal in progress", err: errdefs.Conflict(fmt.Errorf("removal of container abc is already in progress"))},
+		{name: "other conflict", err: errdefs.Conflict(other), want: other},
+		{name: "other", err: other, want: other},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := ignoreRemoved(tt.err); !errors.Is(got, tt.want) {
+				t.Errorf("ignoreRemoved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
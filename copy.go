@@ -0,0 +1,176 @@
+package sqltestutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// CopyToContainer writes the contents of r to dstPath inside the container.
+// The file is created with mode 0644; use CopyFileToContainer to keep the mode
+// of a local file.
+func (c *PostgresContainer) CopyToContainer(ctx context.Context, r io.Reader, dstPath string) error {
+	if c.pod != nil {
+		return errPod
+	}
+	if c.external {
+		return errExternal
+	}
+	cli, release, err := c.docker.open()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return copyToContainer(ctx, cli, c.id, r, dstPath, 0o644)
+}
+
+// CopyFileToContainer copies the local file at srcPath to dstPath inside the
+// container.
+func (c *PostgresContainer) CopyFileToContainer(ctx context.Context, srcPath, dstPath string) error {
+	if c.pod != nil {
+		return errPod
+	}
+	if c.external {
+		return errExternal
+	}
+	cli, release, err := c.docker.open()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return copyFileToContainer(ctx, cli, c.id, srcPath, dstPath)
+}
+
+// CopyFromContainer writes the contents of the file at srcPath inside the
+// container to w.
+func (c *PostgresContainer) CopyFromContainer(ctx context.Context, srcPath string, w io.Writer) error {
+	if c.pod != nil {
+		return errPod
+	}
+	if c.external {
+		return errExternal
+	}
+	cli, release, err := c.docker.open()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return copyFromContainer(ctx, cli, c.id, srcPath, w)
+}
+
+// CopyToContainer writes the contents of r to dstPath inside the created
+// container. This is typically used between CreateContainer and Start, e.g.
+// to provide certificates or configuration files.
+func (b *PostgresContainerBuilder) CopyToContainer(ctx context.Context, r io.Reader, dstPath string) error {
+	if b.containerID == "" {
+		return errors.New("container not created")
+	}
+	return copyToContainer(ctx, b.cli, b.containerID, r, dstPath, 0o644)
+}
+
+// CopyFileToContainer copies the local file at srcPath to dstPath inside the
+// created container.
+func (b *PostgresContainerBuilder) CopyFileToContainer(ctx context.Context, srcPath, dstPath string) error {
+	if b.containerID == "" {
+		return errors.New("container not created")
+	}
+	return copyFileToContainer(ctx, b.cli, b.containerID, srcPath, dstPath)
+}
+
+func copyFileToContainer(ctx context.Context, cli *client.Client, containerID, srcPath, dstPath string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: is a directory", srcPath)
+	}
+	return copyToContainer(ctx, cli, containerID, f, dstPath, info.Mode().Perm())
+}
+
+// copyToContainer wraps the contents of r in a single-file tar archive, which
+// is what the Docker copy API expects, and extracts it at dstPath.
+func copyToContainer(
+	ctx context.Context,
+	cli *client.Client,
+	containerID string,
+	r io.Reader,
+	dstPath string,
+	mode fs.FileMode,
+) error {
+	archive, err := tarFile(r, path.Base(dstPath), mode)
+	if err != nil {
+		return err
+	}
+	err = cli.CopyToContainer(ctx, containerID, path.Dir(dstPath), archive, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("copy to container error: %w", err)
+	}
+	return nil
+}
+
+// tarFile returns a tar archive holding the contents of r as a single file
+// with the given name and mode.
+func tarFile(r io.Reader, name string, mode fs.FileMode) (*bytes.Buffer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	err = tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: int64(mode),
+		Size: int64(len(data)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+func copyFromContainer(ctx context.Context, cli *client.Client, containerID, srcPath string, w io.Writer) error {
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, srcPath)
+	if err != nil {
+		return fmt.Errorf("copy from container error: %w", err)
+	}
+	defer reader.Close()
+	return untarFile(reader, srcPath, w)
+}
+
+// untarFile writes the contents of the first entry of the tar archive in r,
+// which must be the regular file srcPath, to w.
+func untarFile(r io.Reader, srcPath string, w io.Writer) error {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("read archive error: %w", err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return fmt.Errorf("%s: not a regular file", srcPath)
+	}
+	_, err = io.Copy(w, tr)
+	return err
+}
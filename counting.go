@@ -0,0 +1,309 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// QueryStats are the statements executed through a CountingDB.
+type QueryStats struct {
+	// Queries is the number of statements executed, including each execution
+	// of a prepared statement.
+	Queries int
+	// Transactions is the number of transactions begun.
+	Transactions int
+	// Statements holds the SQL of the executed statements, in order.
+	Statements []string
+}
+
+// CountingDB is a *sql.DB that counts the statements executed through it, see
+// CountQueries.
+type CountingDB struct {
+	*sql.DB
+}
+
+// CountQueries returns a CountingDB that executes statements on connections
+// of db and counts them, and a function that returns the counts so far. The
+// CountingDB is a regular *sql.DB, so it can be handed to the code under test,
+// including ORMs, to guard code paths against N+1 query regressions:
+//
+//	counting, stats := sqltestutil.CountQueries(db)
+//	defer counting.Close()
+//	_, err := store.ListOrdersWithItems(ctx, counting.DB)
+//	// handle err
+//	sqltestutil.AssertMaxQueries(t, stats(), 2)
+//
+// Closing the CountingDB returns its connections to db but doesn't close db.
+func CountQueries(db *sql.DB) (*CountingDB, func() QueryStats) {
+	counter := &queryCounter{}
+	counting := &CountingDB{
+		DB: sql.OpenDB(&countingConnector{db: db, counter: counter}),
+	}
+	return counting, counter.stats
+}
+
+// AssertMaxQueries fails t if more than n statements were executed, listing
+// them.
+func AssertMaxQueries(t testing.TB, stats QueryStats, n int) {
+	t.Helper()
+
+	if stats.Queries > n {
+		t.Errorf(
+			"executed %d queries, want at most %d:\n\t%s",
+			stats.Queries,
+			n,
+			strings.Join(stats.Statements, "\n\t"),
+		)
+	}
+}
+
+type queryCounter struct {
+	mu sync.Mutex
+	QueryStats
+}
+
+func (c *queryCounter) query(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Queries++
+	c.Statements = append(c.Statements, query)
+}
+
+func (c *queryCounter) transaction() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Transactions++
+}
+
+func (c *queryCounter) stats() QueryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.QueryStats
+	stats.Statements = append([]string(nil), c.Statements...)
+	return stats
+}
+
+// countingConnector hands out connections of db that count the statements
+// executed on them.
+type countingConnector struct {
+	db      *sql.DB
+	counter *queryCounter
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn: conn, counter: c.counter}, nil
+}
+
+func (c *countingConnector) Driver() driver.Driver {
+	return countingDriver{}
+}
+
+type countingDriver struct{}
+
+func (countingDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("counting driver must be used through CountQueries")
+}
+
+// sqlRunner is implemented by *sql.Conn and *sql.Tx.
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type countingConn struct {
+	conn    *sql.Conn
+	counter *queryCounter
+	// tx is the transaction in progress, if any, which statements must go
+	// through.
+	tx *sql.Tx
+}
+
+// runner returns what statements are executed on.
+func (c *countingConn) runner() sqlRunner {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.conn
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.runner().PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &countingStmt{stmt: stmt, query: query, counter: c.counter}, nil
+}
+
+func (c *countingConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *countingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.conn.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.IsolationLevel(opts.Isolation),
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	c.counter.transaction()
+	return countingTx{conn: c}, nil
+}
+
+func (c *countingConn) PingContext(ctx context.Context) error {
+	return c.conn.PingContext(ctx)
+}
+
+// CheckNamedValue passes arguments through unchanged, so that the wrapped
+// driver converts them as it would without the CountingDB.
+func (c *countingConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.counter.query(query)
+	return c.runner().ExecContext(ctx, query, namedValueArgs(args)...)
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.counter.query(query)
+	rows, err := c.runner().QueryContext(ctx, query, namedValueArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return newCountingRows(rows)
+}
+
+type countingTx struct {
+	conn *countingConn
+}
+
+func (t countingTx) Commit() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Commit()
+}
+
+func (t countingTx) Rollback() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Rollback()
+}
+
+type countingStmt struct {
+	stmt    *sql.Stmt
+	query   string
+	counter *queryCounter
+}
+
+func (s *countingStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *countingStmt) NumInput() int {
+	return -1
+}
+
+func (s *countingStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("Exec is replaced by ExecContext")
+}
+
+func (s *countingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("Query is replaced by QueryContext")
+}
+
+func (s *countingStmt) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (s *countingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	s.counter.query(s.query)
+	return s.stmt.ExecContext(ctx, namedValueArgs(args)...)
+}
+
+func (s *countingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	s.counter.query(s.query)
+	rows, err := s.stmt.QueryContext(ctx, namedValueArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return newCountingRows(rows)
+}
+
+// namedValueArgs converts driver arguments back to database/sql arguments.
+func namedValueArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			values[i] = sql.Named(arg.Name, arg.Value)
+		} else {
+			values[i] = arg.Value
+		}
+	}
+	return values
+}
+
+// countingRows serves the rows of the wrapped connection.
+type countingRows struct {
+	rows    *sql.Rows
+	columns []string
+}
+
+func newCountingRows(rows *sql.Rows) (*countingRows, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("read columns error: %w", err)
+	}
+	return &countingRows{rows: rows, columns: columns}, nil
+}
+
+func (r *countingRows) Columns() []string {
+	return r.columns
+}
+
+func (r *countingRows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *countingRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	values := make([]interface{}, len(dest))
+	pointers := make([]interface{}, len(dest))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := r.rows.Scan(pointers...); err != nil {
+		return err
+	}
+	for i, value := range values {
+		dest[i] = value
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package sqltestutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCountQueries(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id FROM orders").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE orders").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	counting, stats := CountQueries(db)
+	defer counting.Close()
+
+	ctx := context.Background()
+	rows, err := counting.QueryContext(ctx, "SELECT id FROM orders")
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err() = %v", err)
+	}
+	rows.Close()
+
+	tx, err := counting.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, "UPDATE orders SET shipped = true WHERE id = $1", id); err != nil {
+			t.Fatalf("ExecContext() error = %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	got := stats()
+	if got.Queries != 3 || got.Transactions != 1 || len(got.Statements) != 3 {
+		t.Errorf("stats() = %+v, want 3 queries in 1 transaction", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAssertMaxQueries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		queries  int
+		max      int
+		wantFail bool
+	}{
+		{name: "under", queries: 1, max: 2},
+		{name: "equal", queries: 2, max: 2},
+		{name: "over", queries: 3, max: 2, wantFail: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rec := &failRecorder{TB: t}
+			AssertMaxQueries(rec, QueryStats{Queries: tt.queries}, tt.max)
+			if rec.failed != tt.wantFail {
+				t.Errorf("failed = %v, want %v", rec.failed, tt.wantFail)
+			}
+		})
+	}
+}
+
+// failRecorder records failures instead of failing the test.
+type failRecorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *failRecorder) Errorf(string, ...interface{}) {
+	r.failed = true
+}
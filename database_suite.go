@@ -3,7 +3,7 @@ package sqltestutil
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"log/slog"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -84,6 +84,6 @@ func (s *Suite) SetupSuite() {
 
 func (s *Suite) TearDownSuite() {
 	if err := s.db.Close(); err != nil {
-		fmt.Println("error in database close:", err)
+		slog.Default().Error("could not close database", "err", err)
 	}
 }
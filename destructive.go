@@ -0,0 +1,194 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// testDatabaseTag is the comment TestDB tags the databases it creates with.
+const testDatabaseTag = fixtureTagPrefix + "test"
+
+// ErrNotTestDatabase is returned by the destructive methods of
+// PostgresContainer when the target database wasn't created by this package,
+// see AllowDestructive.
+var ErrNotTestDatabase = errors.New("database was not created by sqltestutil")
+
+// DestructiveOption configures TruncateAll, DropDatabasesMatching and
+// RestoreDump.
+type DestructiveOption func(*destructiveConfig)
+
+type destructiveConfig struct {
+	allowed map[string]bool
+}
+
+// AllowDestructive lets TruncateAll, DropDatabasesMatching and RestoreDump
+// modify the named database even though it wasn't created by this package.
+//
+// Without it, on a server connected to with ConnectPostgres they only touch
+// the databases created by TestDB and PrepareDatabase, which are tagged with
+// a comment, so that a production DSN accidentally exported in the
+// environment, e.g. as SQLTESTUTIL_DSN, can't be wiped. Every database of a
+// container is fair game.
+func AllowDestructive(dbName string) DestructiveOption {
+	return func(c *destructiveConfig) {
+		c.allowed[dbName] = true
+	}
+}
+
+func destructiveOptions(options []DestructiveOption) *destructiveConfig {
+	config := &destructiveConfig{allowed: map[string]bool{}}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// TruncateAll empties every table of the named database, except the
+// migration history of WithMigrationTracking, and restarts their sequences.
+func (c *PostgresContainer) TruncateAll(ctx context.Context, dbName string, options ...DestructiveOption) error {
+	if err := c.checkDestructive(ctx, []string{dbName}, destructiveOptions(options)); err != nil {
+		return err
+	}
+	return c.withDatabase(ctx, dbName, func(db *sql.DB) error {
+		tables, err := queryStrings(ctx, db, DefaultQueries.ListTables)
+		if err != nil {
+			return fmt.Errorf("list tables error: %w", err)
+		}
+		tables = truncatableTables(tables)
+		if len(tables) == 0 {
+			return nil
+		}
+		_, err = db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE")
+		if err != nil {
+			return fmt.Errorf("truncate error: %w", err)
+		}
+		return nil
+	})
+}
+
+// DropDatabasesMatching drops the databases whose name matches the LIKE
+// pattern, e.g. "test_%", except the database the container was started
+// with, and returns their names. If any of them may not be dropped, see
+// AllowDestructive, none are.
+func (c *PostgresContainer) DropDatabasesMatching(ctx context.Context, pattern string, options ...DestructiveOption) ([]string, error) {
+	var names []string
+	err := c.withDatabase(ctx, c.dbName, func(db *sql.DB) error {
+		var err error
+		names, err = queryStrings(ctx, db, DefaultQueries.MatchingDatabases, pattern)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list databases error: %w", err)
+	}
+	names = without(names, c.dbName)
+	if err := c.checkDestructive(ctx, names, destructiveOptions(options)); err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, name := range names {
+		if err := dropDatabase(ctx, c, name); err != nil {
+			return dropped, fmt.Errorf("drop database %s error: %w", name, err)
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
+
+// RestoreDump executes the plain SQL dump at path in the named database. The
+// dump must not use COPY ... FROM stdin, so dumps are taken with
+// pg_dump --inserts, or --column-inserts.
+func (c *PostgresContainer) RestoreDump(ctx context.Context, dbName, path string, options ...DestructiveOption) error {
+	if err := c.checkDestructive(ctx, []string{dbName}, destructiveOptions(options)); err != nil {
+		return err
+	}
+	dump, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read dump error: %w", err)
+	}
+	return c.withDatabase(ctx, dbName, func(db *sql.DB) error {
+		if _, err := db.ExecContext(ctx, string(dump)); err != nil {
+			return fmt.Errorf("restore dump error: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkDestructive returns an error wrapping ErrNotTestDatabase if any of the
+// named databases may not be modified by a destructive method.
+func (c *PostgresContainer) checkDestructive(ctx context.Context, names []string, config *destructiveConfig) error {
+	if !c.external {
+		return nil
+	}
+	return c.withDatabase(ctx, c.dbName, func(db *sql.DB) error {
+		return checkTestDatabases(ctx, db, names, config)
+	})
+}
+
+// checkTestDatabases returns an error wrapping ErrNotTestDatabase if any of
+// the named databases of the server db is connected to isn't tagged by this
+// package nor allowed by config.
+func checkTestDatabases(ctx context.Context, db *sql.DB, names []string, config *destructiveConfig) error {
+	var refused []string
+	for _, name := range names {
+		if config.allowed[name] {
+			continue
+		}
+		var comment sql.NullString
+		err := db.QueryRowContext(ctx, DefaultQueries.DatabaseComment, name).Scan(&comment)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("query database error: %w", err)
+		}
+		if !strings.HasPrefix(comment.String, fixtureTagPrefix) {
+			refused = append(refused, name)
+		}
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("refusing to modify %s: %w; pass AllowDestructive to override", strings.Join(refused, ", "), ErrNotTestDatabase)
+	}
+	return nil
+}
+
+// truncatableTables returns tables without the migration history.
+func truncatableTables(tables []string) []string {
+	var truncatable []string
+	for _, table := range tables {
+		if table != "public."+migrationTable {
+			truncatable = append(truncatable, table)
+		}
+	}
+	return truncatable
+}
+
+// without returns names without name.
+func without(names []string, name string) []string {
+	var rest []string
+	for _, n := range names {
+		if n != name {
+			rest = append(rest, n)
+		}
+	}
+	return rest
+}
+
+// queryStrings returns the single string column of the rows of query.
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,65 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckTestDatabases(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		comment interface{}
+		options []DestructiveOption
+		wantErr bool
+	}{
+		{name: "prepared", comment: fixtureTagPrefix + "0123abcd"},
+		{name: "test database", comment: testDatabaseTag},
+		{name: "no comment", comment: nil, wantErr: true},
+		{name: "other comment", comment: "orders of the shop", wantErr: true},
+		{name: "allowed", options: []DestructiveOption{AllowDestructive("app")}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if len(tt.options) == 0 {
+				mock.ExpectQuery("SELECT shobj_description").
+					WithArgs("app").
+					WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow(tt.comment))
+			}
+
+			err = checkTestDatabases(context.Background(), db, []string{"app"}, destructiveOptions(tt.options))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkTestDatabases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrNotTestDatabase) {
+				t.Errorf("checkTestDatabases() error = %v, want ErrNotTestDatabase", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTruncatableTables(t *testing.T) {
+	t.Parallel()
+
+	got := truncatableTables([]string{"public.orders", "public." + migrationTable, "billing.invoices"})
+	want := []string{"public.orders", "billing.invoices"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("truncatableTables() = %v, want %v", got, want)
+	}
+}
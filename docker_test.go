@@ -0,0 +1,289 @@
+package sqltestutil
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// fakeDocker is a Docker daemon serving canned responses, for testing code
+// that talks to Docker without one.
+type fakeDocker struct {
+	mu     sync.Mutex
+	routes map[string]http.HandlerFunc
+	calls  []string
+}
+
+// apiVersion matches the API version prefix of request paths.
+var apiVersion = regexp.MustCompile(`^/v[0-9.]+`)
+
+// newFakeDocker starts a fake daemon answering requests with the handlers of
+// routes, keyed by method and path without the API version, e.g.
+// "POST /containers/create". Other requests get a 404. The returned client is
+// connected to it.
+func newFakeDocker(t *testing.T, routes map[string]http.HandlerFunc) (*client.Client, *fakeDocker) {
+	t.Helper()
+
+	d := &fakeDocker{routes: routes}
+	srv := httptest.NewServer(d)
+	t.Cleanup(srv.Close)
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(srv.URL, "http://")),
+		client.WithVersion("1.41"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli, d
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + apiVersion.ReplaceAllString(r.URL.Path, "")
+	d.mu.Lock()
+	d.calls = append(d.calls, route)
+	handler, ok := d.routes[route]
+	d.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "no such route: " + route})
+		return
+	}
+	handler(w, r)
+}
+
+// requests returns the routes requested so far, in order.
+func (d *fakeDocker) requests() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.calls)
+}
+
+// called returns how many times route was requested.
+func (d *fakeDocker) called(route string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, call := range d.calls {
+		if call == route {
+			n++
+		}
+	}
+	return n
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// respond returns a handler that writes v as JSON with the given status.
+func respond(status int, v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, status, v)
+	}
+}
+
+// runRoutes returns the routes of a fake daemon that runs a container from
+// image with the ID abc, publishing port on hostPort. The config the container
+// is created with is stored in created.
+func runRoutes(image string, port nat.Port, hostPort string, created *container.Config) map[string]http.HandlerFunc {
+	var mu sync.Mutex
+	return map[string]http.HandlerFunc{
+		"GET /images/" + image + "/json": respond(http.StatusOK, types.ImageInspect{ID: "sha256:abc"}),
+		"POST /containers/create": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, container.ContainerCreateCreatedBody{ID: "abc"})
+		},
+		"POST /containers/abc/start": respond(http.StatusNoContent, nil),
+		"GET /containers/abc/json": inspectResponse(
+			&types.ContainerState{Running: true, Health: &types.Health{Status: "healthy"}},
+			&types.NetworkSettings{
+				NetworkSettingsBase: types.NetworkSettingsBase{
+					Ports: nat.PortMap{port: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}},
+				},
+			},
+		),
+	}
+}
+
+// serveFake listens on a local port and serves each connection with serve,
+// returning the port.
+func serveFake(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+// fakeMySQL starts a server that greets clients with a MySQL handshake
+// packet, and returns its port.
+func fakeMySQL(t *testing.T) string {
+	t.Helper()
+
+	return serveFake(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte{0x4a, 0x00, 0x00, 0x00, 0x0a})
+	})
+}
+
+// fakePostgres starts a server that accepts any Postgres startup and answers
+// every query with an empty result, enough for a ping, and returns its port.
+func fakePostgres(t *testing.T) string {
+	t.Helper()
+
+	return serveFake(t, func(conn net.Conn) {
+		backend := pgproto3.NewBackend(conn, conn)
+		for {
+			msg, err := backend.ReceiveStartupMessage()
+			if err != nil {
+				return
+			}
+			if _, ok := msg.(*pgproto3.StartupMessage); ok {
+				break
+			}
+			// decline SSL and GSS encryption
+			if _, err := conn.Write([]byte("N")); err != nil {
+				return
+			}
+		}
+		backend.Send(&pgproto3.AuthenticationOk{})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		if err := backend.Flush(); err != nil {
+			return
+		}
+		for {
+			msg, err := backend.Receive()
+			if err != nil {
+				return
+			}
+			switch msg.(type) {
+			case *pgproto3.Query:
+				backend.Send(&pgproto3.EmptyQueryResponse{})
+				backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				if err := backend.Flush(); err != nil {
+					return
+				}
+			case *pgproto3.Terminate:
+				return
+			}
+		}
+	})
+}
+
+func TestShutdownContainer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		options    []ShutdownOption
+		wantStop   bool
+		wantStopT  string
+		wantVolume string
+		wantForce  string
+	}{
+		{
+			name:     "defaults",
+			wantStop: true,
+		},
+		{
+			name:      "stop timeout",
+			options:   []ShutdownOption{WithStopTimeout(5 * time.Second)},
+			wantStop:  true,
+			wantStopT: "5",
+		},
+		{
+			name:       "remove volumes",
+			options:    []ShutdownOption{WithRemoveVolumes()},
+			wantStop:   true,
+			wantVolume: "1",
+		},
+		{
+			name:      "force",
+			options:   []ShutdownOption{WithForce()},
+			wantForce: "1",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var stopT, volume, force string
+			cli, d := newFakeDocker(t, map[string]http.HandlerFunc{
+				"POST /containers/abc/stop": func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+					stopT = r.URL.Query().Get("t")
+					w.WriteHeader(http.StatusNoContent)
+				},
+				"DELETE /containers/abc": func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+					volume, force = r.URL.Query().Get("v"), r.URL.Query().Get("force")
+					w.WriteHeader(http.StatusNoContent)
+				},
+			})
+
+			err := shutdownContainer(context.Background(), dockerConfig{client: cli}, "abc", tt.options...)
+			if err != nil {
+				t.Fatalf("shutdownContainer() error = %v", err)
+			}
+			if got := d.called("POST /containers/abc/stop") == 1; got != tt.wantStop {
+				t.Errorf("container stopped = %v, want %v", got, tt.wantStop)
+			}
+			if d.called("DELETE /containers/abc") != 1 {
+				t.Fatalf("requests = %v, want the container removed", d.requests())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if stopT != tt.wantStopT {
+				t.Errorf("stop timeout = %q, want %q", stopT, tt.wantStopT)
+			}
+			if volume != tt.wantVolume {
+				t.Errorf("remove volumes = %q, want %q", volume, tt.wantVolume)
+			}
+			if force != tt.wantForce {
+				t.Errorf("force = %q, want %q", force, tt.wantForce)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const defaultDuckDBDriver = "duckdb"
+
+// DuckDBTestDBOptions configures NewDuckDBTestDB.
+type DuckDBTestDBOptions struct {
+	// DriverName is the database/sql driver to open the database with. The
+	// driver isn't imported by this package, so the caller must import one,
+	// e.g. github.com/marcboeker/go-duckdb. Defaults to "duckdb".
+	DriverName string
+	// InMemory creates an in-memory database instead of a temporary file.
+	InMemory bool
+	// Migrations is a directory of migrations to run, see RunMigrations.
+	// Optional.
+	Migrations string
+	// Scenario is a scenario file to load after the migrations, see
+	// LoadScenario. Optional.
+	Scenario string
+}
+
+// DuckDBTestDB is a throwaway in-process DuckDB database, so that analytical
+// SQL can be tested with the same migrations and scenarios as Postgres. It
+// embeds the *sql.DB connected to it.
+type DuckDBTestDB struct {
+	*sql.DB
+
+	// dir is the temporary directory holding the database file, if any.
+	dir string
+}
+
+// NewDuckDBTestDB opens a DuckDB database in a temporary file, or in memory if
+// opts.InMemory is set, and populates it with opts.Migrations and
+// opts.Scenario. The database is deleted by Close.
+func NewDuckDBTestDB(ctx context.Context, opts DuckDBTestDBOptions) (*DuckDBTestDB, error) {
+	if opts.DriverName == "" {
+		opts.DriverName = defaultDuckDBDriver
+	}
+
+	d := &DuckDBTestDB{}
+	dsn, err := d.dsn(opts.InMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	// The driver's connector shares one database between all connections of
+	// the pool, including in-memory ones.
+	d.DB, err = sql.Open(opts.DriverName, dsn)
+	if err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("open duckdb error: %w", err)
+	}
+
+	var scenarioFiles []string
+	if opts.Scenario != "" {
+		scenarioFiles = append(scenarioFiles, opts.Scenario)
+	}
+	if err := applyFixtures(ctx, d.DB, opts.Migrations, scenarioFiles); err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// dsn returns the data source name of a new database, creating its temporary
+// directory if it's file-based. DuckDB opens an in-memory database for an
+// empty DSN.
+func (d *DuckDBTestDB) dsn(inMemory bool) (string, error) {
+	if inMemory {
+		return "", nil
+	}
+
+	dir, err := os.MkdirTemp("", "sqltestutil-duckdb-")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir error: %w", err)
+	}
+	d.dir = dir
+	return filepath.Join(dir, "test.duckdb"), nil
+}
+
+// Close closes the database and deletes it.
+func (d *DuckDBTestDB) Close() error {
+	var errs []error
+	if d.DB != nil {
+		errs = append(errs, d.DB.Close())
+	}
+	if d.dir != "" {
+		errs = append(errs, os.RemoveAll(d.dir))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,47 @@
+package sqltestutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuckDBTestDBDSN(t *testing.T) {
+	t.Parallel()
+
+	d := &DuckDBTestDB{}
+	dsn, err := d.dsn(true)
+	if err != nil {
+		t.Fatalf("dsn() error = %v", err)
+	}
+	if dsn != "" || d.dir != "" {
+		t.Errorf("in-memory dsn = %q, dir = %q, want both empty", dsn, d.dir)
+	}
+
+	d = &DuckDBTestDB{}
+	dsn, err = d.dsn(false)
+	if err != nil {
+		t.Fatalf("dsn() error = %v", err)
+	}
+	if want := filepath.Join(d.dir, "test.duckdb"); dsn != want {
+		t.Errorf("file dsn = %q, want %q", dsn, want)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(d.dir); !os.IsNotExist(err) {
+		t.Errorf("temp dir still exists after Close: %v", err)
+	}
+}
+
+func TestNewDuckDBTestDBUnknownDriver(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDuckDBTestDB(context.Background(), DuckDBTestDBOptions{
+		DriverName: "sqltestutil-missing",
+	})
+	if err == nil {
+		t.Fatal("NewDuckDBTestDB() error = nil, want unknown driver error")
+	}
+}
@@ -0,0 +1,107 @@
+package sqltestutil
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what an Event reports.
+type EventType string
+
+// The events emitted by the package, see Subscribe.
+const (
+	// EventContainerCreated, EventContainerStarted and EventContainerReady
+	// report the phases of starting a container, and EventContainerShutdown
+	// its shutdown.
+	EventContainerCreated  EventType = "container_created"
+	EventContainerStarted  EventType = "container_started"
+	EventContainerReady    EventType = "container_ready"
+	EventContainerShutdown EventType = "container_shutdown"
+	// EventImagePulled reports an image pull, and EventHealthChanged a change
+	// of the health status of a starting container.
+	EventImagePulled   EventType = "image_pulled"
+	EventHealthChanged EventType = "health_changed"
+	// EventMigrationApplied reports a migration file applied by
+	// RunMigrations.
+	EventMigrationApplied EventType = "migration_applied"
+	// EventScenarioLoaded reports a table of a scenario file loaded by
+	// LoadScenario.
+	EventScenarioLoaded EventType = "scenario_loaded"
+	// EventCleanup reports a run of CleanupOrphans.
+	EventCleanup EventType = "cleanup"
+)
+
+// Event is emitted to the subscribers of the package, see Subscribe. Only the
+// fields that apply to its Type are set.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// ContainerID and Image identify the container of a container event.
+	ContainerID string
+	Image       string
+	// Status is the new health status for EventHealthChanged, e.g.
+	// "starting" or "healthy".
+	Status string
+	// Filename is the base name of the migration or scenario file, and Table
+	// the scenario table.
+	Filename string
+	Table    string
+	// Count is the number of rows inserted for EventScenarioLoaded and of
+	// containers removed for EventCleanup.
+	Count int
+	// Duration is how long the reported step took.
+	Duration time.Duration
+	// Err is the error the step failed with, if any.
+	Err error
+}
+
+var subscribers = struct {
+	sync.Mutex
+	next int
+	fns  map[int]func(Event)
+}{
+	fns: map[int]func(Event){},
+}
+
+// Subscribe calls fn with every event emitted by the package, by any
+// goroutine, until the returned function is called. It lets custom reporters,
+// CI annotations or analytics of slow and flaky setups observe containers,
+// migrations, scenarios and cleanups without being threaded through every
+// call:
+//
+//	unsubscribe := sqltestutil.Subscribe(func(e sqltestutil.Event) {
+//	    if e.Err != nil {
+//	        fmt.Printf("::warning::%s failed: %v\n", e.Type, e.Err)
+//	    }
+//	})
+//	defer unsubscribe()
+//
+// fn is called synchronously, possibly from several goroutines at once, so it
+// must be safe for concurrent use and return quickly.
+func Subscribe(fn func(Event)) (unsubscribe func()) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	id := subscribers.next
+	subscribers.next++
+	subscribers.fns[id] = fn
+	return func() {
+		subscribers.Lock()
+		defer subscribers.Unlock()
+		delete(subscribers.fns, id)
+	}
+}
+
+// emit calls the subscribers with event, stamped with the current time.
+func emit(event Event) {
+	subscribers.Lock()
+	fns := make([]func(Event), 0, len(subscribers.fns))
+	for _, fn := range subscribers.fns {
+		fns = append(fns, fn)
+	}
+	subscribers.Unlock()
+
+	event.Time = time.Now()
+	for _, fn := range fns {
+		fn(event)
+	}
+}
@@ -0,0 +1,45 @@
+package sqltestutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	unsubscribe := Subscribe(func(e Event) {
+		// other tests emit events concurrently
+		if e.Type != EventScenarioLoaded || e.Filename != "graph.yml" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+
+	if err := LoadScenario(context.Background(), &mockExecerContext{}, "testdata/graph.yml"); err != nil {
+		t.Fatalf("LoadScenario() error = %v", err)
+	}
+	unsubscribe()
+	emit(Event{Type: EventScenarioLoaded, Filename: "graph.yml", Table: "after unsubscribe"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) == 0 {
+		t.Fatal("no scenario events received")
+	}
+	for _, e := range events {
+		if e.Table == "after unsubscribe" {
+			t.Error("event received after unsubscribe")
+		}
+		if e.Time.IsZero() || e.Count == 0 {
+			t.Errorf("event %+v lacks time or row count", e)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// extensionImages lists well-known images that ship an extension the stock
+// postgres image doesn't include.
+var extensionImages = map[string]string{
+	"postgis":          "postgis/postgis",
+	"postgis_raster":   "postgis/postgis",
+	"postgis_topology": "postgis/postgis",
+	"timescaledb":      "timescale/timescaledb",
+	"vector":           "pgvector/pgvector",
+	"age":              "apache/age",
+	"citus":            "citusdata/citus",
+}
+
+// AvailableExtensions returns the sorted names of the extensions that can be
+// created in db, i.e. those installed in the server's image. Tests can use it
+// to assert assumptions about their environment.
+func AvailableExtensions(ctx context.Context, db QueryerContext) ([]string, error) {
+	rows, err := db.QueryContext(ctx, DefaultQueries.AvailableExtensions)
+	if err != nil {
+		return nil, fmt.Errorf("query available extensions error: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// createExtensions creates the given extensions in the database db is
+// connected to, after checking that all of them are available.
+func createExtensions(ctx context.Context, db *sql.DB, names []string) error {
+	available, err := AvailableExtensions(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := checkExtensions(names, available); err != nil {
+		return err
+	}
+	for _, name := range names {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %q", name))
+		if err != nil {
+			return fmt.Errorf("create extension %s error: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkExtensions returns an error naming the wanted extensions that aren't
+// available, with the images known to include them.
+func checkExtensions(wanted, available []string) error {
+	isAvailable := make(map[string]bool, len(available))
+	for _, name := range available {
+		isAvailable[name] = true
+	}
+	var missing, hints []string
+	for _, name := range wanted {
+		if isAvailable[name] {
+			continue
+		}
+		missing = append(missing, name)
+		if image, ok := extensionImages[name]; ok {
+			hints = append(hints, fmt.Sprintf("%s is included in %s", name, image))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("extensions not available in this image: %s", strings.Join(missing, ", "))
+	if len(hints) > 0 {
+		sort.Strings(hints)
+		msg += " (" + strings.Join(hints, "; ") + ", see WithImage)"
+	}
+	return fmt.Errorf("%s", msg)
+}
@@ -0,0 +1,74 @@
+package sqltestutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAvailableExtensions(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT name FROM pg_available_extensions").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("hstore").AddRow("plpgsql"))
+
+	got, err := AvailableExtensions(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hstore", "plpgsql"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AvailableExtensions() = %v, want %v", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckExtensions(t *testing.T) {
+	t.Parallel()
+
+	available := []string{"hstore", "pgcrypto", "plpgsql"}
+	tests := []struct {
+		name    string
+		wanted  []string
+		wantErr string
+	}{
+		{
+			name:   "all available",
+			wanted: []string{"pgcrypto", "hstore"},
+		},
+		{
+			name:    "unknown",
+			wanted:  []string{"pgcrypto", "nope"},
+			wantErr: "extensions not available in this image: nope",
+		},
+		{
+			name:    "known image",
+			wanted:  []string{"vector", "postgis"},
+			wantErr: "extensions not available in this image: vector, postgis (postgis is included in postgis/postgis; vector is included in pgvector/pgvector, see WithImage)",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkExtensions(tt.wanted, available)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkExtensions() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("checkExtensions() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package sqltestutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConnectPostgresInvalidDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dsn     string
+		wantErr string
+	}{
+		{name: "keyword value", dsn: "host=localhost user=app", wantErr: "must be a postgres:// URL"},
+		{name: "mysql", dsn: "mysql://root@localhost/app", wantErr: `got scheme "mysql"`},
+		{name: "malformed", dsn: "postgres://%zz", wantErr: "parse dsn error"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ConnectPostgres(context.Background(), tt.dsn)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ConnectPostgres(%q) error = %v, want %q", tt.dsn, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagedProfile(t *testing.T) {
+	t.Parallel()
+
+	// no server is needed, since nothing must be run
+	pg := &PostgresContainer{external: true, profile: ProfileManaged}
+	if err := pg.ResetStats(context.Background()); err != nil {
+		t.Errorf("ResetStats() error = %v", err)
+	}
+	if err := pg.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := pg.CopyToContainer(context.Background(), strings.NewReader(""), "/tmp/x"); err != errExternal {
+		t.Errorf("CopyToContainer() error = %v, want %v", err, errExternal)
+	}
+}
@@ -0,0 +1,157 @@
+package sqltestutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fixtureTagPrefix prefixes the comment that marks a database whose fixtures
+// have been completely loaded.
+const fixtureTagPrefix = "sqltestutil:"
+
+// PrepareDatabase returns the connection string of a database on the container
+// that has the migrations in migrationDir and the given scenario files loaded.
+// The database is named after a hash of those inputs and tagged with it once
+// fully loaded, so when the container already has a database for the same
+// inputs (e.g. because the container is reused across test runs) the work is
+// skipped entirely. If the inputs change, a new database is created.
+//
+// An empty migrationDir skips migrations.
+func (c *PostgresContainer) PrepareDatabase(
+	ctx context.Context,
+	migrationDir string,
+	scenarioFiles ...string,
+) (string, error) {
+	dbName, err := c.prepareDatabase(ctx, migrationDir, scenarioFiles)
+	if err != nil {
+		return "", err
+	}
+	return c.connectionStringFor(dbName)
+}
+
+// prepareDatabase does the work of PrepareDatabase and returns the name of the
+// database.
+func (c *PostgresContainer) prepareDatabase(
+	ctx context.Context,
+	migrationDir string,
+	scenarioFiles []string,
+) (string, error) {
+	hash, err := fixtureHash(migrationDir, scenarioFiles)
+	if err != nil {
+		return "", err
+	}
+	dbName := "sqltestutil_" + hash[:16]
+	tag := fixtureTagPrefix + hash
+
+	admin, err := c.open(c.ConnectionString())
+	if err != nil {
+		return "", err
+	}
+	defer admin.Close()
+
+	var comment sql.NullString
+	err = admin.QueryRowContext(
+		ctx,
+		DefaultQueries.DatabaseComment,
+		dbName,
+	).Scan(&comment)
+	switch {
+	case err == nil && comment.String == tag:
+		return dbName, nil
+	case err == nil:
+		// left over from an interrupted load
+		if _, err := admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE %q", dbName)); err != nil {
+			return "", fmt.Errorf("drop database error: %w", err)
+		}
+	case err != sql.ErrNoRows:
+		return "", fmt.Errorf("query database error: %w", err)
+	}
+
+	if _, err := admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %q", dbName)); err != nil {
+		return "", fmt.Errorf("create database error: %w", err)
+	}
+
+	connStr, err := c.connectionStringFor(dbName)
+	if err != nil {
+		return "", err
+	}
+	if err := c.loadFixtures(ctx, connStr, migrationDir, scenarioFiles); err != nil {
+		return "", err
+	}
+
+	_, err = admin.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %q IS '%s'", dbName, tag))
+	if err != nil {
+		return "", fmt.Errorf("tag database error: %w", err)
+	}
+	return dbName, nil
+}
+
+func (c *PostgresContainer) loadFixtures(ctx context.Context, connStr, migrationDir string, scenarioFiles []string) error {
+	db, err := c.open(connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return applyFixtures(ctx, db, migrationDir, scenarioFiles)
+}
+
+// applyFixtures runs the migrations in migrationDir, if any, and then loads the
+// scenario files into db.
+func applyFixtures(ctx context.Context, db ExecerContext, migrationDir string, scenarioFiles []string) error {
+	if migrationDir != "" {
+		if err := RunMigrations(ctx, db, migrationDir); err != nil {
+			return err
+		}
+	}
+	for _, filename := range scenarioFiles {
+		if err := LoadScenario(ctx, db, filename); err != nil {
+			return fmt.Errorf("load scenario %s error: %w", filename, err)
+		}
+	}
+	return nil
+}
+
+// fixtureHash returns a hex encoded hash of the names and contents of the
+// migrations in migrationDir and of the scenario files.
+func fixtureHash(migrationDir string, scenarioFiles []string) (string, error) {
+	var filenames []string
+	if migrationDir != "" {
+		migrations, err := filepath.Glob(filepath.Join(migrationDir, "*.up.sql"))
+		if err != nil {
+			return "", fmt.Errorf("glob migrationDir error: %w", err)
+		}
+		sort.Strings(migrations)
+		filenames = append(filenames, migrations...)
+	}
+	filenames = append(filenames, scenarioFiles...)
+
+	h := sha256.New()
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return "", fmt.Errorf("read file error: %w", err)
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(filename), len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// connectionStringFor returns the connection string of another database on
+// the same container.
+func (c *PostgresContainer) connectionStringFor(dbName string) (string, error) {
+	u, err := url.Parse(c.ConnectionString())
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + strings.TrimPrefix(dbName, "/")
+	return u.String(), nil
+}
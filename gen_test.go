@@ -0,0 +1,62 @@
+package sqltestutil
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFactories(t *testing.T) {
+	t.Parallel()
+
+	src, err := GenerateFactories(lintSchema, "fixtures")
+	if err != nil {
+		t.Fatalf("GenerateFactories() error = %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", src, 0); err != nil {
+		t.Fatalf("generated source doesn't parse: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"package fixtures",
+		"type PostsRow struct",
+		"UserID int32  `db:\"user_id\"`",
+		"type UsersRow struct",
+		"ID       *int32 `db:\"id\"`",
+		"func InsertUsersRows(ctx context.Context, db sqltestutil.ExecerContext, rows ...UsersRow) error",
+		"if row.ID != nil {",
+		"values = append(values, *row.ID)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source doesn't contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), `"time"`) {
+		t.Errorf("generated source imports time without time columns:\n%s", src)
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "users", want: "Users"},
+		{name: "user_id", want: "UserID"},
+		{name: "avatar_url", want: "AvatarURL"},
+		{name: "2fa_secret", want: "X2faSecret"},
+		{name: "created-at", want: "CreatedAt"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := goIdentifier(tt.name); got != tt.want {
+				t.Errorf("goIdentifier(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
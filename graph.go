@@ -0,0 +1,187 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type graphConfig struct {
+	schema *Schema
+}
+
+// GraphOption configures ScenarioGraph.
+type GraphOption func(*graphConfig)
+
+// WithGraphSchema makes ScenarioGraph take foreign keys from schema instead of
+// guessing them from column names.
+func WithGraphSchema(schema *Schema) GraphOption {
+	return func(c *graphConfig) {
+		c.schema = schema
+	}
+}
+
+// ScenarioGraph returns a Graphviz (dot) graph of the rows in a scenario file
+// and the foreign key references between them, which helps untangle large
+// fixture files. Render it with e.g. `dot -Tsvg`.
+//
+// By default a column named <name>_id is assumed to reference the id column of
+// the table <name>s (or <name>); pass WithGraphSchema to use the real foreign
+// keys. References that no row in the file satisfies, e.g. because the
+// referenced row gets its ID from a sequence, point at the table as a whole.
+func ScenarioGraph(filename string, options ...GraphOption) (string, error) {
+	config := &graphConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	tables, _, err := parseScenario(data)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph scenario {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=record];\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote("cluster_"+table.name))
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(table.name))
+		for i, row := range table.rows {
+			fields := []string{fmt.Sprintf("%s #%d", table.name, i+1)}
+			for j, column := range row.columns {
+				fields = append(fields, dotEscape(fmt.Sprintf("%s: %v", column, row.values[j])))
+			}
+			fmt.Fprintf(&b, "    %s [label=%s];\n", dotQuote(rowNodeID(table.name, i)), dotQuote(strings.Join(fields, "|")))
+		}
+		b.WriteString("  }\n")
+	}
+
+	tableNodes := map[string]bool{}
+	for _, table := range tables {
+		for i, row := range table.rows {
+			for _, fk := range graphForeignKeys(config.schema, table.name, row, tables) {
+				values, ok := foreignKeyValues(fk, row)
+				if !ok {
+					continue
+				}
+				label := strings.Join(fk.Columns, ", ")
+				refs := findRows(tables, fk.RefTable, fk.RefColumns, values)
+				if len(refs) == 0 {
+					if !tableNodes[fk.RefTable] {
+						tableNodes[fk.RefTable] = true
+						fmt.Fprintf(&b, "  %s [shape=box, style=dashed];\n", dotQuote(fk.RefTable))
+					}
+					fmt.Fprintf(&b, "  %s -> %s [label=%s, style=dashed];\n", dotQuote(rowNodeID(table.name, i)), dotQuote(fk.RefTable), dotQuote(label))
+					continue
+				}
+				for _, ref := range refs {
+					fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(rowNodeID(table.name, i)), dotQuote(rowNodeID(fk.RefTable, ref)), dotQuote(label))
+				}
+			}
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// graphForeignKeys returns the foreign keys of a table, either from schema or
+// guessed from the columns of row.
+func graphForeignKeys(schema *Schema, table string, row scenarioRow, tables []scenarioTable) []ForeignKey {
+	if schema != nil {
+		if t := schema.Tables[table]; t != nil {
+			return t.ForeignKeys
+		}
+		return nil
+	}
+
+	var fks []ForeignKey
+	for _, column := range row.columns {
+		name, ok := strings.CutSuffix(column, "_id")
+		if !ok || name == "" {
+			continue
+		}
+		for _, refTable := range []string{name + "s", name} {
+			if hasTable(tables, refTable) {
+				fks = append(fks, ForeignKey{
+					Columns:    []string{column},
+					RefTable:   refTable,
+					RefColumns: []string{"id"},
+				})
+				break
+			}
+		}
+	}
+	return fks
+}
+
+func hasTable(tables []scenarioTable, name string) bool {
+	for _, table := range tables {
+		if table.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKeyValues returns the values of the foreign key columns in row, and
+// false if any of them is missing or NULL.
+func foreignKeyValues(fk ForeignKey, row scenarioRow) ([]interface{}, bool) {
+	values := make([]interface{}, len(fk.Columns))
+	for i, column := range fk.Columns {
+		v, ok := row.value(column)
+		if !ok || v == nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+// findRows returns the indexes of the rows of table whose columns have the
+// given values.
+func findRows(tables []scenarioTable, table string, columns []string, values []interface{}) []int {
+	var found []int
+	for _, t := range tables {
+		if t.name != table {
+			continue
+		}
+	rows:
+		for i, row := range t.rows {
+			for j, column := range columns {
+				v, ok := row.value(column)
+				if !ok || fmt.Sprint(v) != fmt.Sprint(values[j]) {
+					continue rows
+				}
+			}
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func rowNodeID(table string, index int) string {
+	return fmt.Sprintf("%s_%d", table, index+1)
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// dotEscape escapes characters that have a special meaning in record labels.
+func dotEscape(s string) string {
+	return strings.NewReplacer(
+		"|", `\|`,
+		"{", `\{`,
+		"}", `\}`,
+		"<", `\<`,
+		">", `\>`,
+	).Replace(s)
+}
@@ -0,0 +1,35 @@
+package sqltestutil
+
+import (
+	"testing"
+)
+
+func TestScenarioGraph(t *testing.T) {
+	t.Parallel()
+
+	want := `digraph scenario {
+  rankdir=LR;
+  node [shape=record];
+  subgraph "cluster_users" {
+    label="users";
+    "users_1" [label="users #1|id: 1|username: user1"];
+  }
+  subgraph "cluster_posts" {
+    label="posts";
+    "posts_1" [label="posts #1|id: 1|user_id: 1|title: Hello, world!"];
+    "posts_2" [label="posts #2|id: 2|user_id: 2|title: Goodbye, world!"];
+  }
+  "posts_1" -> "users_1" [label="user_id"];
+  "users" [shape=box, style=dashed];
+  "posts_2" -> "users" [label="user_id", style=dashed];
+}
+`
+
+	got, err := ScenarioGraph("testdata/graph.yml")
+	if err != nil {
+		t.Fatalf("ScenarioGraph() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("ScenarioGraph() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+const defaultWatchInterval = time.Second
+
+type watchConfig struct {
+	interval    time.Duration
+	maxRestarts int
+}
+
+// WatchOption configures WatchHealth.
+type WatchOption func(*watchConfig)
+
+// WithWatchInterval sets how often WatchHealth checks the container. The
+// default is one second.
+func WithWatchInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.interval = interval
+	}
+}
+
+// WithRestartOnUnhealthy makes WatchHealth restart the container, up to
+// maxRestarts times, when it is found to be unhealthy. onUnhealthy is still
+// called for every failure, and watching continues once the restarted container
+// is healthy again. Without this option WatchHealth stops at the first failure.
+// The restarted container may be published on a different port and get a
+// different IP address, which ConnectionString and NetworkConnectionString
+// return from then on, so connection strings obtained before the restart no
+// longer work.
+func WithRestartOnUnhealthy(maxRestarts int) WatchOption {
+	return func(c *watchConfig) {
+		c.maxRestarts = maxRestarts
+	}
+}
+
+// WatchHealth keeps monitoring the container in the background until ctx is
+// done. Each check inspects the container state and pings Postgres; when a
+// check fails, onUnhealthy is called with the reason. This lets a suite fail
+// fast if Postgres crashes mid-run, instead of every subsequent test timing out
+// on dead connections. For example:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	pg.WatchHealth(ctx, func(err error) {
+//	    log.Printf("postgres died: %v", err)
+//	    cancel()
+//	})
+//
+// onUnhealthy is called from a separate goroutine, so it must not call
+// t.FailNow and friends. Cancel ctx to stop watching.
+func (c *PostgresContainer) WatchHealth(
+	ctx context.Context,
+	onUnhealthy func(error),
+	options ...WatchOption,
+) {
+	config := &watchConfig{
+		interval: defaultWatchInterval,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	go c.watchHealth(ctx, onUnhealthy, config)
+}
+
+func (c *PostgresContainer) watchHealth(
+	ctx context.Context,
+	onUnhealthy func(error),
+	config *watchConfig,
+) {
+	if c.pod != nil {
+		onUnhealthy(errPod)
+		return
+	}
+	if c.external {
+		onUnhealthy(errExternal)
+		return
+	}
+	cli, release, err := c.docker.open()
+	if err != nil {
+		onUnhealthy(err)
+		return
+	}
+	defer release()
+
+	db, err := c.open(c.ConnectionString())
+	if err != nil {
+		onUnhealthy(err)
+		return
+	}
+	defer func() { db.Close() }()
+
+	ticker := time.NewTicker(config.interval)
+	defer ticker.Stop()
+
+	restarts := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := checkHealth(ctx, cli, db, c.id)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		onUnhealthy(err)
+
+		if restarts >= config.maxRestarts {
+			return
+		}
+		restarts++
+		if err := c.restart(ctx, cli); err != nil {
+			if ctx.Err() == nil {
+				onUnhealthy(fmt.Errorf("restart container: %w", err))
+			}
+			return
+		}
+		// the connection string may have changed with the restart
+		reopened, err := c.open(c.ConnectionString())
+		if err != nil {
+			onUnhealthy(err)
+			return
+		}
+		db.Close()
+		db = reopened
+	}
+}
+
+func checkHealth(ctx context.Context, cli *client.Client, db *sql.DB, containerID string) error {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("error inspecting container: %w", err)
+	}
+	if !inspect.State.Running {
+		return fmt.Errorf("container not running: %s", inspect.State.Status)
+	}
+	if inspect.State.Health != nil && inspect.State.Health.Status == "unhealthy" {
+		return errors.New("container unhealthy")
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		return fmt.Errorf("error pinging database: %w", err)
+	}
+	return nil
+}
+
+// restart restarts the container and waits until it's connectable again.
+// Docker may assign a different host port or IP address on restart, so the
+// connection strings are rebuilt from the restarted container.
+func (c *PostgresContainer) restart(ctx context.Context, cli *client.Client) error {
+	if err := cli.ContainerRestart(ctx, c.id, nil); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	if err := waitUntilHealthy(ctx, cli, c.id, nil); err != nil {
+		return err
+	}
+	port, connStr, networkConnStr, err := c.readdress(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if err := waitUntilConnectable(ctx, connStr); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.port, c.connStr, c.networkConnStr = port, connStr, networkConnStr
+	c.mu.Unlock()
+	return nil
+}
+
+// readdress returns the port, connection string and network connection string
+// the container is reached on now, keeping the hosts unless they're its IP
+// address.
+func (c *PostgresContainer) readdress(ctx context.Context, cli *client.Client) (port, connStr, networkConnStr string, err error) {
+	inspect, err := cli.ContainerInspect(ctx, c.id)
+	if err != nil {
+		return "", "", "", fmt.Errorf("inspect container error: %w", err)
+	}
+	if inspect.NetworkSettings == nil {
+		return "", "", "", fmt.Errorf("container %s has no network settings", c.id)
+	}
+
+	u, err := url.Parse(c.ConnectionString())
+	if err != nil {
+		return "", "", "", err
+	}
+	host := u.Hostname()
+	if c.viaIP {
+		host, err = networkIP(inspect.NetworkSettings, c.network)
+		port = "5432"
+	} else {
+		port, err = publishedPort(inspect.NetworkSettings.Ports, "5432/tcp")
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	u.Host = net.JoinHostPort(host, port)
+
+	// a network alias survives the restart, an IP address may not
+	networkConnStr = c.NetworkConnectionString()
+	if networkConnStr != "" {
+		n, err := url.Parse(networkConnStr)
+		if err != nil {
+			return "", "", "", err
+		}
+		if net.ParseIP(n.Hostname()) != nil {
+			ip, err := networkIP(inspect.NetworkSettings, c.network)
+			if err != nil {
+				return "", "", "", err
+			}
+			n.Host = net.JoinHostPort(ip, "5432")
+			networkConnStr = n.String()
+		}
+	}
+	return port, u.String(), networkConnStr, nil
+}
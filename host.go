@@ -0,0 +1,45 @@
+package sqltestutil
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/docker/docker/client"
+)
+
+// localHost is where the ports published by a local Docker daemon are
+// reachable.
+const localHost = "127.0.0.1"
+
+// containerHost returns the host the published ports of containers are
+// reachable on: override if it's set, the host of the daemon if it's remote,
+// e.g. with DOCKER_HOST=tcp://docker-vm:2376, and 127.0.0.1 otherwise.
+func containerHost(cli *client.Client, override string) string {
+	if override != "" {
+		return override
+	}
+	return daemonHostname(cli.DaemonHost())
+}
+
+// daemonHostname returns the host of a remote daemon address, or 127.0.0.1
+// for a local one.
+func daemonHostname(daemonHost string) string {
+	u, err := url.Parse(daemonHost)
+	if err != nil {
+		return localHost
+	}
+	switch u.Scheme {
+	case "tcp", "http", "https", "ssh":
+	default:
+		// unix sockets and named pipes are local
+		return localHost
+	}
+	host := u.Hostname()
+	if host == "" || host == "localhost" {
+		return localHost
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return localHost
+	}
+	return host
+}
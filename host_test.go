@@ -0,0 +1,32 @@
+package sqltestutil
+
+import "testing"
+
+func TestDaemonHostname(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		daemonHost string
+		want       string
+	}{
+		{daemonHost: "unix:///var/run/docker.sock", want: "127.0.0.1"},
+		{daemonHost: "npipe:////./pipe/docker_engine", want: "127.0.0.1"},
+		{daemonHost: "tcp://192.168.99.100:2376", want: "192.168.99.100"},
+		{daemonHost: "tcp://docker-vm:2375", want: "docker-vm"},
+		{daemonHost: "ssh://ci@runner.example.com", want: "runner.example.com"},
+		{daemonHost: "tcp://localhost:2375", want: "127.0.0.1"},
+		{daemonHost: "tcp://127.0.0.1:2375", want: "127.0.0.1"},
+		{daemonHost: "tcp://[::1]:2375", want: "127.0.0.1"},
+		{daemonHost: "tcp://0.0.0.0:2375", want: "127.0.0.1"},
+		{daemonHost: "tcp://[2001:db8::1]:2376", want: "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.daemonHost, func(t *testing.T) {
+			t.Parallel()
+			if got := daemonHostname(tt.daemonHost); got != tt.want {
+				t.Errorf("daemonHostname(%q) = %q, want %q", tt.daemonHost, got, tt.want)
+			}
+		})
+	}
+}
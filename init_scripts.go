@@ -0,0 +1,40 @@
+package sqltestutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// initScriptsDir is where the official postgres image looks for scripts to run
+// when it initializes the database.
+const initScriptsDir = "/docker-entrypoint-initdb.d"
+
+// initScriptBinds returns the bind mounts of the init scripts at paths: a
+// directory is mounted as initScriptsDir, and files are mounted into it.
+func initScriptBinds(paths []string) ([]string, error) {
+	var binds []string
+	dirs := 0
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("init script path error: %w", err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("init script error: %w", err)
+		}
+		if info.IsDir() {
+			dirs++
+			binds = append(binds, abs+":"+initScriptsDir+":ro")
+			continue
+		}
+		binds = append(binds, abs+":"+path.Join(initScriptsDir, filepath.Base(abs))+":ro")
+	}
+	if dirs > 1 {
+		return nil, errors.New("at most one init script directory can be mounted")
+	}
+	return binds, nil
+}
@@ -0,0 +1,52 @@
+package sqltestutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInitScriptBinds(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "01-roles.sql")
+	if err := os.WriteFile(script, []byte("CREATE ROLE app"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name:  "directory",
+			paths: []string{dir},
+			want:  []string{dir + ":/docker-entrypoint-initdb.d:ro"},
+		},
+		{
+			name:  "file",
+			paths: []string{script},
+			want:  []string{script + ":/docker-entrypoint-initdb.d/01-roles.sql:ro"},
+		},
+		{name: "missing", paths: []string{filepath.Join(dir, "missing.sql")}, wantErr: true},
+		{name: "two directories", paths: []string{dir, dir}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := initScriptBinds(tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initScriptBinds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initScriptBinds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
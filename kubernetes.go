@@ -0,0 +1,277 @@
+package sqltestutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// podStartupTimeout bounds the wait for a pod to be scheduled, pull its image
+// and become ready.
+const podStartupTimeout = 5 * time.Minute
+
+// errPod is returned by the methods of PostgresContainer that need Docker when
+// the container is a Kubernetes pod.
+var errPod = errors.New("not supported for Postgres running in a Kubernetes pod")
+
+// kubePod is a pod started by StartPostgresPod, together with the kubectl
+// port-forward that makes it reachable.
+type kubePod struct {
+	name        string
+	namespace   string
+	kubeContext string
+	portForward *exec.Cmd
+}
+
+// StartPostgresPod starts Postgres as a short-lived pod in a Kubernetes
+// cluster instead of a Docker container, for CI environments that have a
+// cluster but no Docker daemon. It runs kubectl, which must be on the PATH and
+// is configured as usual through KUBECONFIG; WithKubeContext and
+// WithKubeNamespace select the cluster and namespace. The pod is made
+// reachable on a local port with kubectl port-forward.
+//
+// The returned PostgresContainer is used like one from StartPostgresContainer:
+// ID returns the pod name and Shutdown deletes the pod. The methods that work
+// on the container through Docker, such as CopyToContainer, return an error
+// instead, and WatchHealth reports one. Of the container options, WithImage,
+// WithDBName, WithDBUser, WithDBPassword, WithTimeZone, WithSSLMode,
+// WithSharedBuffers, WithStartupTimeout (five minutes by default, to allow for
+// scheduling), WithExtensions and WithOnHealthy apply.
+func StartPostgresPod(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (pg *PostgresContainer, err error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	config := &PostgresContainerConfig{
+		DBName:     "pgtest",
+		DBUser:     "pgtest",
+		DBPassword: password,
+		TimeZone:   "UTC",
+		SSLMode:    "disable",
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	pod := &kubePod{
+		name:        "sqltestutil-" + hex.EncodeToString(suffix),
+		namespace:   config.KubeNamespace,
+		kubeContext: config.KubeContext,
+	}
+	manifest, err := json.Marshal(podManifest(pod.name, postgresImage(version, config), config))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pod.kubectl(ctx, manifest, "create", "-f", "-"); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// delete the pod if there's an error
+		if err != nil {
+			if deleteErr := pod.delete(context.Background(), true); deleteErr != nil {
+				orDefault(config.Logger).Error("could not delete pod", "pod", pod.name, "err", deleteErr)
+			}
+		}
+	}()
+
+	timeout := config.startupTimeout(podStartupTimeout)
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err = pod.kubectl(waitCtx, nil, "wait", "--for=condition=Ready", "pod/"+pod.name, "--timeout="+timeout.String())
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := pod.forwardPort(waitCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	connStr := config.connectionString(localHost, port)
+	if err := waitUntilConnectable(waitCtx, connStr); err != nil {
+		return nil, err
+	}
+	serverVersion, err := queryServerVersion(waitCtx, connStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkServerVersion(version, serverVersion); err != nil {
+		return nil, err
+	}
+
+	pg = &PostgresContainer{
+		id:       pod.name,
+		user:     config.DBUser,
+		password: config.DBPassword,
+		dbName:   config.DBName,
+		port:     port,
+		connStr:  connStr,
+
+		serverVersion: serverVersion,
+		pod:           pod,
+		profile:       config.Profile,
+		logger:        config.Logger,
+	}
+	if err := config.provision(ctx, waitCtx, pg); err != nil {
+		return nil, err
+	}
+	return pg, nil
+}
+
+// forwardedPortLine matches the line kubectl port-forward prints with the
+// local port it picked.
+var forwardedPortLine = regexp.MustCompile(`^Forwarding from 127\.0\.0\.1:(\d+) -> 5432`)
+
+// forwardPort starts kubectl port-forward to the pod on a local port kubectl
+// picks, which can't race with other processes like a port picked up front,
+// and returns that port. The port-forward is stopped by delete.
+func (p *kubePod) forwardPort(ctx context.Context) (string, error) {
+	p.portForward = exec.Command("kubectl", p.args("port-forward", "pod/"+p.name, ":5432")...)
+	stdout, err := p.portForward.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := p.portForward.Start(); err != nil {
+		return "", fmt.Errorf("kubectl port-forward error: %w", err)
+	}
+
+	ports := make(chan string, 1)
+	go func() {
+		ports <- forwardedPort(stdout)
+		// kubectl logs every connection, so keep reading
+		_, _ = io.Copy(io.Discard, stdout)
+	}()
+	select {
+	case port := <-ports:
+		if port == "" {
+			return "", errors.New("kubectl port-forward exited without forwarding a port")
+		}
+		return port, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("wait for kubectl port-forward: %w", ctx.Err())
+	}
+}
+
+// forwardedPort returns the local port in the output of kubectl
+// port-forward, or an empty string if the output ends without one.
+func forwardedPort(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if m := forwardedPortLine.FindStringSubmatch(scanner.Text()); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// podManifest returns the manifest of a pod running Postgres as configured.
+func podManifest(name, image string, config *PostgresContainerConfig) map[string]interface{} {
+	health := healthcheck(config)
+	container := map[string]interface{}{
+		"name":  "postgres",
+		"image": image,
+		"env": []map[string]string{
+			{"name": "POSTGRES_DB", "value": config.DBName},
+			{"name": "POSTGRES_USER", "value": config.DBUser},
+			{"name": "POSTGRES_PASSWORD", "value": config.DBPassword},
+			{"name": "TZ", "value": config.TimeZone},
+		},
+		"ports": []map[string]interface{}{
+			{"containerPort": 5432},
+		},
+		"readinessProbe": map[string]interface{}{
+			"exec": map[string]interface{}{
+				"command": []string{"/bin/sh", "-c", health.Test[1]},
+			},
+			"periodSeconds":  probeSeconds(health.Interval),
+			"timeoutSeconds": probeSeconds(health.Timeout),
+		},
+	}
+	if cmd := postgresCmd(config); cmd != nil {
+		container["args"] = cmd[1:]
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name": name,
+			"labels": map[string]string{
+				"app.kubernetes.io/managed-by": "sqltestutil",
+			},
+		},
+		"spec": map[string]interface{}{
+			"restartPolicy": "Never",
+			"containers":    []interface{}{container},
+		},
+	}
+}
+
+// probeSeconds returns d in whole seconds for a probe, at least 1.
+func probeSeconds(d time.Duration) int {
+	return max(int(d/time.Second), 1)
+}
+
+// args returns the kubectl command line for args, selecting the pod's context
+// and namespace.
+func (p *kubePod) args(args ...string) []string {
+	var prefix []string
+	if p.kubeContext != "" {
+		prefix = append(prefix, "--context", p.kubeContext)
+	}
+	if p.namespace != "" {
+		prefix = append(prefix, "--namespace", p.namespace)
+	}
+	return append(prefix, args...)
+}
+
+// kubectl runs kubectl with args and stdin, and returns its output.
+func (p *kubePod) kubectl(ctx context.Context, stdin []byte, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "kubectl", p.args(args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("kubectl %s error: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// delete stops the port-forward and deletes the pod, right away if force is
+// set.
+func (p *kubePod) delete(ctx context.Context, force bool) error {
+	if p.portForward != nil && p.portForward.Process != nil {
+		_ = p.portForward.Process.Kill()
+		_ = p.portForward.Wait()
+	}
+	args := []string{"delete", "pod", p.name, "--ignore-not-found"}
+	if force {
+		args = append(args, "--grace-period=0", "--force")
+	}
+	_, err := p.kubectl(ctx, nil, args...)
+	return err
+}
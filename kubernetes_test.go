@@ -0,0 +1,101 @@
+package sqltestutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPodManifest(t *testing.T) {
+	t.Parallel()
+
+	config := &PostgresContainerConfig{
+		DBName:        "app",
+		DBUser:        "app",
+		DBPassword:    "secret",
+		TimeZone:      "UTC",
+		SharedBuffers: 256 * 1024 * 1024,
+
+		HealthcheckTimeout: 5 * time.Second,
+	}
+	data, err := json.Marshal(podManifest("sqltestutil-abc", "postgres:16", config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"kind":"Pod"`,
+		`"name":"sqltestutil-abc"`,
+		`"image":"postgres:16"`,
+		`{"name":"POSTGRES_PASSWORD","value":"secret"}`,
+		`"command":["/bin/sh","-c","pg_isready -U 'app'"]`,
+		`"periodSeconds":1`,
+		`"timeoutSeconds":5`,
+		`"args":["-c","shared_buffers=262144kB"]`,
+		`"restartPolicy":"Never"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("podManifest() = %s, want it to contain %s", data, want)
+		}
+	}
+}
+
+func TestKubectlArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		pod  kubePod
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"get", "pods"},
+		},
+		{
+			name: "context and namespace",
+			pod:  kubePod{kubeContext: "ci", namespace: "tests"},
+			want: []string{"--context", "ci", "--namespace", "tests", "get", "pods"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.pod.args("get", "pods"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedPort(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "forwarding",
+			output: "Forwarding from 127.0.0.1:43127 -> 5432\nForwarding from [::1]:43127 -> 5432\n",
+			want:   "43127",
+		},
+		{
+			name:   "exited",
+			output: "error: unable to forward port\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := forwardedPort(strings.NewReader(tt.output)); got != tt.want {
+				t.Errorf("forwardedPort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Finding is a problem found by LintScenario.
+type Finding struct {
+	// Line is the line in the scenario file the finding refers to, or zero if
+	// it concerns the file as a whole.
+	Line    int
+	Table   string
+	Column  string
+	Message string
+}
+
+func (f Finding) String() string {
+	var b strings.Builder
+	if f.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", f.Line)
+	}
+	if f.Table != "" {
+		b.WriteString(f.Table)
+		if f.Column != "" {
+			b.WriteString("." + f.Column)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(f.Message)
+	return b.String()
+}
+
+// LintScenario checks a scenario file against schema without touching a
+// database. It reports unknown tables and columns, values whose type doesn't
+// fit the column, missing NOT NULL columns that have no default, and foreign
+// keys that no row in the scenario satisfies. The schema can come from
+// InspectSchema.
+//
+// Foreign keys are only checked when the referenced rows spell out the
+// referenced columns, since values generated by the database (e.g. serial
+// IDs) can't be known up front.
+func LintScenario(filename string, schema *Schema) []Finding {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return []Finding{{Message: err.Error()}}
+	}
+	tables, _, err := parseScenario(data)
+	if err != nil {
+		return []Finding{{Message: err.Error()}}
+	}
+
+	var findings []Finding
+	for _, table := range tables {
+		t := schema.Tables[table.name]
+		if t == nil {
+			findings = append(findings, Finding{
+				Line:    table.line,
+				Table:   table.name,
+				Message: "unknown table",
+			})
+			continue
+		}
+		for _, row := range table.rows {
+			findings = append(findings, lintRow(t, row)...)
+			findings = append(findings, lintForeignKeys(t, row, tables)...)
+		}
+	}
+	return findings
+}
+
+func lintRow(t *Table, row scenarioRow) []Finding {
+	var findings []Finding
+	for i, name := range row.columns {
+		column := t.Column(name)
+		if column == nil {
+			findings = append(findings, Finding{
+				Line:    row.lines[i],
+				Table:   t.Name,
+				Column:  name,
+				Message: "unknown column",
+			})
+			continue
+		}
+		value := row.values[i]
+		if value == nil {
+			if !column.Nullable {
+				findings = append(findings, Finding{
+					Line:    row.lines[i],
+					Table:   t.Name,
+					Column:  name,
+					Message: "null value in NOT NULL column",
+				})
+			}
+			continue
+		}
+		if !valueFitsType(value, column.Type) {
+			findings = append(findings, Finding{
+				Line:    row.lines[i],
+				Table:   t.Name,
+				Column:  name,
+				Message: fmt.Sprintf("%T value %v does not fit column type %s", value, value, column.Type),
+			})
+		}
+	}
+	for _, column := range t.Columns {
+		if column.Nullable || column.HasDefault {
+			continue
+		}
+		if _, ok := row.value(column.Name); !ok {
+			findings = append(findings, Finding{
+				Line:    row.line,
+				Table:   t.Name,
+				Column:  column.Name,
+				Message: "missing value for NOT NULL column without default",
+			})
+		}
+	}
+	return findings
+}
+
+func lintForeignKeys(t *Table, row scenarioRow, tables []scenarioTable) []Finding {
+	var findings []Finding
+	for _, fk := range t.ForeignKeys {
+		values, ok := foreignKeyValues(fk, row)
+		if !ok {
+			continue
+		}
+		satisfied, checkable := foreignKeySatisfied(fk, values, tables)
+		if checkable && !satisfied {
+			findings = append(findings, Finding{
+				Line:   row.line,
+				Table:  t.Name,
+				Column: strings.Join(fk.Columns, ", "),
+				Message: fmt.Sprintf(
+					"no row in %s matches %v (foreign key %s)",
+					fk.RefTable,
+					values,
+					fk.Name,
+				),
+			})
+		}
+	}
+	return findings
+}
+
+// foreignKeySatisfied reports whether any scenario row of the referenced table
+// has the given values. checkable is false when some referenced row leaves the
+// referenced columns to the database.
+func foreignKeySatisfied(fk ForeignKey, values []interface{}, tables []scenarioTable) (satisfied, checkable bool) {
+	for _, table := range tables {
+		if table.name != fk.RefTable {
+			continue
+		}
+		for _, row := range table.rows {
+			match := true
+			for i, column := range fk.RefColumns {
+				v, ok := row.value(column)
+				if !ok {
+					return false, false
+				}
+				if fmt.Sprint(v) != fmt.Sprint(values[i]) {
+					match = false
+				}
+			}
+			if match {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// valueFitsType reports whether a value decoded from YAML can be inserted into
+// a column of the given information_schema data type. Strings are accepted for
+// every type since Postgres parses them, and unknown types accept anything.
+func valueFitsType(value interface{}, dataType string) bool {
+	switch dataType {
+	case "json", "jsonb", "USER-DEFINED", "ARRAY":
+		return true
+	}
+	switch value.(type) {
+	case string:
+		return true
+	case bool:
+		return dataType == "boolean"
+	case int, int64, uint64:
+		return isNumericType(dataType)
+	case float64:
+		return isNumericType(dataType) && !isIntegerType(dataType)
+	case time.Time:
+		return strings.HasPrefix(dataType, "timestamp") || dataType == "date"
+	default:
+		return false
+	}
+}
+
+func isIntegerType(dataType string) bool {
+	switch dataType {
+	case "smallint", "integer", "bigint":
+		return true
+	}
+	return false
+}
+
+func isNumericType(dataType string) bool {
+	switch dataType {
+	case "numeric", "real", "double precision":
+		return true
+	}
+	return isIntegerType(dataType)
+}
@@ -0,0 +1,84 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+var lintSchema = &Schema{
+	Tables: map[string]*Table{
+		"users": {
+			Name: "users",
+			Columns: []Column{
+				{Name: "id", Type: "integer", HasDefault: true},
+				{Name: "username", Type: "character varying"},
+				{Name: "password", Type: "character varying"},
+			},
+		},
+		"posts": {
+			Name: "posts",
+			Columns: []Column{
+				{Name: "id", Type: "integer", HasDefault: true},
+				{Name: "user_id", Type: "integer"},
+				{Name: "title", Type: "text"},
+			},
+			ForeignKeys: []ForeignKey{
+				{
+					Name:       "posts_user_id_fkey",
+					Columns:    []string{"user_id"},
+					RefTable:   "users",
+					RefColumns: []string{"id"},
+				},
+			},
+		},
+	},
+}
+
+func TestLintScenario(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		filename string
+		want     []string
+	}{
+		{
+			name:     "good",
+			filename: "testdata/scenario.yml",
+		},
+		{
+			name:     "bad",
+			filename: "testdata/lint.yml",
+			want: []string{
+				"line 7: users.nickname: unknown column",
+				"line 5: users.password: missing value for NOT NULL column without default",
+				"line 9: users.username: int value 3 does not fit column type character varying",
+				"line 10: users.password: null value in NOT NULL column",
+				"line 15: posts.user_id: no row in users matches [4] (foreign key posts_user_id_fkey)",
+				"line 18: comments: unknown table",
+			},
+		},
+		{
+			name:     "missing file",
+			filename: "testdata/missing.yml",
+			want: []string{
+				"open testdata/missing.yml: no such file or directory",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, finding := range LintScenario(tt.filename, lintSchema) {
+				got = append(got, finding.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintScenario() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultLockSampleInterval = 10 * time.Millisecond
+
+// execWithLockBudget executes the statements of query one at a time on a
+// dedicated connection while sampling the exclusive locks that connection
+// holds, and returns an error if any of them was held longer than the budget.
+func execWithLockBudget(ctx context.Context, db *sql.DB, query string, config *migrationConfig) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var pid int
+	if err := conn.QueryRowContext(ctx, DefaultQueries.BackendPID).Scan(&pid); err != nil {
+		return fmt.Errorf("query backend pid error: %w", err)
+	}
+
+	tracker := newLockTracker()
+	sampleCtx, stopSampling := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sampleLocks(sampleCtx, db, pid, config.lockSampleInterval, tracker)
+	}()
+
+	statements := splitStatements(query)
+	var execErr error
+	for i, statement := range statements {
+		tracker.begin(i)
+		if _, execErr = conn.ExecContext(ctx, statement); execErr != nil {
+			break
+		}
+	}
+	stopSampling()
+	wg.Wait()
+	if execErr != nil {
+		return execErr
+	}
+
+	// Holds are sorted longest first, so the worst offender is reported.
+	for _, hold := range tracker.finish(time.Now()) {
+		if hold.duration > config.lockBudget {
+			return fmt.Errorf(
+				"statement %d (%s) held %s on %s for %s, exceeding the lock budget of %s",
+				hold.statement+1,
+				statementSummary(statements[hold.statement]),
+				hold.lock.Mode,
+				hold.lock.Relation,
+				hold.duration,
+				config.lockBudget,
+			)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a migration into its statements at semicolons that
+// are not inside a quoted string, a quoted identifier, a dollar-quoted string
+// or a comment. Empty statements are dropped.
+func splitStatements(query string) []string {
+	var statements []string
+	start := 0
+	add := func(end int) {
+		if statement := strings.TrimSpace(query[start:end]); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == ';':
+			add(i)
+			start = i + 1
+		case query[i] == '\'' || query[i] == '"':
+			if end := strings.IndexByte(query[i+1:], query[i]); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+		case query[i] == '$':
+			tag := dollarQuoteTag(query[i:])
+			if tag == "" {
+				continue
+			}
+			if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+				i += len(tag) + end + len(tag) - 1
+			} else {
+				i = len(query)
+			}
+		}
+	}
+	add(len(query))
+	return statements
+}
+
+// dollarQuoteTag returns the dollar-quote tag s starts with, e.g. "$$" or
+// "$body$", or "" if s doesn't start with one.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// statementSummary shortens statement to its first line for error messages.
+func statementSummary(statement string) string {
+	const maxLen = 60
+	summary, _, more := strings.Cut(statement, "\n")
+	summary = strings.TrimSpace(summary)
+	if len(summary) > maxLen {
+		summary, more = summary[:maxLen], true
+	}
+	if more {
+		summary += " ..."
+	}
+	return summary
+}
+
+func sampleLocks(ctx context.Context, db *sql.DB, pid int, interval time.Duration, tracker *lockTracker) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		locks, err := queryExclusiveLocks(ctx, db, pid)
+		if err == nil {
+			tracker.observe(time.Now(), locks)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func queryExclusiveLocks(ctx context.Context, db *sql.DB, pid int) ([]Lock, error) {
+	rows, err := db.QueryContext(ctx, DefaultQueries.ExclusiveLocks, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []Lock
+	for rows.Next() {
+		var lock Lock
+		if err := rows.Scan(&lock.Relation, &lock.Mode); err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, rows.Err()
+}
+
+// lockHold is a continuous period during which a lock was observed.
+type lockHold struct {
+	lock     Lock
+	duration time.Duration
+	// statement is the index of the statement that was running when the
+	// lock was first observed.
+	statement int
+}
+
+// lockTracker turns pg_locks samples into the longest continuous hold time of
+// each lock. A lock that disappears between samples and shows up again, e.g.
+// because it was taken by two separate statements, counts as two holds.
+type lockTracker struct {
+	mu        sync.Mutex
+	statement int
+	holding   map[Lock]lockHold
+	since     map[Lock]time.Time
+	longest   map[Lock]lockHold
+}
+
+func newLockTracker() *lockTracker {
+	return &lockTracker{
+		holding: map[Lock]lockHold{},
+		since:   map[Lock]time.Time{},
+		longest: map[Lock]lockHold{},
+	}
+}
+
+// begin records that the statement with the given index started running, so
+// that locks observed from now on are attributed to it.
+func (t *lockTracker) begin(statement int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statement = statement
+}
+
+func (t *lockTracker) observe(now time.Time, locks []Lock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := map[Lock]bool{}
+	for _, lock := range locks {
+		seen[lock] = true
+		if _, ok := t.holding[lock]; !ok {
+			t.holding[lock] = lockHold{lock: lock, statement: t.statement}
+			t.since[lock] = now
+		}
+	}
+	for lock := range t.holding {
+		if !seen[lock] {
+			t.release(lock, now)
+		}
+	}
+}
+
+// release records a hold that ended at now; the caller must hold t.mu.
+func (t *lockTracker) release(lock Lock, now time.Time) {
+	hold := t.holding[lock]
+	hold.duration = now.Sub(t.since[lock])
+	if longest, ok := t.longest[lock]; !ok || hold.duration > longest.duration {
+		t.longest[lock] = hold
+	}
+	delete(t.holding, lock)
+	delete(t.since, lock)
+}
+
+// finish ends all holds still in progress at now and returns the longest hold
+// of every lock observed, longest first.
+func (t *lockTracker) finish(now time.Time) []lockHold {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for lock := range t.holding {
+		t.release(lock, now)
+	}
+	holds := make([]lockHold, 0, len(t.longest))
+	for _, hold := range t.longest {
+		holds = append(holds, hold)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if holds[i].duration != holds[j].duration {
+			return holds[i].duration > holds[j].duration
+		}
+		if holds[i].lock.Relation != holds[j].lock.Relation {
+			return holds[i].lock.Relation < holds[j].lock.Relation
+		}
+		return holds[i].lock.Mode < holds[j].lock.Mode
+	})
+	return holds
+}
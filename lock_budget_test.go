@@ -0,0 +1,80 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLockTracker(t *testing.T) {
+	t.Parallel()
+
+	users := Lock{Relation: "users", Mode: "AccessExclusiveLock"}
+	posts := Lock{Relation: "posts", Mode: "ShareRowExclusiveLock"}
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	tracker := newLockTracker()
+	tracker.observe(at(0), []Lock{users})
+	tracker.begin(1)
+	tracker.observe(at(10), []Lock{users, posts})
+	tracker.observe(at(20), []Lock{posts})
+	tracker.observe(at(40), nil)
+	tracker.begin(2)
+	tracker.observe(at(50), []Lock{users})
+
+	want := []lockHold{
+		{lock: posts, duration: 30 * time.Millisecond, statement: 1},
+		{lock: users, duration: 20 * time.Millisecond, statement: 0},
+	}
+	if got := tracker.finish(at(55)); !reflect.DeepEqual(got, want) {
+		t.Errorf("finish() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "statements",
+			query: "CREATE TABLE a (id int);\nALTER TABLE a ADD b text;\n",
+			want:  []string{"CREATE TABLE a (id int)", "ALTER TABLE a ADD b text"},
+		},
+		{
+			name:  "quoted",
+			query: `INSERT INTO "a;b" VALUES ('x;''y'); SELECT 1`,
+			want:  []string{`INSERT INTO "a;b" VALUES ('x;''y')`, "SELECT 1"},
+		},
+		{
+			name:  "comments",
+			query: "-- one; two\nSELECT 1; /* three; */ SELECT 2;",
+			want:  []string{"-- one; two\nSELECT 1", "/* three; */ SELECT 2"},
+		},
+		{
+			name:  "dollar quoted",
+			query: "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $$;$$",
+			want:  []string{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $$;$$"},
+		},
+		{
+			name:  "positional parameter",
+			query: "PREPARE p AS SELECT $1; EXECUTE p(1)",
+			want:  []string{"PREPARE p AS SELECT $1", "EXECUTE p(1)"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := splitStatements(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
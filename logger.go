@@ -0,0 +1,54 @@
+package sqltestutil
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logEvent logs e with logger unless it's nil: at error level if the reported
+// step failed, at debug level for health transitions, and at info level
+// otherwise.
+func logEvent(logger *slog.Logger, e Event) {
+	if logger == nil {
+		return
+	}
+	level := slog.LevelInfo
+	switch {
+	case e.Err != nil:
+		level = slog.LevelError
+	case e.Type == EventHealthChanged:
+		level = slog.LevelDebug
+	}
+	attrs := []slog.Attr{slog.String("event", string(e.Type))}
+	if e.ContainerID != "" {
+		attrs = append(attrs, slog.String("container_id", e.ContainerID))
+	}
+	if e.Image != "" {
+		attrs = append(attrs, slog.String("image", e.Image))
+	}
+	if e.Status != "" {
+		attrs = append(attrs, slog.String("status", e.Status))
+	}
+	if e.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("err", e.Err))
+	}
+	logger.LogAttrs(context.Background(), level, "sqltestutil "+string(e.Type), attrs...)
+}
+
+// emitTo emits e to the subscribers and logs it with logger, see logEvent.
+func emitTo(logger *slog.Logger, e Event) {
+	emit(e)
+	logEvent(logger, e)
+}
+
+// orDefault returns logger, or slog.Default() if it's nil, for errors that
+// must be reported even without WithLogger.
+func orDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
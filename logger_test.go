@@ -0,0 +1,89 @@
+package sqltestutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		event Event
+		want  map[string]any
+	}{
+		{
+			name:  "started",
+			event: Event{Type: EventContainerStarted, ContainerID: "abc", Image: "postgres:16", Duration: time.Second},
+			want: map[string]any{
+				"level":        "INFO",
+				"msg":          "sqltestutil container_started",
+				"event":        "container_started",
+				"container_id": "abc",
+				"image":        "postgres:16",
+				"duration":     float64(time.Second),
+			},
+		},
+		{
+			name:  "health",
+			event: Event{Type: EventHealthChanged, ContainerID: "abc", Status: "healthy"},
+			want: map[string]any{
+				"level":        "DEBUG",
+				"msg":          "sqltestutil health_changed",
+				"event":        "health_changed",
+				"container_id": "abc",
+				"status":       "healthy",
+			},
+		},
+		{
+			name:  "failed pull",
+			event: Event{Type: EventImagePulled, Image: "postgres:16", Err: errors.New("toomanyrequests")},
+			want: map[string]any{
+				"level": "ERROR",
+				"msg":   "sqltestutil image_pulled",
+				"event": "image_pulled",
+				"image": "postgres:16",
+				"err":   "toomanyrequests",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			logEvent(logger, tt.event)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("could not decode %q: %v", buf.String(), err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("logEvent() logged %v, want %v", got, tt.want)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("logEvent() logged %s = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+
+	// without a logger nothing happens
+	logEvent(nil, Event{Type: EventContainerStarted})
+}
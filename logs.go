@@ -0,0 +1,96 @@
+package sqltestutil
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Logs returns the stdout and stderr of the container, interleaved, from its
+// start on. The stream follows the logs until ctx is done, the container stops
+// or the stream is closed, which it must be.
+func (c *PostgresContainer) Logs(ctx context.Context) (io.ReadCloser, error) {
+	if c.pod != nil {
+		return nil, errPod
+	}
+	if c.external {
+		return nil, errExternal
+	}
+	cli, release, err := c.docker.open()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := cli.ContainerLogs(ctx, c.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, reader)
+		pw.CloseWithError(err)
+		release()
+	}()
+	return &logStream{PipeReader: pr, body: reader}, nil
+}
+
+// logStream is the demultiplexed log stream returned by Logs. Closing it also
+// closes the underlying response body, which unblocks the copy waiting for
+// more logs.
+type logStream struct {
+	*io.PipeReader
+	body io.Closer
+}
+
+func (s *logStream) Close() error {
+	s.PipeReader.Close()
+	return s.body.Close()
+}
+
+// StreamLogsTo logs the container's stdout and stderr with tb.Log, line by
+// line, until the test finishes, so server side errors show up next to the
+// test that caused them:
+//
+//	pg.StreamLogsTo(t)
+func (c *PostgresContainer) StreamLogsTo(tb testing.TB) {
+	tb.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logs, err := c.Logs(ctx)
+	if err != nil {
+		cancel()
+		tb.Fatalf("could not stream container logs: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logLines(logs, tb.Log)
+	}()
+	tb.Cleanup(func() {
+		cancel()
+		logs.Close()
+		// tb.Log mustn't be called once the test has finished
+		wg.Wait()
+	})
+}
+
+// logLines calls log with each line read from r, prefixed to tell it apart
+// from the test's own output.
+func logLines(r io.Reader, log func(args ...any)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log("postgres: " + scanner.Text())
+	}
+}
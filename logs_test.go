@@ -0,0 +1,34 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogLines(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	logLines(strings.NewReader("LOG:  starting PostgreSQL 16.2\nERROR:  relation \"users\" does not exist\n"), func(args ...any) {
+		got = append(got, args[0].(string))
+	})
+	want := []string{
+		"postgres: LOG:  starting PostgreSQL 16.2",
+		`postgres: ERROR:  relation "users" does not exist`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logLines() logged %q, want %q", got, want)
+	}
+}
+
+func TestLogsExternal(t *testing.T) {
+	t.Parallel()
+
+	pg := &PostgresContainer{external: true}
+	if _, err := pg.Logs(context.Background()); !errors.Is(err, errExternal) {
+		t.Errorf("Logs() error = %v, want %v", err, errExternal)
+	}
+}
@@ -0,0 +1,176 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maintenanceDatabase holds the record of the last maintenance run. It's the
+// default database every Postgres server has, so the record doesn't show up in
+// the schema of the databases tests use.
+const maintenanceDatabase = "postgres"
+
+// maintenanceTable is the table the last maintenance run is recorded in.
+const maintenanceTable = "sqltestutil_maintenance"
+
+// MaintenancePolicy configures PostgresContainer.Maintain, and WithMaintenance
+// for reused containers.
+type MaintenancePolicy struct {
+	// Interval is the minimum time between maintenance runs. Zero runs
+	// maintenance on every call.
+	Interval time.Duration
+	// SkipReindex leaves out ReindexDatabase, which is the slowest step.
+	SkipReindex bool
+}
+
+// VacuumFull runs VACUUM FULL ANALYZE in every database of the container,
+// returning the space taken by dead rows to the operating system and
+// refreshing planner statistics.
+func (c *PostgresContainer) VacuumFull(ctx context.Context) error {
+	return c.eachDatabase(ctx, func(db *sql.DB, name string) error {
+		_, err := db.ExecContext(ctx, DefaultQueries.VacuumFull)
+		return err
+	})
+}
+
+// ReindexDatabase rebuilds all indexes in every database of the container.
+func (c *PostgresContainer) ReindexDatabase(ctx context.Context) error {
+	return c.eachDatabase(ctx, func(db *sql.DB, name string) error {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("REINDEX DATABASE %q", name))
+		return err
+	})
+}
+
+// ResetStats resets the cumulative statistics of every database of the
+// container and of the server, so that tests inspecting pg_stat views start
+// from zero. With ProfileManaged it does nothing, since resetting statistics
+// takes superuser privileges.
+func (c *PostgresContainer) ResetStats(ctx context.Context) error {
+	if c.profile == ProfileManaged {
+		return nil
+	}
+	err := c.eachDatabase(ctx, func(db *sql.DB, name string) error {
+		_, err := db.ExecContext(ctx, DefaultQueries.ResetStats)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return c.withDatabase(ctx, maintenanceDatabase, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, DefaultQueries.ResetSharedStats)
+		return err
+	})
+}
+
+// Maintain runs VacuumFull, ReindexDatabase and ResetStats if the last run
+// recorded in the container is older than policy.Interval, and reports whether
+// it did. It's meant for containers that are reused for a long time, e.g.
+// across local test runs, which otherwise accumulate bloat from countless
+// created and dropped databases and slowly get slower, making timing-sensitive
+// tests flaky. Call it once before the tests run.
+func (c *PostgresContainer) Maintain(ctx context.Context, policy MaintenancePolicy) (bool, error) {
+	var lastRun sql.NullTime
+	err := c.withDatabase(ctx, maintenanceDatabase, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, DefaultQueries.CreateMaintenanceTable)
+		if err != nil {
+			return err
+		}
+		err = db.QueryRowContext(ctx, DefaultQueries.LastMaintenance).Scan(&lastRun)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("read maintenance record error: %w", err)
+	}
+	if !maintenanceDue(lastRun, time.Now(), policy.Interval) {
+		return false, nil
+	}
+
+	if err := c.VacuumFull(ctx); err != nil {
+		return false, fmt.Errorf("vacuum error: %w", err)
+	}
+	if !policy.SkipReindex {
+		if err := c.ReindexDatabase(ctx); err != nil {
+			return false, fmt.Errorf("reindex error: %w", err)
+		}
+	}
+	if err := c.ResetStats(ctx); err != nil {
+		return false, fmt.Errorf("reset stats error: %w", err)
+	}
+
+	err = c.withDatabase(ctx, maintenanceDatabase, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, DefaultQueries.ClearMaintenance)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, DefaultQueries.RecordMaintenance)
+		return err
+	})
+	if err != nil {
+		return true, fmt.Errorf("write maintenance record error: %w", err)
+	}
+	return true, nil
+}
+
+// maintenanceDue reports whether maintenance last run at lastRun is due again
+// at now.
+func maintenanceDue(lastRun sql.NullTime, now time.Time, interval time.Duration) bool {
+	return !lastRun.Valid || now.Sub(lastRun.Time) >= interval
+}
+
+// eachDatabase calls fn with a connection to every database of the container
+// that accepts connections, except the templates. With ProfileManaged, only
+// the databases the user owns are included.
+func (c *PostgresContainer) eachDatabase(ctx context.Context, fn func(db *sql.DB, name string) error) error {
+	var names []string
+	err := c.withDatabase(ctx, c.dbName, func(db *sql.DB) error {
+		query := DefaultQueries.ListDatabases
+		if c.profile == ProfileManaged {
+			query = DefaultQueries.ListOwnedDatabases
+		}
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("list databases error: %w", err)
+	}
+
+	var errs []error
+	for _, name := range names {
+		err := c.withDatabase(ctx, name, func(db *sql.DB) error {
+			return fn(db, name)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("database %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withDatabase calls fn with a connection to the named database of the
+// container.
+func (c *PostgresContainer) withDatabase(ctx context.Context, name string, fn func(db *sql.DB) error) error {
+	connStr, err := c.connectionStringFor(name)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(db)
+}
@@ -0,0 +1,51 @@
+package sqltestutil
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		lastRun  sql.NullTime
+		interval time.Duration
+		want     bool
+	}{
+		{
+			name:     "never run",
+			interval: 24 * time.Hour,
+			want:     true,
+		},
+		{
+			name:     "recent",
+			lastRun:  sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+			interval: 24 * time.Hour,
+			want:     false,
+		},
+		{
+			name:     "old",
+			lastRun:  sql.NullTime{Time: now.Add(-7 * 24 * time.Hour), Valid: true},
+			interval: 24 * time.Hour,
+			want:     true,
+		},
+		{
+			name:    "no interval",
+			lastRun: sql.NullTime{Time: now, Valid: true},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := maintenanceDue(tt.lastRun, now, tt.interval); got != tt.want {
+				t.Errorf("maintenanceDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
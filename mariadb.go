@@ -0,0 +1,162 @@
+package sqltestutil
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// mariaDBStartupTimeout is the default StartupTimeout for MariaDB, which takes
+// longer than Postgres to initialize.
+const mariaDBStartupTimeout = time.Minute
+
+// MariaDBContainer is a Docker container running MariaDB. It can be used to
+// cheaply start a throwaway MariaDB instance for testing.
+type MariaDBContainer struct {
+	id       string
+	user     string
+	password string
+	dbName   string
+	port     string
+	connStr  string
+
+	docker dockerConfig
+}
+
+// StartMariaDBContainer starts a new MariaDB Docker container, analogous to
+// StartPostgresContainer. The version parameter is the tagged version of the
+// mariadb image to use, e.g. to use mariadb:11 pass "11". The options for the
+// Docker client, image pulls, the reaper and the host apply; of those
+// configuring the database only WithDBName, WithDBUser, WithDBPassword,
+// WithTimeZone and WithStartupTimeout do, and the user defaults to "test" with
+// a random password, and the database to "test". The root user gets the same
+// password.
+//
+// The connection string is meant for the github.com/go-sql-driver/mysql driver,
+// which has to be imported by the caller.
+func StartMariaDBContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*MariaDBContainer, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &PostgresContainerConfig{
+		DBName:     "test",
+		DBUser:     "test",
+		DBPassword: password,
+		TimeZone:   "UTC",
+		SSLMode:    "disable",
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	spec := mariaDBSpec(version, config)
+	containerID, port, err := runContainer(ctx, cli, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	host := containerHost(cli, config.HostOverride)
+	connStr := mysqlDSN(config.DBUser, config.DBPassword, host, port, config.DBName)
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(mariaDBStartupTimeout))
+	defer cancel()
+
+	// wait until the server is reachable through the published port
+	if err := waitUntilMySQLReady(waitCtx, net.JoinHostPort(host, port)); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &MariaDBContainer{
+		id:       containerID,
+		user:     config.DBUser,
+		password: config.DBPassword,
+		dbName:   config.DBName,
+		port:     port,
+		connStr:  connStr,
+		docker:   config.docker(),
+	}, nil
+}
+
+func mariaDBSpec(version string, config *PostgresContainerConfig) containerSpec {
+	env := []string{
+		"MARIADB_DATABASE=" + config.DBName,
+		"MARIADB_ROOT_PASSWORD=" + config.DBPassword,
+		"TZ=" + config.TimeZone,
+	}
+	if config.DBUser != "root" {
+		env = append(env,
+			"MARIADB_USER="+config.DBUser,
+			"MARIADB_PASSWORD="+config.DBPassword,
+		)
+	}
+
+	return containerSpec{
+		image:   "mariadb:" + version,
+		env:     env,
+		port:    "3306/tcp",
+		timeout: config.startupTimeout(mariaDBStartupTimeout),
+		pull:    config.imagePull(),
+		reaper:  config.Reaper,
+		healthcheck: &container.HealthConfig{
+			// the entrypoint runs a temporary server without networking while
+			// initializing, so only answer once the real server listens on TCP
+			Test: []string{
+				"CMD-SHELL",
+				"mariadb-admin ping -h 127.0.0.1 -u root -p\"$MARIADB_ROOT_PASSWORD\" --silent || " +
+					"mysqladmin ping -h 127.0.0.1 -u root -p\"$MARIADB_ROOT_PASSWORD\" --silent",
+			},
+			Interval: time.Second,
+			Timeout:  time.Second,
+			Retries:  30,
+		},
+	}
+}
+
+// ConnectionString returns a DSN for the github.com/go-sql-driver/mysql driver
+// that can be used to connect to the running MariaDB container.
+func (c *MariaDBContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running MariaDB container.
+func (c *MariaDBContainer) ID() string {
+	return c.id
+}
+
+// User returns the name of the database user.
+func (c *MariaDBContainer) User() string {
+	return c.user
+}
+
+// Password returns the password of the database user.
+func (c *MariaDBContainer) Password() string {
+	return c.password
+}
+
+// Database returns the name of the database created on startup.
+func (c *MariaDBContainer) Database() string {
+	return c.dbName
+}
+
+// Shutdown cleans up the MariaDB container by stopping and removing it.
+func (c *MariaDBContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
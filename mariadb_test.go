@@ -0,0 +1,50 @@
+package sqltestutil
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStartMariaDBContainer(t *testing.T) {
+	t.Parallel()
+
+	port := fakeMySQL(t)
+	var created container.Config
+	cli, _ := newFakeDocker(t, runRoutes("mariadb:11", "3306/tcp", port, &created))
+
+	c, err := StartMariaDBContainer(context.Background(), "11",
+		WithDockerClient(cli),
+		WithDBUser("app"),
+		WithDBPassword("secret"),
+		WithDBName("shop"),
+	)
+	if err != nil {
+		t.Fatalf("StartMariaDBContainer() error = %v", err)
+	}
+	if created.Image != "mariadb:11" {
+		t.Errorf("image = %s, want mariadb:11", created.Image)
+	}
+	wantEnv := []string{
+		"MARIADB_DATABASE=shop",
+		"MARIADB_ROOT_PASSWORD=secret",
+		"TZ=UTC",
+		"MARIADB_USER=app",
+		"MARIADB_PASSWORD=secret",
+	}
+	if !slices.Equal(created.Env, wantEnv) {
+		t.Errorf("env = %v, want %v", created.Env, wantEnv)
+	}
+	if _, ok := created.ExposedPorts["3306/tcp"]; !ok {
+		t.Errorf("exposed ports = %v, want 3306/tcp", created.ExposedPorts)
+	}
+	if created.Healthcheck == nil {
+		t.Error("healthcheck = nil, want the mariadb-admin ping")
+	}
+	want := "app:secret@tcp(127.0.0.1:" + port + ")/shop?parseTime=true&multiStatements=true"
+	if got := c.ConnectionString(); got != want {
+		t.Errorf("ConnectionString() = %s, want %s", got, want)
+	}
+}
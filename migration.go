@@ -3,10 +3,12 @@ package sqltestutil
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 )
 
 // ExecerContext is an interface used by MustExecContext and LoadFileContext
@@ -22,23 +24,151 @@ type ExecerContext interface {
 //	002_create_posts.up.sql
 //	003_create_comments.up.sql
 //
-// Note that this function does not check whether the migration has already been
-// run. Its primary purpose is to initialize a test database.
-func RunMigrations(ctx context.Context, db ExecerContext, migrationDir string) error {
-	filenames, err := filepath.Glob(filepath.Join(migrationDir, "*.up.sql"))
+// Note that by default this function does not check whether the migration has
+// already been run, since its primary purpose is to initialize a test database;
+// see WithMigrationTracking. The behavior can be extended with other options,
+// e.g. WithLockBudget.
+func RunMigrations(
+	ctx context.Context,
+	db ExecerContext,
+	migrationDir string,
+	options ...MigrationOption,
+) error {
+	config := &migrationConfig{
+		lockSampleInterval: defaultLockSampleInterval,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	var sqlDB *sql.DB
+	if config.lockBudget > 0 {
+		var ok bool
+		if sqlDB, ok = db.(*sql.DB); !ok {
+			return errors.New("WithLockBudget requires db to be a *sql.DB")
+		}
+	}
+
+	var tracker *migrationTracker
+	if config.tracking {
+		var err error
+		if tracker, err = newMigrationTracker(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	filenames, err := migrationFilenames(migrationDir)
 	if err != nil {
-		return fmt.Errorf("glob migrationDir error: %w", err)
+		return err
 	}
-	sort.Strings(filenames)
 	for _, filename := range filenames {
 		data, err := os.ReadFile(filename)
 		if err != nil {
 			return fmt.Errorf("read file error: %w", err)
 		}
-		_, err = db.ExecContext(ctx, string(data))
+		if tracker != nil {
+			applied, err := tracker.isApplied(filename, data)
+			if err != nil {
+				return err
+			}
+			if applied {
+				continue
+			}
+		}
+		start := time.Now()
+		err = config.retry.do(ctx, func() error {
+			if sqlDB != nil {
+				return execWithLockBudget(ctx, sqlDB, string(data), config)
+			}
+			_, err := db.ExecContext(ctx, string(data))
+			return err
+		})
+		duration := time.Since(start)
+		if config.reporter != nil {
+			config.reporter.MigrationApplied(MigrationEvent{
+				Filename: filepath.Base(filename),
+				Duration: duration,
+				Err:      err,
+			})
+		}
+		emit(Event{Type: EventMigrationApplied, Filename: filepath.Base(filename), Duration: duration, Err: err})
 		if err != nil {
-			return fmt.Errorf("exec file error: %w", err)
+			return fmt.Errorf("exec file %s error: %w", filepath.Base(filename), err)
+		}
+		if tracker != nil {
+			if err := tracker.record(ctx, filename, data, start, time.Since(start)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
+
+// migrationFilenames returns the migration files in migrationDir in the order
+// RunMigrations applies them.
+func migrationFilenames(migrationDir string) ([]string, error) {
+	filenames, err := filepath.Glob(filepath.Join(migrationDir, "*.up.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("glob migrationDir error: %w", err)
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+type migrationConfig struct {
+	lockBudget         time.Duration
+	lockSampleInterval time.Duration
+	reporter           Reporter
+	tracking           bool
+	retry              *RetryPolicy
+}
+
+// MigrationOption configures RunMigrations.
+type MigrationOption func(*migrationConfig)
+
+// WithLockBudget makes RunMigrations fail when a migration holds an exclusive
+// lock on a table (ShareRowExclusiveLock, ExclusiveLock or
+// AccessExclusiveLock) for longer than budget, which enforces zero-downtime
+// migration discipline in tests. Lock hold times are measured per statement by
+// sampling pg_locks from a second connection while each migration runs, so db
+// must be a *sql.DB and the measurement is only as precise as the sample
+// interval, see WithLockSampleInterval.
+//
+// To tell the locks of statements apart, the statements of a migration are
+// executed one at a time instead of as a single implicit transaction. A
+// migration that must be atomic should wrap them in BEGIN and COMMIT; its
+// locks are then held until COMMIT, as they would be in production, and
+// count against the statement that took them.
+func WithLockBudget(budget time.Duration) MigrationOption {
+	return func(c *migrationConfig) {
+		c.lockBudget = budget
+	}
+}
+
+// WithLockSampleInterval sets how often pg_locks is sampled when a lock
+// budget is set. The default is 10ms.
+func WithLockSampleInterval(interval time.Duration) MigrationOption {
+	return func(c *migrationConfig) {
+		c.lockSampleInterval = interval
+	}
+}
+
+// WithMigrationRetry makes RunMigrations retry a migration file that fails
+// with a transient error, such as a serialization failure, a deadlock or a
+// dropped connection while the container warms up, as policy allows, e.g.
+// DefaultRetryPolicy. The whole file is executed again, so this is only safe
+// when a failed file leaves nothing behind, as with Postgres, which runs a
+// multi-statement file in one implicit transaction.
+func WithMigrationRetry(policy RetryPolicy) MigrationOption {
+	return func(c *migrationConfig) {
+		c.retry = &policy
+	}
+}
+
+// WithMigrationReporter makes RunMigrations report each applied migration to
+// r, e.g. a PrettyReporter.
+func WithMigrationReporter(r Reporter) MigrationOption {
+	return func(c *migrationConfig) {
+		c.reporter = r
+	}
+}
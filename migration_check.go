@@ -0,0 +1,197 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MigrationCheck is the result of CheckMigrations.
+type MigrationCheck struct {
+	// Migrations holds a result per migration file, up to and including the
+	// first one that failed.
+	Migrations []MigrationCheckResult
+}
+
+// MigrationCheckResult is the result of applying one migration file.
+type MigrationCheckResult struct {
+	Filename string
+	Duration time.Duration
+	// Locks lists the table-level locks the migration held when it finished.
+	// It's empty for migrations that can't run inside a transaction, such as
+	// CREATE INDEX CONCURRENTLY.
+	Locks []Lock
+	Err   error
+}
+
+// Lock is a lock held on a relation.
+type Lock struct {
+	Relation string
+	// Mode is the lock mode, e.g. "AccessExclusiveLock".
+	Mode string
+}
+
+// CheckMigrations is a dry run of the migrations in dir, as RunMigrations
+// would apply them, against a throwaway clone of the container's database. It
+// reports how long each migration took and which locks it took, so a new
+// migration can be validated against realistic data without touching the
+// database tests use.
+//
+// Migrations recorded in the tracking table of the database, see
+// WithMigrationTracking, are skipped, so only the pending ones are checked
+// against an already migrated database. As with RunMigrations, a recorded
+// migration whose file has changed since is an error.
+//
+// The clone is created with CREATE DATABASE ... TEMPLATE, which requires that
+// nobody else is connected to the container's database at the time. Each
+// migration runs in its own transaction so that its locks can be observed
+// before it commits.
+//
+// The returned error is non-nil if a migration failed; the MigrationCheck is
+// returned either way once the clone exists.
+func CheckMigrations(ctx context.Context, c *PostgresContainer, dir string) (*MigrationCheck, error) {
+	filenames, err := migrationFilenames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	cloneName := "sqltestutil_check_" + strings.ToLower(suffix[:16])
+
+	admin, err := c.open(c.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	_, err = admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %q TEMPLATE %q", cloneName, c.dbName))
+	if err != nil {
+		return nil, fmt.Errorf("clone database error: %w", err)
+	}
+	defer func() {
+		_, dropErr := admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %q", cloneName))
+		if dropErr != nil {
+			orDefault(c.logger).Error("could not drop clone database", "database", cloneName, "err", dropErr)
+		}
+	}()
+
+	connStr, err := c.connectionStringFor(cloneName)
+	if err != nil {
+		return nil, err
+	}
+	clone, err := c.open(connStr)
+	if err != nil {
+		return nil, err
+	}
+	defer clone.Close()
+
+	return checkMigrations(ctx, clone, filenames)
+}
+
+// checkMigrations applies the migrations of filenames that aren't recorded in
+// the tracking table of db with checkMigration, stopping at the first one that
+// fails.
+func checkMigrations(ctx context.Context, db *sql.DB, filenames []string) (*MigrationCheck, error) {
+	// the tracking table is created in the clone if it doesn't exist, in
+	// which case every migration is pending
+	tracker, err := newMigrationTracker(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &MigrationCheck{}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return check, fmt.Errorf("read file error: %w", err)
+		}
+		applied, err := tracker.isApplied(filename, data)
+		if err != nil {
+			return check, err
+		}
+		if applied {
+			continue
+		}
+		result := checkMigration(ctx, db, string(data))
+		result.Filename = filepath.Base(filename)
+		check.Migrations = append(check.Migrations, result)
+		if result.Err != nil {
+			return check, fmt.Errorf("migration %s failed: %w", result.Filename, result.Err)
+		}
+	}
+	return check, nil
+}
+
+// checkMigration applies a migration in a transaction and records the locks it
+// holds before committing. Migrations that can't run in a transaction are
+// applied directly instead.
+func checkMigration(ctx context.Context, db *sql.DB, query string) MigrationCheckResult {
+	var result MigrationCheckResult
+
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		if sqlState(err) != "25001" { // active_sql_transaction
+			result.Err = err
+			return result
+		}
+		start = time.Now()
+		_, result.Err = db.ExecContext(ctx, query)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	result.Locks, err = ownLocks(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		result.Err = err
+		return result
+	}
+	result.Err = tx.Commit()
+	result.Duration = time.Since(start)
+	return result
+}
+
+func ownLocks(ctx context.Context, tx *sql.Tx) ([]Lock, error) {
+	rows, err := tx.QueryContext(ctx, DefaultQueries.OwnLocks)
+	if err != nil {
+		return nil, fmt.Errorf("query locks error: %w", err)
+	}
+	defer rows.Close()
+
+	var locks []Lock
+	for rows.Next() {
+		var lock Lock
+		if err := rows.Scan(&lock.Relation, &lock.Mode); err != nil {
+			return nil, fmt.Errorf("scan lock error: %w", err)
+		}
+		locks = append(locks, lock)
+	}
+	return locks, rows.Err()
+}
+
+// sqlState returns the SQLSTATE code of a database error, or an empty string
+// if err doesn't carry one. pgx errors implement SQLState.
+func sqlState(err error) string {
+	var stateErr interface {
+		SQLState() string
+	}
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckMigration(t *testing.T) {
+	t.Parallel()
+
+	const query = "CREATE INDEX users_email ON users (email)"
+	lockColumns := []string{"relname", "mode"}
+	failed := errors.New("syntax error")
+
+	tests := []struct {
+		name      string
+		expect    func(mock sqlmock.Sqlmock)
+		wantLocks []Lock
+		wantErr   error
+	}{
+		{
+			name: "in transaction",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("FROM pg_locks").WillReturnRows(sqlmock.NewRows(lockColumns).
+					AddRow("users", "ShareLock").
+					AddRow("users_email", "AccessExclusiveLock"))
+				mock.ExpectCommit()
+			},
+			wantLocks: []Lock{
+				{Relation: "users", Mode: "ShareLock"},
+				{Relation: "users_email", Mode: "AccessExclusiveLock"},
+			},
+		},
+		{
+			name: "failed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(failed)
+				mock.ExpectRollback()
+			},
+			wantErr: failed,
+		},
+		{
+			name: "outside transaction",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(stateError("25001"))
+				mock.ExpectRollback()
+				mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "locks failed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("FROM pg_locks").WillReturnError(failed)
+				mock.ExpectRollback()
+			},
+			wantErr: failed,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+			tt.expect(mock)
+
+			result := checkMigration(context.Background(), db, query)
+			if !errors.Is(result.Err, tt.wantErr) || (result.Err == nil) != (tt.wantErr == nil) {
+				t.Errorf("checkMigration() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(result.Locks, tt.wantLocks) {
+				t.Errorf("checkMigration() locks = %v, want %v", result.Locks, tt.wantLocks)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestOwnLocks(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("WHERE l.pid = pg_backend_pid()").WillReturnRows(sqlmock.NewRows([]string{"relname", "mode"}))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	locks, err := ownLocks(context.Background(), tx)
+	if err != nil {
+		t.Fatalf("ownLocks() error = %v", err)
+	}
+	if len(locks) != 0 {
+		t.Errorf("ownLocks() = %v, want none", locks)
+	}
+}
+
+func TestCheckMigrationsSkipsApplied(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_users.up.sql": "CREATE TABLE users (id int)",
+		"002_posts.up.sql": "CREATE TABLE posts (id int)",
+	}
+	for name, query := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(query), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filenames, err := migrationFilenames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS sqltestutil_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, filename, checksum, applied_at, duration_ms").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "filename", "checksum", "applied_at", "duration_ms"}).
+			AddRow("001", "001_users.up.sql", migrationChecksum([]byte(files["001_users.up.sql"])), time.Now(), 3))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(files["002_posts.up.sql"])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM pg_locks").WillReturnRows(sqlmock.NewRows([]string{"relname", "mode"}))
+	mock.ExpectCommit()
+
+	check, err := checkMigrations(context.Background(), db, filenames)
+	if err != nil {
+		t.Fatalf("checkMigrations() error = %v", err)
+	}
+	if len(check.Migrations) != 1 || check.Migrations[0].Filename != "002_posts.up.sql" {
+		t.Errorf("checkMigrations() = %+v, want only 002_posts.up.sql", check.Migrations)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,136 @@
+package sqltestutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// migrationTable is the table WithMigrationTracking records applied
+// migrations in.
+const migrationTable = "sqltestutil_migrations"
+
+// AppliedMigration is a migration recorded in the tracking table, see
+// WithMigrationTracking.
+type AppliedMigration struct {
+	// Version is the prefix of the filename up to the first underscore, dash or
+	// dot, e.g. "001" for 001_create_users.up.sql.
+	Version  string
+	Filename string
+	// Checksum is the hex encoded SHA-256 of the file's contents.
+	Checksum  string
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+// AppliedMigrations returns the migrations recorded in the tracking table of
+// db in the order they were applied, so tests can assert the migration state
+// of a database. The table only exists if RunMigrations has been run with
+// WithMigrationTracking.
+func AppliedMigrations(ctx context.Context, db QueryerContext) ([]AppliedMigration, error) {
+	rows, err := db.QueryContext(ctx, DefaultQueries.AppliedMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("query applied migrations error: %w", err)
+	}
+	defer rows.Close()
+
+	var migrations []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		var durationMS int64
+		if err := rows.Scan(&m.Version, &m.Filename, &m.Checksum, &m.AppliedAt, &durationMS); err != nil {
+			return nil, fmt.Errorf("scan applied migration error: %w", err)
+		}
+		m.Duration = time.Duration(durationMS) * time.Millisecond
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
+
+// WithMigrationTracking makes RunMigrations record each applied migration in
+// a sqltestutil_migrations table, and skip migrations that are already
+// recorded there, which makes it safe to run against a database repeatedly.
+// A recorded migration whose file has changed since is an error. db must also
+// implement QueryerContext. See AppliedMigrations.
+func WithMigrationTracking() MigrationOption {
+	return func(c *migrationConfig) {
+		c.tracking = true
+	}
+}
+
+// migrationTracker reads and writes the tracking table for RunMigrations.
+type migrationTracker struct {
+	db ExecerContext
+	// applied maps filenames to checksums.
+	applied map[string]string
+}
+
+func newMigrationTracker(ctx context.Context, db ExecerContext) (*migrationTracker, error) {
+	queryer, ok := db.(QueryerContext)
+	if !ok {
+		return nil, fmt.Errorf("WithMigrationTracking requires db to implement QueryerContext")
+	}
+	if _, err := db.ExecContext(ctx, DefaultQueries.CreateMigrationTable); err != nil {
+		return nil, fmt.Errorf("create migration table error: %w", err)
+	}
+	applied, err := AppliedMigrations(ctx, queryer)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &migrationTracker{
+		db:      db,
+		applied: map[string]string{},
+	}
+	for _, m := range applied {
+		t.applied[m.Filename] = m.Checksum
+	}
+	return t, nil
+}
+
+// isApplied reports whether the migration has been applied before, and fails
+// if its contents changed since.
+func (t *migrationTracker) isApplied(filename string, data []byte) (bool, error) {
+	checksum, ok := t.applied[filepath.Base(filename)]
+	if !ok {
+		return false, nil
+	}
+	if checksum != migrationChecksum(data) {
+		return true, fmt.Errorf("migration %s changed since it was applied", filepath.Base(filename))
+	}
+	return true, nil
+}
+
+func (t *migrationTracker) record(ctx context.Context, filename string, data []byte, appliedAt time.Time, duration time.Duration) error {
+	base := filepath.Base(filename)
+	_, err := t.db.ExecContext(
+		ctx,
+		DefaultQueries.RecordMigration,
+		base,
+		migrationVersion(base),
+		migrationChecksum(data),
+		appliedAt,
+		duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("record migration %s error: %w", base, err)
+	}
+	return nil
+}
+
+// migrationVersion returns the version prefix of a migration filename.
+func migrationVersion(filename string) string {
+	if i := strings.IndexAny(filename, "_-."); i >= 0 {
+		return filename[:i]
+	}
+	return filename
+}
+
+func migrationChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
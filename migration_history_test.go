@@ -0,0 +1,101 @@
+package sqltestutil
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRunMigrationsWithTracking(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("testdata/0001-init.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	appliedAt := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	columns := []string{"version", "filename", "checksum", "applied_at", "duration_ms"}
+
+	tests := []struct {
+		name    string
+		applied *sqlmock.Rows
+		expect  func(mock sqlmock.Sqlmock)
+		wantErr bool
+	}{
+		{
+			name:    "fresh",
+			applied: sqlmock.NewRows(columns),
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(string(data))).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO sqltestutil_migrations").
+					WithArgs("0001-init.up.sql", "0001", migrationChecksum(data), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "already applied",
+			applied: sqlmock.NewRows(columns).
+				AddRow("0001", "0001-init.up.sql", migrationChecksum(data), appliedAt, 12),
+			expect: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name: "changed",
+			applied: sqlmock.NewRows(columns).
+				AddRow("0001", "0001-init.up.sql", "old", appliedAt, 12),
+			expect:  func(mock sqlmock.Sqlmock) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS sqltestutil_migrations").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("SELECT version, filename, checksum, applied_at, duration_ms").
+				WillReturnRows(tt.applied)
+			tt.expect(mock)
+
+			err = RunMigrations(context.Background(), db, "testdata", WithMigrationTracking())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMigrationVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "001_create_users.up.sql", want: "001"},
+		{filename: "0001-init.up.sql", want: "0001"},
+		{filename: "20240108.up.sql", want: "20240108"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.filename, func(t *testing.T) {
+			t.Parallel()
+			if got := migrationVersion(tt.filename); got != tt.want {
+				t.Errorf("migrationVersion(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package sqltestutil
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// NamingPolicy is the naming convention for migration files enforced by
+// ValidateMigrationNames.
+type NamingPolicy int
+
+const (
+	// NamingSequential requires numeric versions zero-padded to the same
+	// width, e.g. 001_create_users.up.sql. It's the default.
+	NamingSequential NamingPolicy = iota
+	// NamingTimestamp requires versions that are UTC timestamps in the
+	// format YYYYMMDDHHMMSS, e.g. 20240131120000_create_users.up.sql.
+	NamingTimestamp
+)
+
+// timestampVersionLayout is the layout of NamingTimestamp versions.
+const timestampVersionLayout = "20060102150405"
+
+// ValidateMigrationNames checks that the names of the migration files in
+// migrationDir follow policy, so that the lexicographical order in which
+// RunMigrations applies them is also their numeric order. Without padding,
+// 10_add_index.up.sql sorts before 2_create_table.up.sql and is silently
+// applied first. Duplicate versions are reported as well. All problems are
+// returned together as one error. It's meant to be called from a test:
+//
+//	func TestMigrationNames(t *testing.T) {
+//	    if err := sqltestutil.ValidateMigrationNames("migrations", sqltestutil.NamingSequential); err != nil {
+//	        t.Error(err)
+//	    }
+//	}
+func ValidateMigrationNames(migrationDir string, policy NamingPolicy) error {
+	filenames, err := migrationFilenames(migrationDir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(filenames))
+	for i, filename := range filenames {
+		names[i] = filepath.Base(filename)
+	}
+	if errs := checkMigrationNames(names, policy); len(errs) > 0 {
+		return fmt.Errorf("invalid migration names: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// checkMigrationNames returns the problems with the given migration file
+// names, which are in lexicographical order.
+func checkMigrationNames(names []string, policy NamingPolicy) []error {
+	width := 0
+	for _, name := range names {
+		width = max(width, len(migrationVersion(name)))
+	}
+
+	var errs []error
+	seen := map[uint64]string{}
+	// highest is the name with the highest version so far.
+	var highest string
+	var highestNumber uint64
+	for _, name := range names {
+		version := migrationVersion(name)
+		number, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: version %q is not numeric", name, version))
+			continue
+		}
+
+		switch policy {
+		case NamingTimestamp:
+			if _, err := time.Parse(timestampVersionLayout, version); err != nil {
+				errs = append(errs, fmt.Errorf("%s: version %q is not a timestamp in the format YYYYMMDDHHMMSS", name, version))
+			}
+		default:
+			if len(version) != width {
+				errs = append(errs, fmt.Errorf("%s: version %q is not zero-padded to %d digits", name, version, width))
+			}
+		}
+
+		if other, ok := seen[number]; ok {
+			errs = append(errs, fmt.Errorf("%s: version %s is also used by %s", name, version, other))
+		}
+		seen[number] = name
+		if highest != "" && number < highestNumber {
+			errs = append(errs, fmt.Errorf("%s sorts after %s but has a lower version, so it's applied out of order", name, highest))
+		}
+		if highest == "" || number > highestNumber {
+			highest, highestNumber = name, number
+		}
+	}
+	return errs
+}
@@ -0,0 +1,78 @@
+package sqltestutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateMigrationNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		files   []string
+		policy  NamingPolicy
+		wantErr []string
+	}{
+		{
+			name:  "sequential",
+			files: []string{"001_users.up.sql", "002_posts.up.sql", "010_index.up.sql", "002_posts.down.sql"},
+		},
+		{
+			name:    "not padded",
+			files:   []string{"1_users.up.sql", "2_posts.up.sql", "10_index.up.sql"},
+			wantErr: []string{`2_posts.up.sql: version "2" is not zero-padded to 2 digits`, "2_posts.up.sql sorts after 10_index.up.sql"},
+		},
+		{
+			name:    "duplicate",
+			files:   []string{"001_users.up.sql", "001_posts.up.sql"},
+			wantErr: []string{"version 001 is also used by 001_posts.up.sql"},
+		},
+		{
+			name:    "not numeric",
+			files:   []string{"001_users.up.sql", "users.up.sql"},
+			wantErr: []string{`users.up.sql: version "users" is not numeric`},
+		},
+		{
+			name:   "timestamps",
+			files:  []string{"20240131120000_users.up.sql", "20240201083000_posts.up.sql"},
+			policy: NamingTimestamp,
+		},
+		{
+			name:    "not a timestamp",
+			files:   []string{"20240131120000_users.up.sql", "002_posts.up.sql"},
+			policy:  NamingTimestamp,
+			wantErr: []string{`002_posts.up.sql: version "002" is not a timestamp`},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := ValidateMigrationNames(dir, tt.policy)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("ValidateMigrationNames() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateMigrationNames() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateMigrationNames() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
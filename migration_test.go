@@ -6,6 +6,7 @@ import (
 	"errors"
 	"log"
 	"testing"
+	"time"
 )
 
 func TestRunMigrations(t *testing.T) {
@@ -14,6 +15,7 @@ func TestRunMigrations(t *testing.T) {
 	type args struct {
 		db           ExecerContext
 		migrationDir string
+		options      []MigrationOption
 	}
 	tests := []struct {
 		name    string
@@ -28,6 +30,15 @@ func TestRunMigrations(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "lock budget without sql.DB",
+			args: args{
+				db:           &mockExecerContext{},
+				migrationDir: "testdata",
+				options:      []MigrationOption{WithLockBudget(time.Second)},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -35,7 +46,7 @@ func TestRunMigrations(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := RunMigrations(context.Background(), tt.args.db, tt.args.migrationDir)
+			err := RunMigrations(context.Background(), tt.args.db, tt.args.migrationDir, tt.args.options...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RunMigrations() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -0,0 +1,90 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// StartPostgresContainers starts n independent Postgres containers
+// concurrently and waits until all of them are ready, for tests of e.g.
+// replication between databases or multi-primary application logic. The
+// version and options have the same meaning as for StartPostgresContainer and
+// apply to every container; each gets its own port and, unless set with
+// WithDBPassword, its own password.
+//
+// The containers are always new, so WithReuse and WithContainerName can't be
+// used. The image is pulled once up front. If any container fails to start, the
+// others are shut down and the errors of all failed containers are returned
+// together. Otherwise each returned container should be stopped with its
+// Shutdown method.
+func StartPostgresContainers(
+	ctx context.Context,
+	n int,
+	version string,
+	options ...Option,
+) ([]*PostgresContainer, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("n must be at least 1, got %d", n)
+	}
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if n > 1 && config.HostPort != 0 {
+		return nil, errors.New("containers can't share a host port, so WithHostPort can't be used")
+	}
+	if config.ReuseKey != "" || config.ContainerName != "" {
+		return nil, errors.New("containers are always started anew, so WithReuse and WithContainerName can't be used")
+	}
+
+	builders := make([]*PostgresContainerBuilder, 0, n)
+	defer func() {
+		for _, b := range builders {
+			b.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		b, err := NewPostgresContainerBuilder(version, options...)
+		if err != nil {
+			return nil, err
+		}
+		builders = append(builders, b)
+	}
+
+	if err := builders[0].EnsureImage(ctx); err != nil {
+		return nil, err
+	}
+
+	containers := make([]*PostgresContainer, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, b := range builders {
+		wg.Add(1)
+		go func(i int, b *PostgresContainerBuilder) {
+			defer wg.Done()
+			containers[i], errs[i] = b.buildWithRetry(ctx)
+		}(i, b)
+	}
+	wg.Wait()
+
+	var startErrs []error
+	for i, err := range errs {
+		if err != nil {
+			startErrs = append(startErrs, fmt.Errorf("container %d: %w", i, err))
+		}
+	}
+	if len(startErrs) == 0 {
+		return containers, nil
+	}
+	for _, pg := range containers {
+		if pg == nil {
+			continue
+		}
+		if err := pg.Shutdown(ctx, WithForce(), WithRemoveVolumes()); err != nil {
+			startErrs = append(startErrs, fmt.Errorf("shutdown container %s: %w", pg.ID(), err))
+		}
+	}
+	return nil, errors.Join(startErrs...)
+}
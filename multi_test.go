@@ -0,0 +1,67 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestStartPostgresContainers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	containers, err := StartPostgresContainers(ctx, 2, "15")
+	if err != nil {
+		t.Fatalf("could not start containers: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, container := range containers {
+			_ = container.Shutdown(ctx)
+		}
+	})
+
+	if containers[0].ConnectionString() == containers[1].ConnectionString() {
+		t.Fatalf("containers share connection string %s", containers[0].ConnectionString())
+	}
+	for _, container := range containers {
+		db, err := sql.Open("pgx", container.ConnectionString())
+		if err != nil {
+			t.Fatalf("could not open connection: %v", err)
+		}
+		err = db.PingContext(ctx)
+		_ = db.Close()
+		if err != nil {
+			t.Fatalf("could not ping database: %v", err)
+		}
+	}
+}
+
+func TestStartPostgresContainersInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		n       int
+		options []Option
+		wantErr string
+	}{
+		{name: "n", n: 0, wantErr: "n must be at least 1"},
+		{name: "host port", n: 2, options: []Option{WithHostPort(54321)}, wantErr: "WithHostPort"},
+		{name: "reuse", n: 1, options: []Option{WithReuse("shared")}, wantErr: "WithReuse"},
+		{name: "container name", n: 2, options: []Option{WithContainerName("pg")}, wantErr: "WithContainerName"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := StartPostgresContainers(context.Background(), tt.n, "15", tt.options...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("StartPostgresContainers() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
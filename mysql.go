@@ -0,0 +1,68 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// mysqlDSN returns a connection string in the format of the
+// github.com/go-sql-driver/mysql driver. multiStatements is enabled so that
+// RunMigrations can execute whole migration files.
+func mysqlDSN(user, password, host, port, dbName string) string {
+	auth := user
+	if password != "" {
+		auth += ":" + password
+	}
+	return fmt.Sprintf(
+		"%s@tcp(%s)/%s?parseTime=true&multiStatements=true",
+		auth,
+		net.JoinHostPort(host, port),
+		dbName,
+	)
+}
+
+// waitUntilMySQLReady waits until the server at addr greets new connections
+// with a MySQL protocol handshake. Port forwarding accepts connections before
+// the server is up, so a successful dial alone doesn't mean much.
+func waitUntilMySQLReady(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			err = readMySQLHandshake(conn)
+			conn.Close()
+			if err == nil {
+				return nil
+			}
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// readMySQLHandshake reads the first packet sent by the server and checks that
+// it's an initial handshake rather than an error packet.
+func readMySQLHandshake(conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+	// 3 bytes payload length, 1 byte sequence ID, then the payload whose first
+	// byte is the protocol version (10) or 0xff for an error packet.
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[4] != 10 {
+		return errors.New("server not ready")
+	}
+	return nil
+}
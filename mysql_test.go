@@ -0,0 +1,82 @@
+package sqltestutil
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMySQLDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     string
+	}{
+		{
+			name:     "with password",
+			user:     "test",
+			password: "secret",
+			want:     "test:secret@tcp(127.0.0.1:3306)/test?parseTime=true&multiStatements=true",
+		},
+		{
+			name: "without password",
+			user: "root",
+			want: "root@tcp(127.0.0.1:3306)/test?parseTime=true&multiStatements=true",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := mysqlDSN(tt.user, tt.password, "127.0.0.1", "3306", "test"); got != tt.want {
+				t.Errorf("mysqlDSN() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMySQLHandshake(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		packet  []byte
+		wantErr bool
+	}{
+		{
+			name:   "handshake",
+			packet: []byte{0x4a, 0x00, 0x00, 0x00, 0x0a},
+		},
+		{
+			name:    "error packet",
+			packet:  []byte{0x17, 0x00, 0x00, 0x00, 0xff},
+			wantErr: true,
+		},
+		{
+			name:    "closed",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, client := net.Pipe()
+			go func() {
+				_, _ = server.Write(tt.packet)
+				server.Close()
+			}()
+			defer client.Close()
+
+			if err := readMySQLHandshake(client); (err != nil) != tt.wantErr {
+				t.Errorf("readMySQLHandshake() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
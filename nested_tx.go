@@ -0,0 +1,47 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// savepointCounter makes savepoint names unique within the process.
+var savepointCounter atomic.Int64
+
+// NestedTx creates a savepoint in tx and rolls back to it when t finishes, so
+// that table-driven subtests can share expensive setup inside one transaction
+// while staying isolated from each other:
+//
+//	tx := s.Tx() // or any *sql.Tx holding the shared setup
+//	for _, tt := range tests {
+//	    t.Run(tt.name, func(t *testing.T) {
+//	        tx := sqltestutil.NestedTx(t, tx)
+//	        // changes made through tx are undone after the subtest
+//	    })
+//	}
+//
+// It returns tx for convenience. NestedTx can be nested to any depth, but the
+// subtests sharing a transaction must not run in parallel.
+func NestedTx(t testing.TB, tx *sql.Tx) *sql.Tx {
+	t.Helper()
+
+	ctx := context.Background()
+	name := fmt.Sprintf("sqltestutil_%d", savepointCounter.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		t.Fatalf("could not create savepoint: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+			t.Errorf("could not roll back to savepoint: %v", err)
+			return
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+			t.Errorf("could not release savepoint: %v", err)
+		}
+	})
+	return tx
+}
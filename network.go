@@ -0,0 +1,122 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// Addressing decides how the connection string reaches a Postgres container.
+type Addressing int
+
+const (
+	// AddressingAuto uses AddressingContainerIP when the tests themselves run
+	// in a container on the same Docker daemon, as in CI jobs with the
+	// Docker socket mounted, where ports published on the daemon's host
+	// aren't reachable on 127.0.0.1, and AddressingPublishedPort otherwise.
+	// It's the default.
+	AddressingAuto Addressing = iota
+	// AddressingPublishedPort connects to the port published on the daemon's
+	// host, see WithHostOverride.
+	AddressingPublishedPort
+	// AddressingContainerIP connects to the container's IP address on its
+	// network, see WithNetwork, and port 5432.
+	AddressingContainerIP
+)
+
+// containerEnvFiles are created by Docker and Podman in the root of their
+// containers.
+var containerEnvFiles = []string{"/.dockerenv", "/run/.containerenv"}
+
+// runningInContainer reports whether this process runs in a Docker or Podman
+// container.
+func runningInContainer(exists func(string) bool) bool {
+	for _, path := range containerEnvFiles {
+		if exists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// useContainerIP reports whether a container should be reached on its IP
+// address rather than on a published port.
+func useContainerIP(config *PostgresContainerConfig, daemonHost string, inContainer bool) bool {
+	switch config.Addressing {
+	case AddressingContainerIP:
+		return true
+	case AddressingPublishedPort:
+		return false
+	}
+	if config.HostOverride != "" || !inContainer {
+		return false
+	}
+	// a remote daemon is reached through DOCKER_HOST instead
+	return daemonHostname(daemonHost) == localHost
+}
+
+// containerIP returns the IP address of a container on the given network, or
+// on its only network if network is empty.
+func containerIP(ctx context.Context, cli *client.Client, containerID, network string) (string, error) {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("error inspecting container: %w", err)
+	}
+	return networkIP(inspect.NetworkSettings, network)
+}
+
+// networkIP returns the IP address in settings for the given network, or for
+// the only network if network is empty.
+func networkIP(settings *types.NetworkSettings, network string) (string, error) {
+	if settings == nil {
+		return "", fmt.Errorf("container has no network settings")
+	}
+	if network != "" {
+		endpoint := settings.Networks[network]
+		if endpoint == nil || endpoint.IPAddress == "" {
+			return "", fmt.Errorf("container has no IP address on network %s", network)
+		}
+		return endpoint.IPAddress, nil
+	}
+	if settings.IPAddress != "" {
+		return settings.IPAddress, nil
+	}
+	for name, endpoint := range settings.Networks {
+		if len(settings.Networks) > 1 {
+			return "", fmt.Errorf("container is on several networks, choose one with WithNetwork")
+		}
+		if endpoint == nil || endpoint.IPAddress == "" {
+			return "", fmt.Errorf("container has no IP address on network %s", name)
+		}
+		return endpoint.IPAddress, nil
+	}
+	return "", fmt.Errorf("container has no IP address")
+}
+
+// networkingConfig attaches a container to config.Network under
+// config.NetworkAliases, so that other containers on the network can reach it
+// by name. It's nil for Docker's default network.
+func networkingConfig(config *PostgresContainerConfig) *network.NetworkingConfig {
+	if config.Network == "" {
+		return nil
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			config.Network: {
+				Aliases: config.NetworkAliases,
+			},
+		},
+	}
+}
+
+// networkHost returns the host other containers on config.Network reach the
+// container on: its first alias, or ip without one.
+func networkHost(config *PostgresContainerConfig, ip string) string {
+	if len(config.NetworkAliases) > 0 {
+		return config.NetworkAliases[0]
+	}
+	return ip
+}
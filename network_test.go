@@ -0,0 +1,173 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestUseContainerIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      PostgresContainerConfig
+		daemonHost  string
+		inContainer bool
+		want        bool
+	}{
+		{name: "on host", daemonHost: "unix:///var/run/docker.sock"},
+		{name: "in container", daemonHost: "unix:///var/run/docker.sock", inContainer: true, want: true},
+		{name: "in container with remote daemon", daemonHost: "tcp://docker:2375", inContainer: true},
+		{
+			name:        "in container with host override",
+			config:      PostgresContainerConfig{HostOverride: "host.docker.internal"},
+			daemonHost:  "unix:///var/run/docker.sock",
+			inContainer: true,
+		},
+		{
+			name:        "forced published port",
+			config:      PostgresContainerConfig{Addressing: AddressingPublishedPort},
+			daemonHost:  "unix:///var/run/docker.sock",
+			inContainer: true,
+		},
+		{
+			name:       "forced container IP",
+			config:     PostgresContainerConfig{Addressing: AddressingContainerIP},
+			daemonHost: "tcp://docker:2375",
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := useContainerIP(&tt.config, tt.daemonHost, tt.inContainer); got != tt.want {
+				t.Errorf("useContainerIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkIP(t *testing.T) {
+	t.Parallel()
+
+	bridge := &types.NetworkSettings{
+		DefaultNetworkSettings: types.DefaultNetworkSettings{IPAddress: "172.17.0.3"},
+		Networks: map[string]*network.EndpointSettings{
+			"bridge": {IPAddress: "172.17.0.3"},
+		},
+	}
+	shared := &types.NetworkSettings{
+		Networks: map[string]*network.EndpointSettings{
+			"ci":    {IPAddress: "10.1.0.5"},
+			"other": {IPAddress: "10.2.0.5"},
+		},
+	}
+	tests := []struct {
+		name     string
+		settings *types.NetworkSettings
+		network  string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default bridge", settings: bridge, want: "172.17.0.3"},
+		{name: "named network", settings: shared, network: "ci", want: "10.1.0.5"},
+		{name: "several networks", settings: shared, wantErr: true},
+		{name: "missing network", settings: bridge, network: "ci", wantErr: true},
+		{name: "no settings", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := networkIP(tt.settings, tt.network)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("networkIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("networkIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkingConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      *PostgresContainerConfig
+		ip          string
+		wantAliases []string
+		wantHost    string
+	}{
+		{
+			name:     "default network",
+			config:   &PostgresContainerConfig{},
+			ip:       "172.17.0.3",
+			wantHost: "172.17.0.3",
+		},
+		{
+			name:     "network without aliases",
+			config:   &PostgresContainerConfig{Network: "ci"},
+			ip:       "10.1.0.5",
+			wantHost: "10.1.0.5",
+		},
+		{
+			name:        "network with aliases",
+			config:      &PostgresContainerConfig{Network: "ci", NetworkAliases: []string{"postgres", "db"}},
+			wantAliases: []string{"postgres", "db"},
+			wantHost:    "postgres",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := networkingConfig(tt.config)
+			if tt.config.Network == "" {
+				if got != nil {
+					t.Errorf("networkingConfig() = %+v, want nil", got)
+				}
+			} else {
+				endpoint := got.EndpointsConfig[tt.config.Network]
+				if endpoint == nil {
+					t.Fatalf("networkingConfig() has no endpoint for %s", tt.config.Network)
+				}
+				if !reflect.DeepEqual(endpoint.Aliases, tt.wantAliases) {
+					t.Errorf("networkingConfig() aliases = %v, want %v", endpoint.Aliases, tt.wantAliases)
+				}
+			}
+			if host := networkHost(tt.config, tt.ip); host != tt.wantHost {
+				t.Errorf("networkHost() = %q, want %q", host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestRunningInContainer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		existing string
+		want     bool
+	}{
+		{name: "host"},
+		{name: "docker", existing: "/.dockerenv", want: true},
+		{name: "podman", existing: "/run/.containerenv", want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			exists := func(path string) bool { return path == tt.existing }
+			if got := runningInContainer(exists); got != tt.want {
+				t.Errorf("runningInContainer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// oracleStartupTimeout is the default StartupTimeout for Oracle, whose
+	// first start can take several minutes.
+	oracleStartupTimeout = 5 * time.Minute
+
+	// oracleReadyMessage is logged by the gvenzl/oracle-free image once the
+	// database can be used.
+	oracleReadyMessage = "DATABASE IS READY TO USE!"
+
+	// oracleService is the pluggable database of the Oracle Free image.
+	oracleService = "FREEPDB1"
+)
+
+// OracleContainer is a Docker container running Oracle Database Free. It can
+// be used to start a throwaway Oracle instance for testing.
+type OracleContainer struct {
+	id       string
+	user     string
+	password string
+	host     string
+	port     string
+
+	docker dockerConfig
+}
+
+// StartOracleContainer starts a new Oracle Database Free container from the
+// gvenzl/oracle-free image. The version parameter is the image tag, e.g.
+// "23-slim-faststart". The options for the Docker client, image pulls, the
+// reaper and the host apply; of those configuring the database only
+// WithDBUser, WithDBPassword and WithStartupTimeout do, and the application
+// user defaults to "test" with a random password, which is also used for SYS
+// and SYSTEM.
+//
+// Oracle is slow to start, so readiness is detected by waiting for the image's
+// "DATABASE IS READY TO USE!" log message rather than by polling, and the
+// startup timeout defaults to five minutes.
+func StartOracleContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*OracleContainer, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &PostgresContainerConfig{
+		DBName:     oracleService,
+		DBUser:     "test",
+		DBPassword: password,
+		TimeZone:   "UTC",
+		SSLMode:    "disable",
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image: "gvenzl/oracle-free:" + version,
+		env: []string{
+			"ORACLE_PASSWORD=" + config.DBPassword,
+			"APP_USER=" + config.DBUser,
+			"APP_USER_PASSWORD=" + config.DBPassword,
+		},
+		port:   "1521/tcp",
+		pull:   config.imagePull(),
+		reaper: config.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(oracleStartupTimeout))
+	defer cancel()
+
+	if err := waitForLog(waitCtx, cli, containerID, oracleReadyMessage, 1); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &OracleContainer{
+		id:       containerID,
+		user:     config.DBUser,
+		password: config.DBPassword,
+		host:     containerHost(cli, config.HostOverride),
+		port:     port,
+		docker:   config.docker(),
+	}, nil
+}
+
+// ConnectionString returns a connection URL for the github.com/sijms/go-ora
+// driver.
+func (c *OracleContainer) ConnectionString() string {
+	u := url.URL{
+		Scheme: "oracle",
+		User:   url.UserPassword(c.user, c.password),
+		Host:   net.JoinHostPort(c.host, c.port),
+		Path:   "/" + oracleService,
+	}
+	return u.String()
+}
+
+// GodrorConnectionString returns a connection string for the
+// github.com/godror/godror driver.
+func (c *OracleContainer) GodrorConnectionString() string {
+	return fmt.Sprintf(
+		`user="%s" password="%s" connectString="%s/%s"`,
+		c.user,
+		c.password,
+		net.JoinHostPort(c.host, c.port),
+		oracleService,
+	)
+}
+
+// ID returns the Docker container ID of the running Oracle container.
+func (c *OracleContainer) ID() string {
+	return c.id
+}
+
+// User returns the name of the application user.
+func (c *OracleContainer) User() string {
+	return c.user
+}
+
+// Password returns the password of the application user.
+func (c *OracleContainer) Password() string {
+	return c.password
+}
+
+// Shutdown cleans up the Oracle container by stopping and removing it.
+func (c *OracleContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
@@ -0,0 +1,31 @@
+package sqltestutil
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeVector converts a !vector scenario value, a sequence of numbers, into
+// the text the pgvector vector type accepts as input:
+//
+//	embedding: !vector [0.1, 0.25, -1]
+func decodeVector(node *yaml.Node) (interface{}, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, errors.New("must be a sequence of numbers")
+	}
+	var components []float32
+	if err := node.Decode(&components); err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return nil, errors.New("vector must have at least one dimension")
+	}
+	elems := make([]string, len(components))
+	for i, c := range components {
+		elems[i] = strconv.FormatFloat(float64(c), 'g', -1, 32)
+	}
+	return "[" + strings.Join(elems, ",") + "]", nil
+}
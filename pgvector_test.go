@@ -0,0 +1,39 @@
+package sqltestutil
+
+import (
+	"testing"
+)
+
+func TestVectorTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "floats", value: "!vector [0.1, 0.25, -1]", want: "[0.1,0.25,-1]"},
+		{name: "block", value: "!vector\n      - 1\n      - 2", want: "[1,2]"},
+		{name: "empty", value: "!vector []", wantErr: true},
+		{name: "not numbers", value: "!vector [a, b]", wantErr: true},
+		{name: "scalar", value: "!vector 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tables, _, err := parseScenario([]byte("documents:\n  - embedding: " + tt.value + "\n"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseScenario() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tables[0].rows[0].values[0]; got != tt.want {
+				t.Errorf("embedding = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// parsePlatform parses a platform in the "os/arch[/variant]" form of
+// docker --platform, e.g. "linux/amd64". An empty platform is nil, which lets
+// the daemon choose.
+func parsePlatform(platform string) (*specs.Platform, error) {
+	if platform == "" {
+		return nil, nil
+	}
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid platform %q, want os/arch[/variant]", platform)
+	}
+	p := &specs.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// matchesPlatform reports whether an image built for os and arch runs as
+// platform without pulling it again. A nil platform matches any image.
+func matchesPlatform(platform *specs.Platform, os, arch string) bool {
+	return platform == nil || (platform.OS == os && platform.Architecture == arch)
+}
+
+// emulationWarning returns a warning when an image for imageArch runs on a
+// daemon on daemonArch, which works through emulation but is many times
+// slower, or an empty string when they're the same.
+func emulationWarning(image, imageArch, daemonArch string) string {
+	if imageArch == "" || daemonArch == "" || imageArch == daemonArch {
+		return ""
+	}
+	return fmt.Sprintf("image %s is built for %s and runs emulated on the %s Docker host, which is much slower", image, imageArch, daemonArch)
+}
+
+// warnEmulation logs a warning if image runs emulated on the daemon of cli.
+// Failing to tell isn't an error.
+func warnEmulation(ctx context.Context, cli *client.Client, image string, logger *slog.Logger) {
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return
+	}
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return
+	}
+	if warning := emulationWarning(image, inspect.Architecture, version.Arch); warning != "" {
+		orDefault(logger).Warn(warning)
+	}
+}
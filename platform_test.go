@@ -0,0 +1,78 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestParsePlatform(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		platform string
+		want     *specs.Platform
+		wantErr  bool
+	}{
+		{platform: ""},
+		{platform: "linux/amd64", want: &specs.Platform{OS: "linux", Architecture: "amd64"}},
+		{platform: "linux/arm/v7", want: &specs.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{platform: "amd64", wantErr: true},
+		{platform: "linux/", wantErr: true},
+		{platform: "linux/arm/v7/extra", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.platform, func(t *testing.T) {
+			t.Parallel()
+			got, err := parsePlatform(tt.platform)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlatform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePlatform() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesPlatform(t *testing.T) {
+	t.Parallel()
+
+	amd64 := &specs.Platform{OS: "linux", Architecture: "amd64"}
+	tests := []struct {
+		name     string
+		platform *specs.Platform
+		arch     string
+		want     bool
+	}{
+		{name: "any", arch: "arm64", want: true},
+		{name: "same", platform: amd64, arch: "amd64", want: true},
+		{name: "other", platform: amd64, arch: "arm64", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := matchesPlatform(tt.platform, "linux", tt.arch); got != tt.want {
+				t.Errorf("matchesPlatform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmulationWarning(t *testing.T) {
+	t.Parallel()
+
+	if got := emulationWarning("postgres:16", "arm64", "arm64"); got != "" {
+		t.Errorf("emulationWarning() = %q for a native image", got)
+	}
+	if got := emulationWarning("postgres:16", "", "arm64"); got != "" {
+		t.Errorf("emulationWarning() = %q for an unknown architecture", got)
+	}
+	want := "image postgis/postgis:16-3.4 is built for amd64 and runs emulated on the arm64 Docker host, which is much slower"
+	if got := emulationWarning("postgis/postgis:16-3.4", "amd64", "arm64"); got != want {
+		t.Errorf("emulationWarning() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// isPodman reports whether the daemon behind cli is Podman's Docker compatible
+// API rather than Docker.
+func isPodman(ctx context.Context, cli *client.Client) bool {
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return false
+	}
+	return isPodmanVersion(version)
+}
+
+func isPodmanVersion(version types.Version) bool {
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(version.Platform.Name), "podman")
+}
+
+// waitUntilHealthyExec waits for a container by running its healthcheck
+// command itself until it succeeds. Podman only runs healthchecks from
+// systemd timers, which rootless setups often lack, so the health status
+// reported through its Docker API can stay "starting" forever.
+func waitUntilHealthyExec(ctx context.Context, cli *client.Client, containerID string) error {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("error inspecting container: %w", err)
+	}
+	if inspect.Config == nil || inspect.Config.Healthcheck == nil {
+		return nil
+	}
+	cmd := healthcheckCommand(inspect.Config.Healthcheck.Test)
+	if cmd == nil {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		exitCode, err := execInContainer(ctx, cli, containerID, cmd)
+		if err != nil {
+			return err
+		}
+		if exitCode == 0 {
+			return nil
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// healthcheckCommand returns the command line of a healthcheck test, or nil if
+// the test doesn't run a command.
+func healthcheckCommand(test []string) []string {
+	if len(test) < 2 {
+		return nil
+	}
+	switch test[0] {
+	case "CMD":
+		return test[1:]
+	case "CMD-SHELL":
+		return []string{"/bin/sh", "-c", strings.Join(test[1:], " ")}
+	default:
+		return nil
+	}
+}
+
+// execInContainer runs cmd in the container, waits for it to finish and
+// returns its exit code.
+func execInContainer(ctx context.Context, cli *client.Client, containerID string, cmd []string) (int, error) {
+	created, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("exec create error: %w", err)
+	}
+	attach, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return 0, fmt.Errorf("exec attach error: %w", err)
+	}
+	_, err = io.Copy(io.Discard, attach.Reader)
+	attach.Close()
+	if err != nil {
+		return 0, fmt.Errorf("exec output error: %w", err)
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return 0, fmt.Errorf("exec inspect error: %w", err)
+	}
+	return inspect.ExitCode, nil
+}
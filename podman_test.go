@@ -0,0 +1,76 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestDetectRuntime(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		version types.Version
+		want    bool
+	}{
+		{
+			name: "docker",
+			version: types.Version{
+				Platform:   struct{ Name string }{Name: "Docker Engine - Community"},
+				Components: []types.ComponentVersion{{Name: "Engine"}, {Name: "containerd"}},
+			},
+		},
+		{
+			name: "podman",
+			version: types.Version{
+				Components: []types.ComponentVersion{{Name: "Podman Engine"}},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isPodmanVersion(tt.version); got != tt.want {
+				t.Errorf("isPodmanVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthcheckCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		test []string
+		want []string
+	}{
+		{
+			name: "shell",
+			test: []string{"CMD-SHELL", "pg_isready -U postgres"},
+			want: []string{"/bin/sh", "-c", "pg_isready -U postgres"},
+		},
+		{
+			name: "exec",
+			test: []string{"CMD", "healthcheck.sh", "--connect"},
+			want: []string{"healthcheck.sh", "--connect"},
+		},
+		{
+			name: "none",
+			test: []string{"NONE"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := healthcheckCommand(tt.test); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("healthcheckCommand(%q) = %q, want %q", tt.test, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// poolRetryInterval is how long a pool worker waits after a failed start
+// before trying again.
+const poolRetryInterval = time.Second
+
+// PoolStats are the metrics of a ContainerPool.
+type PoolStats struct {
+	// Started is the number of containers the pool started, and Failed the
+	// number of starts that failed.
+	Started int
+	Failed  int
+	// Acquired is the number of containers handed out by Get, of which Waited
+	// had to wait for a container to become ready because none was idle.
+	Acquired int
+	Waited   int
+	// Idle is the number of ready containers waiting to be handed out.
+	Idle int
+	// Leased is the number of containers acquired with Acquire and not yet
+	// released.
+	Leased int
+	// Reaped is the number of ready containers shut down because they weren't
+	// claimed within the idle timeout, see WithPoolIdleTimeout.
+	Reaped int
+	// StartTime is the total time spent starting containers.
+	StartTime time.Duration
+	// LastErr is the error of the most recent failed start, if any.
+	LastErr error
+}
+
+// ContainerPool keeps a number of Postgres containers started and healthy in
+// the background and hands them out on demand, so that tests that each need a
+// fresh server don't wait for one to start. Every container handed out is
+// replaced by a new one in the background. See NewContainerPool.
+type ContainerPool struct {
+	ready chan *PostgresContainer
+	start func(ctx context.Context) (*PostgresContainer, error)
+	// idleTimeout is the PoolIdleTimeout of the pool.
+	idleTimeout time.Duration
+	// demand wakes up a worker whose container was reaped.
+	demand chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	stats  PoolStats
+	leased map[*PostgresContainer]bool
+	// shutdownErrs are the errors of shutting down idle containers on Close.
+	shutdownErrs []error
+}
+
+// NewContainerPool starts a pool that keeps size containers ready. The
+// version and options have the same meaning as for StartPostgresContainer.
+// The image is pulled before NewContainerPool returns, but it doesn't wait for
+// the containers. Get takes a container from the pool, and Close must be
+// called when done, typically from TestMain, to shut down the idle ones:
+//
+//	var pool *sqltestutil.ContainerPool
+//
+//	func TestMain(m *testing.M) {
+//	    var err error
+//	    pool, err = sqltestutil.NewContainerPool(context.Background(), 4, "16")
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    code := m.Run()
+//	    _ = pool.Close(context.Background())
+//	    os.Exit(code)
+//	}
+//
+// Containers handed out by Get belong to the caller, who shuts them down as
+// usual, while those leased with Acquire are returned with Release.
+// WithPoolIdleTimeout shuts down the containers nobody claims.
+func NewContainerPool(ctx context.Context, size int, version string, options ...Option) (*ContainerPool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("size must be at least 1, got %d", size)
+	}
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if err := PullPostgresImage(ctx, version, nil, options...); err != nil {
+		return nil, err
+	}
+	if config.PullPolicy == PullAlways {
+		// the image was just pulled, so the containers don't pull it again
+		options = append(options[:len(options):len(options)], WithPullPolicy(PullIfNotPresent))
+	}
+	return newContainerPool(size, config.PoolIdleTimeout, func(ctx context.Context) (*PostgresContainer, error) {
+		return StartPostgresContainer(ctx, version, options...)
+	}), nil
+}
+
+func newContainerPool(size int, idleTimeout time.Duration, start func(ctx context.Context) (*PostgresContainer, error)) *ContainerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ContainerPool{
+		ready:       make(chan *PostgresContainer),
+		start:       start,
+		idleTimeout: idleTimeout,
+		demand:      make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		leased:      map[*PostgresContainer]bool{},
+	}
+	for i := 0; i < size; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// work keeps one container ready: it starts a container, waits until Get
+// takes it and starts the next, until the pool is closed. A start in progress
+// isn't canceled by Close, so that a half-started container is cleaned up
+// properly; the container is shut down once it's ready instead. A container
+// that isn't taken within the idle timeout is shut down, and the next one is
+// only started once a container is asked for.
+func (p *ContainerPool) work() {
+	defer p.wg.Done()
+	for p.ctx.Err() == nil {
+		start := time.Now()
+		pg, err := p.start(context.Background())
+		p.recordStart(time.Since(start), err)
+		if err != nil {
+			select {
+			case <-p.ctx.Done():
+			case <-time.After(poolRetryInterval):
+			}
+			continue
+		}
+
+		if p.offer(pg) {
+			continue
+		}
+		p.shutdown(pg)
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.mu.Lock()
+		p.stats.Reaped++
+		p.mu.Unlock()
+		select {
+		case <-p.demand:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// offer waits until Get takes pg and reports whether it did, or whether the
+// idle timeout elapsed or the pool was closed first.
+func (p *ContainerPool) offer(pg *PostgresContainer) bool {
+	var idle <-chan time.Time
+	if p.idleTimeout > 0 {
+		timer := time.NewTimer(p.idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	p.addIdle(1)
+	select {
+	case p.ready <- pg:
+		return true
+	case <-idle:
+	case <-p.ctx.Done():
+	}
+	p.addIdle(-1)
+	return false
+}
+
+// shutdown shuts down a container of the pool, recording the error for Close.
+func (p *ContainerPool) shutdown(pg *PostgresContainer) {
+	if err := pg.Shutdown(context.Background(), WithForce(), WithRemoveVolumes()); err != nil {
+		p.mu.Lock()
+		p.shutdownErrs = append(p.shutdownErrs, fmt.Errorf("shutdown container %s: %w", pg.ID(), err))
+		p.mu.Unlock()
+	}
+}
+
+// Get returns a ready container from the pool, waiting for one if none is
+// idle. If ctx is done first, the error includes the last failed start, if
+// any.
+func (p *ContainerPool) Get(ctx context.Context) (*PostgresContainer, error) {
+	select {
+	case pg := <-p.ready:
+		p.recordAcquire(false)
+		return pg, nil
+	default:
+	}
+
+	// wake up a worker whose container was reaped, if any, once
+	demand := p.demand
+	for {
+		select {
+		case pg := <-p.ready:
+			p.recordAcquire(true)
+			return pg, nil
+		case demand <- struct{}{}:
+			demand = nil
+		case <-p.ctx.Done():
+			return nil, errors.New("container pool is closed")
+		case <-ctx.Done():
+			p.mu.Lock()
+			lastErr := p.stats.LastErr
+			p.mu.Unlock()
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w; last start error: %v", ctx.Err(), lastErr)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Acquire leases a container from the pool like Get. The lease ends with
+// Release, or with Close for containers that are still leased then, so
+// callers don't shut leased containers down themselves.
+func (p *ContainerPool) Acquire(ctx context.Context) (*PostgresContainer, error) {
+	pg, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.leased[pg] = true
+	p.stats.Leased++
+	return pg, nil
+}
+
+// Release ends the lease of a container acquired with Acquire and shuts it
+// down; the pool has long started its replacement. A container that wasn't
+// leased from the pool is an error.
+func (p *ContainerPool) Release(ctx context.Context, pg *PostgresContainer) error {
+	p.mu.Lock()
+	leased := p.leased[pg]
+	if leased {
+		delete(p.leased, pg)
+		p.stats.Leased--
+	}
+	p.mu.Unlock()
+	if !leased {
+		return errors.New("container is not leased from the pool")
+	}
+	return pg.Shutdown(ctx, WithForce(), WithRemoveVolumes())
+}
+
+// Stats returns the current metrics of the pool.
+func (p *ContainerPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Close stops replenishing the pool and shuts down the idle containers,
+// waiting for containers that are still starting, and the containers that are
+// still leased. Containers handed out by Get aren't affected.
+func (p *ContainerPool) Close(ctx context.Context) error {
+	p.cancel()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.mu.Lock()
+	leased := p.leased
+	p.leased = map[*PostgresContainer]bool{}
+	p.stats.Leased = 0
+	p.mu.Unlock()
+	for pg := range leased {
+		p.shutdown(pg)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return errors.Join(p.shutdownErrs...)
+}
+
+func (p *ContainerPool) recordStart(d time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.StartTime += d
+	if err != nil {
+		p.stats.Failed++
+		p.stats.LastErr = err
+		return
+	}
+	p.stats.Started++
+}
+
+func (p *ContainerPool) recordAcquire(waited bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Acquired++
+	p.stats.Idle--
+	if waited {
+		p.stats.Waited++
+	}
+}
+
+func (p *ContainerPool) addIdle(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Idle += n
+}
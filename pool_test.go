@@ -0,0 +1,124 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	var started atomic.Int64
+	pool := newContainerPool(2, 0, func(ctx context.Context) (*PostgresContainer, error) {
+		started.Add(1)
+		// external containers need no daemon to shut down
+		return &PostgresContainer{external: true}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Get(ctx); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if err := pool.Close(ctx); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	stats := pool.Stats()
+	if stats.Acquired != 3 || stats.Idle != 0 || stats.Started != int(started.Load()) {
+		t.Errorf("Stats() = %+v, want 3 acquired and none idle, %d started", stats, started.Load())
+	}
+	if _, err := pool.Get(ctx); err == nil {
+		t.Error("Get() after Close() error = nil")
+	}
+}
+
+func TestPoolStartErrors(t *testing.T) {
+	t.Parallel()
+
+	pool := newContainerPool(1, 0, func(ctx context.Context) (*PostgresContainer, error) {
+		return nil, errors.New("no daemon")
+	})
+	defer pool.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := pool.Get(ctx)
+	if err == nil || !strings.Contains(err.Error(), "no daemon") {
+		t.Errorf("Get() error = %v, want the start error", err)
+	}
+	if stats := pool.Stats(); stats.Failed == 0 {
+		t.Errorf("Stats().Failed = 0, want failed starts")
+	}
+}
+
+func TestPoolLeases(t *testing.T) {
+	t.Parallel()
+
+	pool := newContainerPool(1, 0, func(ctx context.Context) (*PostgresContainer, error) {
+		return &PostgresContainer{external: true}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	released, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if _, err := pool.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := pool.Release(ctx, released); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+	if err := pool.Release(ctx, released); err == nil {
+		t.Error("Release() of a released container error = nil")
+	}
+	if leased := pool.Stats().Leased; leased != 1 {
+		t.Errorf("Stats().Leased = %d, want 1", leased)
+	}
+
+	if err := pool.Close(ctx); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if leased := pool.Stats().Leased; leased != 0 {
+		t.Errorf("Stats().Leased after Close() = %d, want 0", leased)
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	var started atomic.Int64
+	pool := newContainerPool(1, 10*time.Millisecond, func(ctx context.Context) (*PostgresContainer, error) {
+		started.Add(1)
+		return &PostgresContainer{external: true}, nil
+	})
+	defer pool.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for pool.Stats().Reaped == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("no container was reaped")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if n := started.Load(); n != 1 {
+		t.Errorf("started %d containers while idle, want 1", n)
+	}
+
+	if _, err := pool.Get(ctx); err != nil {
+		t.Fatalf("Get() after reaping error = %v", err)
+	}
+	if n := started.Load(); n < 2 {
+		t.Errorf("started %d containers, want a replacement of the reaped one", n)
+	}
+}
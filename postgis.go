@@ -0,0 +1,53 @@
+package sqltestutil
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PostGISVersion is the PostGIS version of the image WithPostGIS runs.
+var PostGISVersion = "3.4"
+
+// defaultSRID is the spatial reference system of !geometry points given as
+// longitude and latitude, WGS 84.
+const defaultSRID = 4326
+
+// decodeGeometry converts a !geometry scenario value into the EWKT text the
+// PostGIS geometry type accepts as input. The value is either WKT or EWKT,
+// which is used as is:
+//
+//	location: !geometry SRID=4326;POINT(13.4 52.5)
+//
+// or a point as a mapping of lon, lat and, optionally, srid, which defaults to
+// 4326:
+//
+//	location: !geometry {lon: 13.4, lat: 52.5}
+func decodeGeometry(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value == "" {
+			return nil, errors.New("empty geometry")
+		}
+		return node.Value, nil
+	case yaml.MappingNode:
+		var point struct {
+			Lon  *float64 `yaml:"lon"`
+			Lat  *float64 `yaml:"lat"`
+			SRID int      `yaml:"srid"`
+		}
+		if err := node.Decode(&point); err != nil {
+			return nil, err
+		}
+		if point.Lon == nil || point.Lat == nil {
+			return nil, errors.New("point needs lon and lat")
+		}
+		if point.SRID == 0 {
+			point.SRID = defaultSRID
+		}
+		return fmt.Sprintf("SRID=%d;POINT(%v %v)", point.SRID, *point.Lon, *point.Lat), nil
+	default:
+		return nil, errors.New("must be WKT or a mapping of lon and lat")
+	}
+}
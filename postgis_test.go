@@ -0,0 +1,40 @@
+package sqltestutil
+
+import (
+	"testing"
+)
+
+func TestGeometryTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "wkt", value: "!geometry POINT(13.4 52.5)", want: "POINT(13.4 52.5)"},
+		{name: "ewkt", value: "!geometry SRID=3857;POINT(1 2)", want: "SRID=3857;POINT(1 2)"},
+		{name: "lon lat", value: "!geometry {lon: 13.4, lat: 52.5}", want: "SRID=4326;POINT(13.4 52.5)"},
+		{name: "srid", value: "!geometry {lon: 1, lat: 2, srid: 3857}", want: "SRID=3857;POINT(1 2)"},
+		{name: "missing lat", value: "!geometry {lon: 1}", wantErr: true},
+		{name: "sequence", value: "!geometry [1, 2]", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tables, _, err := parseScenario([]byte("places:\n  - location: " + tt.value + "\n"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseScenario() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tables[0].rows[0].values[0]; got != tt.want {
+				t.Errorf("location = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// PostgresContainerBuilder exposes the individual phases of
+// StartPostgresContainer so that custom steps can be inserted between them,
+// e.g. copying files into the container before it starts:
+//
+//	b, err := sqltestutil.NewPostgresContainerBuilder("15")
+//	if err != nil {
+//	    return err
+//	}
+//	defer b.Close()
+//	if err := b.EnsureImage(ctx); err != nil {
+//	    return err
+//	}
+//	if err := b.CreateContainer(ctx); err != nil {
+//	    return err
+//	}
+//	// custom step goes here
+//	if err := b.Start(ctx); err != nil {
+//	    _ = b.Abort(ctx)
+//	    return err
+//	}
+//	pg, err := b.AwaitReady(ctx)
+//	if err != nil {
+//	    _ = b.Abort(ctx)
+//	    return err
+//	}
+//
+// The phases must be called in order. StartPostgresContainer is equivalent to
+// calling all of them in sequence.
+type PostgresContainerBuilder struct {
+	cli     *client.Client
+	release func() error
+	version string
+	image   string
+	config  *PostgresContainerConfig
+	host    string
+	port    string
+	// name is the name of the container, or empty to let Docker pick one.
+	name string
+
+	containerID string
+	started     bool
+}
+
+// NewPostgresContainerBuilder returns a builder for a Postgres container. The
+// version and options have the same meaning as for StartPostgresContainer.
+// The builder holds a Docker client, so Close must be called when done. An
+// error is returned if the resulting configuration is invalid, see
+// PostgresContainerConfig.Validate.
+func NewPostgresContainerBuilder(version string, options ...Option) (*PostgresContainerBuilder, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &PostgresContainerConfig{
+		DBName:     "pgtest",
+		DBUser:     "pgtest",
+		DBPassword: password,
+		TimeZone:   "UTC",
+		SSLMode:    "disable",
+	}
+
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	port, err := hostPort(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresContainerBuilder{
+		cli:     cli,
+		release: release,
+		version: version,
+		image:   postgresImage(version, config),
+		config:  config,
+		host:    containerHost(cli, config.HostOverride),
+		port:    port,
+	}, nil
+}
+
+// Config returns the configuration the container is built with. Changes made
+// to it before CreateContainer is called take effect.
+func (b *PostgresContainerBuilder) Config() *PostgresContainerConfig {
+	return b.config
+}
+
+// ContainerID returns the Docker container ID, or an empty string before
+// CreateContainer has been called.
+func (b *PostgresContainerBuilder) ContainerID() string {
+	return b.containerID
+}
+
+// EnsureImage pulls the image if it isn't already cached locally.
+func (b *PostgresContainerBuilder) EnsureImage(ctx context.Context) (err error) {
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.pull", containerAttrs("", b.image)...)
+	defer func() { end(err) }()
+	return ensureImage(ctx, b.cli, b.image, b.config.imagePull())
+}
+
+// CreateContainer creates the container without starting it, then calls the
+// OnCreated hook if set.
+func (b *PostgresContainerBuilder) CreateContainer(ctx context.Context) (err error) {
+	if b.containerID != "" {
+		return errors.New("container already created")
+	}
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.create", containerAttrs("", b.image)...)
+	defer func() { end(err) }()
+
+	binds, err := initScriptBinds(b.config.InitScripts)
+	if err != nil {
+		return err
+	}
+	volumes, err := volumeBinds(b.config.Volumes)
+	if err != nil {
+		return err
+	}
+	binds = append(binds, volumes...)
+	platform, err := parsePlatform(b.config.Platform)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if b.config.Reaper {
+		if err := ensureReaper(ctx, b.cli, b.host); err != nil {
+			return err
+		}
+	}
+
+	createResp, err := b.cli.ContainerCreate(ctx, &container.Config{
+		Image:       b.image,
+		Cmd:         postgresCmd(b.config),
+		Entrypoint:  b.config.Entrypoint,
+		Labels:      b.labels(),
+		Env:         postgresEnv(b.config),
+		Healthcheck: healthcheck(b.config),
+	}, &container.HostConfig{
+		ShmSize:     shmSize(b.config),
+		AutoRemove:  b.config.AutoRemove,
+		NetworkMode: container.NetworkMode(b.config.Network),
+		Binds:       binds,
+		Tmpfs:       tmpfsMounts(b.config),
+		Resources: container.Resources{
+			Ulimits: ulimits(b.config),
+		},
+		PortBindings: nat.PortMap{
+			"5432/tcp": []nat.PortBinding{
+				{HostPort: b.port},
+			},
+		},
+	}, networkingConfig(b.config), platform, b.name)
+	emitTo(b.config.Logger, Event{Type: EventContainerCreated, ContainerID: createResp.ID, Image: b.image, Duration: time.Since(start), Err: err})
+	if err != nil {
+		return err
+	}
+
+	b.containerID = createResp.ID
+	warnEmulation(ctx, b.cli, b.image, b.config.Logger)
+	if b.config.OnCreated != nil {
+		if err := b.config.OnCreated(ctx, b); err != nil {
+			return fmt.Errorf("OnCreated hook error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Start starts the created container, then calls the OnStarted hook if set.
+func (b *PostgresContainerBuilder) Start(ctx context.Context) (err error) {
+	if b.containerID == "" {
+		return errors.New("container not created")
+	}
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.start", containerAttrs(b.containerID, b.image)...)
+	defer func() { end(err) }()
+	start := time.Now()
+	err = b.cli.ContainerStart(ctx, b.containerID, types.ContainerStartOptions{})
+	emitTo(b.config.Logger, Event{Type: EventContainerStarted, ContainerID: b.containerID, Image: b.image, Duration: time.Since(start), Err: err})
+	if err != nil {
+		return err
+	}
+	b.started = true
+	if b.port == "" {
+		// Docker assigned the host port, which can't race with other
+		// processes like a port picked up front
+		b.port, err = publishedHostPort(ctx, b.cli, b.containerID, "5432/tcp")
+		if err != nil {
+			return err
+		}
+	}
+	if b.config.OnStarted != nil {
+		if err := b.config.OnStarted(ctx, b); err != nil {
+			return fmt.Errorf("OnStarted hook error: %w", err)
+		}
+	}
+	return nil
+}
+
+// AwaitReady waits for the started container to be healthy and connectable,
+// checks that the server's major version matches the requested one, calls the
+// OnHealthy hook if set, and returns the resulting PostgresContainer. When the
+// container turns unhealthy, its logs are included in the returned error.
+func (b *PostgresContainerBuilder) AwaitReady(ctx context.Context) (*PostgresContainer, error) {
+	if !b.started {
+		return nil, errors.New("container not started")
+	}
+	start := time.Now()
+	pg, err := b.awaitReady(ctx)
+	emitTo(b.config.Logger, Event{Type: EventContainerReady, ContainerID: b.containerID, Image: b.image, Duration: time.Since(start), Err: err})
+	return pg, err
+}
+
+// withLogs adds the last lines of the container's logs to err, which made the
+// startup fail, since the reason, such as bad settings, a failing init script
+// or running out of memory, is usually only found there. The logs are read
+// even if ctx is done, which is often why the startup failed.
+func (b *PostgresContainerBuilder) withLogs(ctx context.Context, err error) error {
+	logsCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), startupLogsTimeout)
+	defer cancel()
+	logs, logsErr := containerLogs(logsCtx, b.cli, b.containerID, startupLogLines)
+	return appendLogs(err, logs, logsErr)
+}
+
+// appendLogs adds logs, or why they couldn't be read, to err.
+func appendLogs(err error, logs string, logsErr error) error {
+	if logsErr != nil {
+		logs = fmt.Sprintf("(could not read logs: %v)", logsErr)
+	}
+	return fmt.Errorf("%w\ncontainer logs:\n%s", err, logs)
+}
+
+// waitHealthy waits until the container is healthy, or whatever the wait
+// strategy decides.
+func (b *PostgresContainerBuilder) waitHealthy(ctx context.Context, c *SQLContainer) (err error) {
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.health_wait", containerAttrs(b.containerID, b.image)...)
+	defer func() { end(err) }()
+	if b.config.WaitStrategy != nil {
+		return b.config.WaitStrategy.WaitUntilReady(ctx, c)
+	}
+	return waitUntilHealthy(ctx, b.cli, b.containerID, func(status string) {
+		emitTo(b.config.Logger, Event{Type: EventHealthChanged, ContainerID: b.containerID, Image: b.image, Status: status})
+	})
+}
+
+// waitConnectable waits until Postgres accepts connections at connStr and the
+// ReadyQuery, if any, returns a row.
+func (b *PostgresContainerBuilder) waitConnectable(ctx context.Context, connStr string) (err error) {
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.connect_wait", containerAttrs(b.containerID, b.image)...)
+	defer func() { end(err) }()
+	if err := waitUntilConnectable(ctx, connStr); err != nil {
+		return fmt.Errorf("wait for connection error: %w", err)
+	}
+	if b.config.ReadyQuery != "" {
+		return waitUntilReadyQuery(ctx, connStr, b.config.ReadyQuery)
+	}
+	return nil
+}
+
+// awaitReady waits until the started container serves the expected version.
+func (b *PostgresContainerBuilder) awaitReady(ctx context.Context) (*PostgresContainer, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, b.config.startupTimeout(waitTimeout))
+	defer cancel()
+
+	host, port := b.host, b.port
+	var ip string
+	var err error
+	viaIP := useContainerIP(b.config, b.cli.DaemonHost(), runningInContainer(fileExists))
+	if viaIP {
+		ip, err = containerIP(waitCtx, b.cli, b.containerID, b.config.Network)
+		if err != nil {
+			return nil, err
+		}
+		host, port = ip, "5432"
+	}
+	connStr := b.config.connectionString(host, port)
+	var networkConnStr string
+	if b.config.Network != "" {
+		if ip == "" && len(b.config.NetworkAliases) == 0 {
+			ip, err = containerIP(waitCtx, b.cli, b.containerID, b.config.Network)
+			if err != nil {
+				return nil, err
+			}
+		}
+		networkConnStr = b.config.connectionString(networkHost(b.config, ip), "5432")
+	}
+
+	if err := b.waitHealthy(waitCtx, &SQLContainer{
+		id:      b.containerID,
+		host:    host,
+		port:    port,
+		connStr: connStr,
+		cli:     b.cli,
+	}); err != nil {
+		return nil, b.withLogs(ctx, err)
+	}
+	if err := b.waitConnectable(waitCtx, connStr); err != nil {
+		return nil, b.withLogs(ctx, err)
+	}
+
+	// make sure the expected version is serving
+	serverVersion, err := queryServerVersion(waitCtx, connStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkServerVersion(b.version, serverVersion); err != nil {
+		return nil, err
+	}
+
+	pg := &PostgresContainer{
+		id:       b.containerID,
+		user:     b.config.DBUser,
+		password: b.config.DBPassword,
+		dbName:   b.config.DBName,
+		port:     port,
+		connStr:  connStr,
+		viaIP:    viaIP,
+		network:  b.config.Network,
+
+		networkConnStr: networkConnStr,
+		serverVersion:  serverVersion,
+		docker:         b.config.docker(),
+		profile:        b.config.Profile,
+		logger:         b.config.Logger,
+	}
+	if err := b.config.provision(ctx, waitCtx, pg); err != nil {
+		return nil, err
+	}
+	return pg, nil
+}
+
+// connectionString returns the connection string of a Postgres server
+// configured by c that listens on the given host and port.
+func (c *PostgresContainerConfig) connectionString(host, port string) string {
+	return fmt.Sprintf(
+		"postgres://%s:%s@%s/%s?sslmode=%s",
+		c.DBUser,
+		c.DBPassword,
+		net.JoinHostPort(host, port),
+		c.DBName,
+		c.SSLMode,
+	)
+}
+
+// provision creates the configured extensions, bounded by waitCtx, and then
+// calls the OnHealthy hook on the ready container.
+func (c *PostgresContainerConfig) provision(ctx, waitCtx context.Context, pg *PostgresContainer) error {
+	if len(c.Extensions) > 0 {
+		db, err := pg.open(pg.connStr)
+		if err != nil {
+			return err
+		}
+		err = createExtensions(waitCtx, db, c.Extensions)
+		db.Close()
+		if err != nil {
+			return err
+		}
+		if !pg.external {
+			// databases created later, e.g. by TestDB, are copies of template1
+			err = pg.withDatabase(waitCtx, "template1", func(db *sql.DB) error {
+				return createExtensions(waitCtx, db, c.Extensions)
+			})
+			if err != nil {
+				return fmt.Errorf("template1: %w", err)
+			}
+		}
+	}
+	if c.OnHealthy != nil {
+		if err := c.OnHealthy(ctx, pg); err != nil {
+			return fmt.Errorf("OnHealthy hook error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Abort stops and removes the container, if one was created, so that the
+// builder can start over from CreateContainer. It's meant for cleaning up
+// after a failed phase; a ready container should be cleaned up with
+// PostgresContainer.Shutdown instead.
+func (b *PostgresContainerBuilder) Abort(ctx context.Context) error {
+	if b.containerID == "" {
+		return nil
+	}
+	if b.started {
+		if err := ignoreRemoved(b.cli.ContainerStop(ctx, b.containerID, nil)); err != nil {
+			return fmt.Errorf("error stopping container: %w", err)
+		}
+		b.started = false
+	}
+	if err := ignoreRemoved(b.cli.ContainerRemove(ctx, b.containerID, types.ContainerRemoveOptions{})); err != nil {
+		return fmt.Errorf("error removing container: %w", err)
+	}
+	b.containerID = ""
+	if b.config.HostPort == 0 {
+		b.port = ""
+	}
+	return nil
+}
+
+// Close releases the builder's Docker client, unless it was passed in with
+// WithDockerClient. It does not affect the container.
+func (b *PostgresContainerBuilder) Close() error {
+	return b.release()
+}
+
+// build runs the create, start and wait phases once, aborting the container
+// if any of them fails, including the OnCreated hook after the container was
+// created, so that the next attempt starts over.
+func (b *PostgresContainerBuilder) build(ctx context.Context) (*PostgresContainer, error) {
+	err := b.CreateContainer(ctx)
+	if err == nil {
+		err = b.Start(ctx)
+	}
+	if err == nil {
+		var pg *PostgresContainer
+		pg, err = b.AwaitReady(ctx)
+		if err == nil {
+			return pg, nil
+		}
+	}
+	if b.containerID == "" {
+		return nil, err
+	}
+	if abortErr := b.Abort(ctx); abortErr != nil {
+		orDefault(b.config.Logger).Error("could not remove container", "container_id", b.containerID, "err", abortErr)
+	}
+	return nil, err
+}
@@ -0,0 +1,242 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// newTestBuilder returns a builder for a fake daemon serving routes.
+func newTestBuilder(t *testing.T, routes map[string]http.HandlerFunc, options ...Option) (*PostgresContainerBuilder, *fakeDocker) {
+	t.Helper()
+
+	cli, docker := newFakeDocker(t, routes)
+	b, err := NewPostgresContainerBuilder("16", append(options, WithDockerClient(cli))...)
+	if err != nil {
+		t.Fatalf("NewPostgresContainerBuilder() error = %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b, docker
+}
+
+func TestBuildAbortsOnCreatedHookError(t *testing.T) {
+	t.Parallel()
+
+	hookErr := errors.New("hook failed")
+	b, docker := newTestBuilder(t, map[string]http.HandlerFunc{
+		"POST /containers/create": respond(http.StatusCreated, container.ContainerCreateCreatedBody{ID: "abc"}),
+		"DELETE /containers/abc":  respond(http.StatusNoContent, nil),
+	}, WithOnCreated(func(context.Context, *PostgresContainerBuilder) error {
+		return hookErr
+	}))
+
+	_, err := b.build(context.Background())
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("build() error = %v, want %v", err, hookErr)
+	}
+	if got := docker.called("DELETE /containers/abc"); got != 1 {
+		t.Errorf("container removed %d times, want 1", got)
+	}
+	if got := b.ContainerID(); got != "" {
+		t.Errorf("ContainerID() = %q after build(), want empty", got)
+	}
+	if got := docker.called("POST /containers/abc/start"); got != 0 {
+		t.Errorf("container started %d times, want 0", got)
+	}
+}
+
+func TestBuilderPhaseOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		created bool
+		phase   func(b *PostgresContainerBuilder, ctx context.Context) error
+		wantErr string
+	}{
+		{
+			name:    "start before create",
+			phase:   (*PostgresContainerBuilder).Start,
+			wantErr: "container not created",
+		},
+		{
+			name: "await before start",
+			phase: func(b *PostgresContainerBuilder, ctx context.Context) error {
+				_, err := b.AwaitReady(ctx)
+				return err
+			},
+			created: true,
+			wantErr: "container not started",
+		},
+		{
+			name:    "create twice",
+			phase:   (*PostgresContainerBuilder).CreateContainer,
+			created: true,
+			wantErr: "container already created",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, docker := newTestBuilder(t, nil)
+			if tt.created {
+				b.containerID = "abc"
+			}
+			err := tt.phase(b, context.Background())
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if calls := docker.requests(); len(calls) != 0 {
+				t.Errorf("Docker called with %v, want no calls", calls)
+			}
+		})
+	}
+}
+
+func TestBuilderAbort(t *testing.T) {
+	t.Parallel()
+
+	noContent := respond(http.StatusNoContent, nil)
+	notFound := respond(http.StatusNotFound, map[string]string{"message": "No such container: abc"})
+	failed := respond(http.StatusInternalServerError, map[string]string{"message": "boom"})
+
+	tests := []struct {
+		name        string
+		containerID string
+		started     bool
+		routes      map[string]http.HandlerFunc
+		wantCalls   []string
+		wantErr     bool
+	}{
+		{
+			name: "nothing created",
+		},
+		{
+			name:        "created",
+			containerID: "abc",
+			routes:      map[string]http.HandlerFunc{"DELETE /containers/abc": noContent},
+			wantCalls:   []string{"DELETE /containers/abc"},
+		},
+		{
+			name:        "started",
+			containerID: "abc",
+			started:     true,
+			routes: map[string]http.HandlerFunc{
+				"POST /containers/abc/stop": noContent,
+				"DELETE /containers/abc":    noContent,
+			},
+			wantCalls: []string{"POST /containers/abc/stop", "DELETE /containers/abc"},
+		},
+		{
+			name:        "already removed",
+			containerID: "abc",
+			started:     true,
+			routes: map[string]http.HandlerFunc{
+				"POST /containers/abc/stop": notFound,
+				"DELETE /containers/abc":    notFound,
+			},
+			wantCalls: []string{"POST /containers/abc/stop", "DELETE /containers/abc"},
+		},
+		{
+			name:        "stop failed",
+			containerID: "abc",
+			started:     true,
+			routes:      map[string]http.HandlerFunc{"POST /containers/abc/stop": failed},
+			wantCalls:   []string{"POST /containers/abc/stop"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, docker := newTestBuilder(t, tt.routes)
+			b.containerID = tt.containerID
+			b.started = tt.started
+			b.port = "55001"
+
+			err := b.Abort(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Abort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls := docker.requests(); !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("Docker called with %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if b.ContainerID() != tt.containerID {
+					t.Errorf("ContainerID() = %q after a failed Abort(), want %q", b.ContainerID(), tt.containerID)
+				}
+				return
+			}
+			if b.ContainerID() != "" || b.started {
+				t.Errorf("builder still has container %q, started %v", b.ContainerID(), b.started)
+			}
+			if tt.containerID != "" && b.port != "" {
+				t.Errorf("port = %q after Abort(), want it reset for Docker to assign", b.port)
+			}
+		})
+	}
+}
+
+func TestBuildWithRetryOnUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		options      []Option
+		wantAttempts int
+	}{
+		{name: "no retry", wantAttempts: 1},
+		{name: "retry", options: []Option{WithRetryOnUnhealthy()}, wantAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			noContent := respond(http.StatusNoContent, nil)
+			b, docker := newTestBuilder(t, map[string]http.HandlerFunc{
+				"POST /containers/create":    respond(http.StatusCreated, container.ContainerCreateCreatedBody{ID: "abc"}),
+				"POST /containers/abc/start": noContent,
+				"GET /containers/abc/json": inspectResponse(
+					&types.ContainerState{Running: true, Health: &types.Health{Status: "unhealthy"}},
+					&types.NetworkSettings{NetworkSettingsBase: types.NetworkSettingsBase{
+						Ports: nat.PortMap{"5432/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "55001"}}},
+					}},
+				),
+				"POST /containers/abc/stop": noContent,
+				"DELETE /containers/abc":    noContent,
+			}, append(tt.options, WithAddressing(AddressingPublishedPort))...)
+
+			_, err := b.buildWithRetry(context.Background())
+			if !errors.Is(err, errUnhealthy) {
+				t.Fatalf("buildWithRetry() error = %v, want %v", err, errUnhealthy)
+			}
+			for _, route := range []string{
+				"POST /containers/create",
+				"POST /containers/abc/start",
+				"POST /containers/abc/stop",
+				"DELETE /containers/abc",
+			} {
+				if got := docker.called(route); got != tt.wantAttempts {
+					t.Errorf("%s called %d times, want %d", route, got, tt.wantAttempts)
+				}
+			}
+			if b.ContainerID() != "" {
+				t.Errorf("ContainerID() = %q, want the unhealthy container aborted", b.ContainerID())
+			}
+		})
+	}
+}
@@ -1,20 +1,29 @@
 package sqltestutil
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"database/sql"
 	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"math/big"
 	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
+	"github.com/docker/docker/pkg/stdcopy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -22,6 +31,22 @@ import (
 const (
 	waitInterval = 100 * time.Millisecond
 	waitTimeout  = 10 * time.Second
+
+	// startupLogLines is the number of container log lines included in the
+	// error when a container doesn't become ready during startup.
+	startupLogLines = 50
+	// startupLogsTimeout bounds reading those lines.
+	startupLogsTimeout = 5 * time.Second
+
+	// defaultShmSize is the size of /dev/shm Docker gives containers when no
+	// --shm-size is set.
+	defaultShmSize = 64 * 1024 * 1024
+	// minShmSize is the least /dev/shm Postgres containers get unless ShmSize
+	// is set, since defaultShmSize is too small for parallel queries and
+	// large work_mem sorts.
+	minShmSize = 256 * 1024 * 1024
+	// minSharedBuffers is the least shared_buffers Postgres starts with.
+	minSharedBuffers = 128 * 1024
 )
 
 // PostgresContainerConfig is a configuration struct for PostgresContainer.
@@ -37,6 +62,155 @@ type PostgresContainerConfig struct {
 	TimeZone string
 	// SSLMode is to set sslmode query parameter in the connection string
 	SSLMode string
+	// RetryOnUnhealthy makes StartPostgresContainer remove a container that
+	// turns unhealthy during startup and try once more before failing
+	RetryOnUnhealthy bool
+	// StartupRetry retries the whole startup when it fails with a transient
+	// error, see WithStartupRetry. It takes precedence over RetryOnUnhealthy.
+	StartupRetry *RetryPolicy
+	// StartupTimeout is how long to wait for the database to become ready once
+	// the container is started. Zero uses a default suited to the database.
+	StartupTimeout time.Duration
+	// HealthcheckInterval, HealthcheckTimeout and HealthcheckRetries configure
+	// the container's healthcheck, which runs DefaultQueries.Healthcheck as
+	// DBUser. Zero uses 1s, 1s and 10 retries.
+	HealthcheckInterval time.Duration
+	HealthcheckTimeout  time.Duration
+	HealthcheckRetries  int
+	// ReadyQuery must return a row before the container is returned, see
+	// WithReadyQuery. Optional.
+	ReadyQuery string
+	// WaitStrategy decides when Postgres is ready, before it's checked to be
+	// connectable. Nil waits for the healthcheck.
+	WaitStrategy WaitStrategy
+	// SharedBuffers is the size in bytes of the shared_buffers server setting.
+	// When set, the container's /dev/shm is sized to match. Zero keeps the
+	// image default; otherwise it must be at least 128kB, the minimum of
+	// Postgres.
+	SharedBuffers int64
+	// ShmSize is the size in bytes of the container's /dev/shm. Zero sizes it
+	// for SharedBuffers, and to at least 256MB.
+	ShmSize int64
+	// TmpfsData mounts the data directory on tmpfs, of TmpfsSize bytes or,
+	// if that's zero, of the Docker default size, see WithTmpfsData.
+	TmpfsData bool
+	TmpfsSize int64
+	// FastSettings trades durability for speed, see WithFastSettings.
+	FastSettings bool
+	// Settings are postgresql.conf settings the server is started with, see
+	// WithPostgresConfig.
+	Settings map[string]string
+	// Cmd and Entrypoint replace the command and entrypoint of the image, see
+	// WithCmd and WithEntrypoint.
+	Cmd        []string
+	Entrypoint []string
+	// Env are extra environment variables of the container, as KEY=value,
+	// see WithEnv.
+	Env []string
+	// HostPort is the host port Postgres is published on instead of a random
+	// one, see WithHostPort.
+	HostPort int
+	// Image is the Postgres-compatible image to run instead of
+	// "postgres:<version>", including its tag.
+	Image string
+	// PostGIS runs the postgis/postgis image instead of postgres, see
+	// WithPostGIS.
+	PostGIS bool
+	// PgVector runs the pgvector/pgvector image instead of postgres, see
+	// WithPgVector.
+	PgVector bool
+	// DockerClientOpts are applied to the Docker clients used for the
+	// container, after the defaults of reading the environment and
+	// negotiating the API version.
+	DockerClientOpts []client.Opt
+	// ReuseKey makes StartPostgresContainer reuse the container started with
+	// the same key, see WithReuse.
+	ReuseKey string
+	// ContainerName is the name of the container, which is attached to if it
+	// exists, see WithContainerName.
+	ContainerName string
+	// Maintenance is run on an existing container picked up with ReuseKey or
+	// ContainerName, see WithMaintenance.
+	Maintenance *MaintenancePolicy
+	// Reaper starts a reaper container that removes the containers of the
+	// test process once it exits, see WithReaper.
+	Reaper bool
+	// PoolIdleTimeout is how long a ContainerPool keeps a ready container
+	// that isn't claimed, see WithPoolIdleTimeout.
+	PoolIdleTimeout time.Duration
+	// DockerClient, if set, is used instead of creating Docker clients, and
+	// DockerClientOpts are ignored. It isn't closed by this package.
+	DockerClient *client.Client
+	// RegistryUsername and RegistryPassword are the credentials to pull the
+	// image with. When both are empty, the credentials the docker CLI would
+	// use are taken from the Docker config file, if any.
+	RegistryUsername string
+	RegistryPassword string
+	// PullPolicy decides when the image is pulled. Defaults to
+	// PullIfNotPresent.
+	PullPolicy PullPolicy
+	// Platform is the platform of the image to pull and run, in the
+	// "os/arch[/variant]" form of docker --platform. Empty lets the daemon
+	// choose, see WithPlatform.
+	Platform string
+	// KubeContext and KubeNamespace select the cluster context and namespace
+	// StartPostgresPod starts the pod in. Empty values keep kubectl's
+	// defaults.
+	KubeContext   string
+	KubeNamespace string
+	// HostOverride is the host the container's published port is reached on.
+	// By default it's the host of DOCKER_HOST for a remote daemon, and
+	// 127.0.0.1 otherwise.
+	HostOverride string
+	// Network is the Docker network the container is attached to, e.g. the
+	// network shared with a CI job's container. Empty uses Docker's default.
+	Network string
+	// NetworkAliases are the names the container has on Network, which other
+	// containers on it can reach it by. Requires Network.
+	NetworkAliases []string
+	// Addressing decides whether the connection string uses the published
+	// port or the container's IP address. Defaults to AddressingAuto.
+	Addressing Addressing
+	// Profile is how privileged the database user is, see ConnectPostgres.
+	Profile Profile
+	// Logger logs the lifecycle of the container, see WithLogger. Optional.
+	Logger *slog.Logger
+	// TracerProvider traces the startup of the container, see
+	// WithTracerProvider. Optional.
+	TracerProvider trace.TracerProvider
+	// PasswordFunc, if set, is called for every new connection to get the
+	// password to authenticate with, e.g. a short-lived IAM token. It
+	// replaces the password in the connection string. Only ConnectPostgres
+	// uses it.
+	PasswordFunc func(ctx context.Context) (string, error)
+	// Extensions are created in the database once Postgres is ready.
+	Extensions []string
+	// InitScripts are the local paths of scripts, or of a directory of them,
+	// that the image runs when it initializes the database, see
+	// WithInitScripts.
+	InitScripts []string
+	// Volumes are mounted into the container, see WithVolume.
+	Volumes []Volume
+	// InitDBArgs are extra arguments of initdb when the image initializes the
+	// database, see WithInitDBArgs.
+	InitDBArgs []string
+	// Locale is the locale the database is initialized with, see WithLocale.
+	// Empty keeps the image default.
+	Locale string
+	// Ulimits are the resource limits of the container, on top of a raised
+	// open files limit, see WithUlimit.
+	Ulimits []Ulimit
+	// AutoRemove makes Docker remove the container once it stops, see
+	// WithAutoRemove.
+	AutoRemove bool
+	// OnCreated is called once the container is created, before it's started.
+	OnCreated func(ctx context.Context, b *PostgresContainerBuilder) error
+	// OnStarted is called once the container is started, before waiting for
+	// Postgres.
+	OnStarted func(ctx context.Context, b *PostgresContainerBuilder) error
+	// OnHealthy is called once Postgres accepts connections, before the
+	// container is returned.
+	OnHealthy func(ctx context.Context, pg *PostgresContainer) error
 }
 
 // PostgresContainerConfig setter
@@ -77,18 +251,744 @@ func WithSSLMode(sslMode string) Option {
 	}
 }
 
+// WithSharedBuffers sets the SharedBuffers field of the
+// PostgresContainerConfig. The size is in bytes and is rounded down to whole
+// kilobytes. The container's shared memory (--shm-size) is grown along with it,
+// since the Docker default of 64MB is too small for larger test datasets and
+// makes Postgres fail with "could not resize shared memory segment".
+func WithSharedBuffers(size int64) Option {
+	return func(c *PostgresContainerConfig) {
+		c.SharedBuffers = size
+	}
+}
+
+// WithShmSize sets the ShmSize field of the PostgresContainerConfig, the size
+// in bytes of the container's shared memory (--shm-size). Postgres uses it for
+// parallel query workers and large sorts, which fail with "could not resize
+// shared memory segment" when it runs out.
+func WithShmSize(size int64) Option {
+	return func(c *PostgresContainerConfig) {
+		c.ShmSize = size
+	}
+}
+
+// WithTmpfsData sets the TmpfsData and TmpfsSize fields of the
+// PostgresContainerConfig. The data directory is then kept in memory, so
+// writes never hit the disk, which speeds up migration and fixture heavy
+// suites considerably. size bounds the tmpfs in bytes; zero leaves it to
+// Docker, which allows half of the host's memory. The data is lost when the
+// container stops, and a database larger than size fails with "No space left
+// on device", so it's only suited to throwaway test databases.
+func WithTmpfsData(size int64) Option {
+	return func(c *PostgresContainerConfig) {
+		c.TmpfsData = true
+		c.TmpfsSize = size
+	}
+}
+
+// WithFastSettings sets the FastSettings field of the
+// PostgresContainerConfig. The server then runs with fsync, synchronous_commit,
+// full_page_writes and jit turned off, which cuts the latency of writes and of
+// the short queries of tests considerably. A crash of the container can
+// corrupt the data, so it's strictly for test databases. The settings need
+// Postgres 11 or later.
+func WithFastSettings() Option {
+	return func(c *PostgresContainerConfig) {
+		c.FastSettings = true
+	}
+}
+
+// WithPostgresConfig adds settings to the Settings field of the
+// PostgresContainerConfig, e.g. {"max_connections": "500", "statement_timeout":
+// "5s"}. The server is started with a -c name=value argument for each, which
+// takes precedence over postgresql.conf and over the settings of
+// WithSharedBuffers and WithFastSettings. Prefer WithSharedBuffers for
+// shared_buffers, which also grows the container's shared memory.
+func WithPostgresConfig(settings map[string]string) Option {
+	return func(c *PostgresContainerConfig) {
+		if c.Settings == nil {
+			c.Settings = map[string]string{}
+		}
+		for name, value := range settings {
+			c.Settings[name] = value
+		}
+	}
+}
+
+// WithCmd sets the Cmd field of the PostgresContainerConfig, the command the
+// image's entrypoint runs, e.g. {"postgres", "-c", "log_statement=all"}. It
+// replaces the command built from WithSharedBuffers, WithFastSettings and
+// WithPostgresConfig, whose settings then only apply if cmd includes them.
+func WithCmd(cmd ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Cmd = cmd
+	}
+}
+
+// WithEntrypoint sets the Entrypoint field of the PostgresContainerConfig,
+// e.g. to run a wrapper script that prepares the container before handing
+// over to the image's docker-entrypoint.sh. The entrypoint receives the
+// command as arguments and must end up running Postgres the way the official
+// image does, including the database initialization.
+func WithEntrypoint(entrypoint ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Entrypoint = entrypoint
+	}
+}
+
+// WithEnv adds the environment variable key to the Env field of the
+// PostgresContainerConfig, e.g. POSTGRES_INITDB_ARGS or PGOPTIONS. It can be
+// passed several times, and a later value for the same key wins. The
+// variables the package sets from the config, such as POSTGRES_USER, can't be
+// overridden; use the dedicated options instead.
+func WithEnv(key, value string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Env = append(c.Env, key+"="+value)
+	}
+}
+
+// WithHostPort sets the HostPort field of the PostgresContainerConfig, so
+// that GUI clients, debuggers or other processes can connect to a known port.
+// Startup fails if the port is already taken, e.g. by a container of an
+// earlier run that wasn't shut down.
+func WithHostPort(port int) Option {
+	return func(c *PostgresContainerConfig) {
+		c.HostPort = port
+	}
+}
+
+// WithImage sets the Image field of the PostgresContainerConfig, e.g. to run
+// "timescale/timescaledb:2.14-pg15" or an image from an internal mirror. The
+// image must accept the environment variables and the pg_isready healthcheck
+// of the official postgres image, or DefaultQueries.Healthcheck must be
+// adjusted to it. The version passed to
+// StartPostgresContainer is still checked against the server's major version,
+// unless it's empty.
+func WithImage(image string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Image = image
+	}
+}
+
+// WithPostGIS sets the PostGIS field of the PostgresContainerConfig and adds
+// the postgis extension to its Extensions. The container then runs
+// "postgis/postgis:<version>-<PostGISVersion>", unless WithImage is also
+// passed, and has the extension created. Scenario files can set geometry
+// columns with the !geometry tag, see LoadScenario. The postgis/postgis images
+// are only built for amd64, so they run emulated on arm64 hosts.
+func WithPostGIS() Option {
+	return func(c *PostgresContainerConfig) {
+		c.PostGIS = true
+		c.Extensions = append(c.Extensions, "postgis")
+	}
+}
+
+// WithPgVector sets the PgVector field of the PostgresContainerConfig and
+// adds the vector extension to its Extensions. The container then runs
+// "pgvector/pgvector:pg<version>", unless WithImage is also passed, and has
+// the extension created. Scenario files can set vector columns with the
+// !vector tag, see LoadScenario.
+func WithPgVector() Option {
+	return func(c *PostgresContainerConfig) {
+		c.PgVector = true
+		c.Extensions = append(c.Extensions, "vector")
+	}
+}
+
+// WithDockerClientOpts sets the DockerClientOpts field of the
+// PostgresContainerConfig, for full control over how the Docker daemon is
+// reached, e.g. client.WithHost or client.WithVersion to pin an API version.
+// They apply to every container type, as well as to Shutdown and the other
+// operations on the started container.
+func WithDockerClientOpts(opts ...client.Opt) Option {
+	return func(c *PostgresContainerConfig) {
+		c.DockerClientOpts = append(c.DockerClientOpts, opts...)
+	}
+}
+
+// WithReuse sets the ReuseKey field of the PostgresContainerConfig.
+// StartPostgresContainer then looks for a container started earlier with the
+// same key, image, user and database name, e.g. by a previous go test run, and
+// reuses it instead of starting a new one, which saves the startup time on
+// every local iteration. If there's none, the container it starts is kept for
+// later runs: Shutdown leaves it running unless WithRemoveReused is passed,
+// and neither the reaper nor CleanupOrphans remove it.
+//
+// A reused container keeps the data of earlier runs, so tests must create
+// what they need, e.g. with TestDB or PrepareDatabase. The lifecycle hooks
+// other than OnHealthy only run when the container is created. Reuse is meant
+// for local development rather than CI.
+func WithReuse(key string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.ReuseKey = key
+	}
+}
+
+// WithContainerName sets the ContainerName field of the
+// PostgresContainerConfig. StartPostgresContainer then attaches to the
+// container of that name if it exists, starting it if it's stopped, and
+// creates it under that name otherwise, so developers can keep one long-lived
+// container, e.g. "myproj-pgtest", while iterating locally. An existing
+// container must run the same image, user and database name, or an error is
+// returned. Like a container started with WithReuse, it's kept by Shutdown
+// unless WithRemoveReused is passed, and by the reaper and CleanupOrphans.
+func WithContainerName(name string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.ContainerName = name
+	}
+}
+
+// WithMaintenance sets the Maintenance field of the PostgresContainerConfig.
+// When StartPostgresContainer picks up an existing container with WithReuse or
+// WithContainerName, it runs PostgresContainer.Maintain with policy on it
+// before returning, so a long-lived container doesn't slowly accumulate bloat.
+// A newly created container has nothing to clean up and is left alone.
+func WithMaintenance(policy MaintenancePolicy) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Maintenance = &policy
+	}
+}
+
+// WithPoolIdleTimeout sets the PoolIdleTimeout field of the
+// PostgresContainerConfig. A ContainerPool shuts down a ready container that
+// isn't claimed within d, and only starts a new one once a container is asked
+// for again, so an idle pool doesn't tie up resources for the rest of a long
+// test run. Zero keeps the containers until the pool is closed.
+func WithPoolIdleTimeout(d time.Duration) Option {
+	return func(c *PostgresContainerConfig) {
+		c.PoolIdleTimeout = d
+	}
+}
+
+// WithReaper sets the Reaper field of the PostgresContainerConfig. The reaper
+// is a container running ReaperImage, started once per test process, that
+// force-removes the containers the process started once it exits, whether it
+// called Shutdown or not, e.g. after a panic, a timeout or SIGKILL. It needs
+// access to the Docker socket from inside a container.
+func WithReaper() Option {
+	return func(c *PostgresContainerConfig) {
+		c.Reaper = true
+	}
+}
+
+// WithDockerClient sets the DockerClient field of the
+// PostgresContainerConfig, to use a client built by the caller, e.g. with a
+// custom TLS configuration or an HTTP client that fakes the daemon in tests.
+// The caller remains responsible for closing it, after Shutdown.
+func WithDockerClient(cli *client.Client) Option {
+	return func(c *PostgresContainerConfig) {
+		c.DockerClient = cli
+	}
+}
+
+// docker returns how the Docker clients for the container are obtained.
+func (c *PostgresContainerConfig) docker() dockerConfig {
+	return dockerConfig{client: c.DockerClient, opts: c.DockerClientOpts}
+}
+
+// WithDockerSocket makes the Docker clients connect to the daemon listening on
+// the Unix socket at path, e.g. a rootless daemon in a non-standard location.
+// It takes precedence over DOCKER_HOST and the detected socket.
+func WithDockerSocket(path string) Option {
+	return WithDockerClientOpts(client.WithHost("unix://" + path))
+}
+
+// WithRegistryAuth sets the RegistryUsername and RegistryPassword fields of the
+// PostgresContainerConfig, for pulling images from a registry that requires
+// authentication. The password may also be an access token. Without this
+// option, credentials are looked up in $DOCKER_CONFIG/config.json or
+// ~/.docker/config.json, including credential helpers, like the docker CLI
+// does.
+func WithRegistryAuth(username, password string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.RegistryUsername = username
+		c.RegistryPassword = password
+	}
+}
+
+// PullPolicy decides whether an image is pulled before the container is
+// created.
+type PullPolicy int
+
+const (
+	// PullIfNotPresent pulls the image only if it isn't cached locally.
+	PullIfNotPresent PullPolicy = iota
+	// PullAlways pulls the image every time, to pick up patched images.
+	PullAlways
+	// PullNever never pulls the image and fails if it isn't cached locally,
+	// for air-gapped environments.
+	PullNever
+)
+
+// WithPullPolicy sets the PullPolicy field of the PostgresContainerConfig.
+func WithPullPolicy(policy PullPolicy) Option {
+	return func(c *PostgresContainerConfig) {
+		c.PullPolicy = policy
+	}
+}
+
+// WithPlatform sets the Platform field of the PostgresContainerConfig, e.g.
+// "linux/amd64" to run an amd64-only image emulated on an Apple Silicon Mac,
+// as some images with bundled extensions have no arm64 build. A cached image
+// of another platform is pulled again. Whenever an image runs emulated, with
+// or without WithPlatform, a warning is printed, since it's much slower.
+func WithPlatform(platform string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Platform = platform
+	}
+}
+
+// WithKubeContext sets the KubeContext field of the PostgresContainerConfig.
+// It only applies to StartPostgresPod.
+func WithKubeContext(kubeContext string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.KubeContext = kubeContext
+	}
+}
+
+// WithKubeNamespace sets the KubeNamespace field of the
+// PostgresContainerConfig. It only applies to StartPostgresPod.
+func WithKubeNamespace(namespace string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.KubeNamespace = namespace
+	}
+}
+
+// WithHostOverride sets the HostOverride field of the PostgresContainerConfig,
+// for setups where the published ports aren't reachable on the daemon's host,
+// e.g. "host.docker.internal" when the tests run in a container themselves.
+func WithHostOverride(host string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.HostOverride = host
+	}
+}
+
+// WithNetwork sets the Network field of the PostgresContainerConfig. When the
+// tests run in a container on the same network, the container is reached on
+// its IP address on that network.
+func WithNetwork(network string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Network = network
+	}
+}
+
+// WithNetworkAliases adds to the NetworkAliases field of the
+// PostgresContainerConfig, e.g. to let the application under test, running in
+// another container on the network, connect to "postgres:5432". See
+// NetworkConnectionString.
+func WithNetworkAliases(aliases ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.NetworkAliases = append(c.NetworkAliases, aliases...)
+	}
+}
+
+// WithAddressing sets the Addressing field of the PostgresContainerConfig.
+func WithAddressing(addressing Addressing) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Addressing = addressing
+	}
+}
+
+// WithProfile sets the Profile field of the PostgresContainerConfig, e.g. to
+// ProfileManaged for Amazon RDS or Aurora.
+func WithProfile(profile Profile) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Profile = profile
+	}
+}
+
+// WithPasswordFunc sets the PasswordFunc field of the
+// PostgresContainerConfig.
+func WithPasswordFunc(fn func(ctx context.Context) (string, error)) Option {
+	return func(c *PostgresContainerConfig) {
+		c.PasswordFunc = fn
+	}
+}
+
+// WithExtensions sets the Extensions field of the PostgresContainerConfig.
+// The extensions are created with CREATE EXTENSION before the container is
+// returned, in the database and, for containers, in template1, so that the
+// databases created by TestDB and PrepareDatabase have them too. If any of
+// them isn't available in the image, startup fails with an error naming them,
+// and known images that include them.
+func WithExtensions(names ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Extensions = append(c.Extensions, names...)
+	}
+}
+
+// WithInitScripts adds to the InitScripts field of the
+// PostgresContainerConfig. The paths are bind-mounted read-only into
+// /docker-entrypoint-initdb.d, where the official postgres image runs *.sql,
+// *.sql.gz and *.sh files in name order when it initializes the database,
+// before the container is reported ready. A directory is mounted as a whole,
+// and files are mounted into it, so at most one directory can be given. It's
+// meant for roles, extensions and schema that must exist before tests
+// connect. Bind mounts need the paths to exist on the Docker host, so they
+// don't work with a remote daemon; use WithOnCreated and
+// PostgresContainerBuilder.CopyFileToContainer there instead.
+func WithInitScripts(paths ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.InitScripts = append(c.InitScripts, paths...)
+	}
+}
+
+// WithVolume adds to the Volumes field of the PostgresContainerConfig. It
+// mounts hostPath, a directory or file on the Docker host or the name of a
+// Docker volume, on containerPath in the container, e.g. seed dumps to restore,
+// custom configuration files, or a named volume on /var/lib/postgresql/data to
+// keep the data across runs. Like WithInitScripts, host paths don't work with a
+// remote daemon.
+func WithVolume(hostPath, containerPath string, readOnly bool) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Volumes = append(c.Volumes, Volume{
+			HostPath:      hostPath,
+			ContainerPath: containerPath,
+			ReadOnly:      readOnly,
+		})
+	}
+}
+
+// WithInitDBArgs adds to the InitDBArgs field of the PostgresContainerConfig,
+// e.g. WithInitDBArgs("--data-checksums"). They're passed in
+// POSTGRES_INITDB_ARGS, which the image's entrypoint evaluates with the shell,
+// so arguments with spaces must be quoted for it.
+func WithInitDBArgs(args ...string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.InitDBArgs = append(c.InitDBArgs, args...)
+	}
+}
+
+// WithLocale sets the Locale field of the PostgresContainerConfig, e.g.
+// "de_DE.UTF-8", to test collation and other locale sensitive behavior. It's
+// passed to initdb as --locale, so the locale must be installed in the image;
+// the Debian based postgres images only include en_US.UTF-8. On Postgres 15
+// and later, WithInitDBArgs("--locale-provider=icu", "--icu-locale=de-DE")
+// works without installing locales.
+func WithLocale(locale string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Locale = locale
+	}
+}
+
+// WithUlimit adds to the Ulimits field of the PostgresContainerConfig (docker
+// run --ulimit), e.g. WithUlimit("nproc", 4096, 4096). The open files limit
+// ("nofile") is raised to 65536 by default, since the defaults of some CI
+// runners make Postgres fail in tests with many connections; WithUlimit
+// overrides that too.
+func WithUlimit(name string, soft, hard int64) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Ulimits = append(c.Ulimits, Ulimit{Name: name, Soft: soft, Hard: hard})
+	}
+}
+
+// WithAutoRemove sets the AutoRemove field of the PostgresContainerConfig
+// (docker run --rm). Docker then removes the container itself when it stops,
+// e.g. when the daemon restarts or the container is killed, as a safety net
+// for when Shutdown never runs. Shutdown still works and doesn't mind the
+// container being gone already. Its logs can't be read after it stopped.
+func WithAutoRemove() Option {
+	return func(c *PostgresContainerConfig) {
+		c.AutoRemove = true
+	}
+}
+
+// WithOnCreated sets the OnCreated field of the PostgresContainerConfig, e.g.
+// to copy files into the container with
+// PostgresContainerBuilder.CopyToContainer before Postgres starts. An error
+// returned by the hook fails the startup and the container is removed.
+func WithOnCreated(hook func(ctx context.Context, b *PostgresContainerBuilder) error) Option {
+	return func(c *PostgresContainerConfig) {
+		c.OnCreated = hook
+	}
+}
+
+// WithOnStarted sets the OnStarted field of the PostgresContainerConfig. An
+// error returned by the hook fails the startup and the container is removed.
+func WithOnStarted(hook func(ctx context.Context, b *PostgresContainerBuilder) error) Option {
+	return func(c *PostgresContainerConfig) {
+		c.OnStarted = hook
+	}
+}
+
+// WithOnHealthy sets the OnHealthy field of the PostgresContainerConfig, for
+// provisioning that needs a running server, such as enabling extensions or
+// creating roles:
+//
+//	sqltestutil.WithOnHealthy(func(ctx context.Context, pg *sqltestutil.PostgresContainer) error {
+//	    db, err := sql.Open("pgx", pg.ConnectionString())
+//	    if err != nil {
+//	        return err
+//	    }
+//	    defer db.Close()
+//	    _, err = db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pgcrypto")
+//	    return err
+//	})
+//
+// An error returned by the hook fails the startup and the container is
+// removed.
+func WithOnHealthy(hook func(ctx context.Context, pg *PostgresContainer) error) Option {
+	return func(c *PostgresContainerConfig) {
+		c.OnHealthy = hook
+	}
+}
+
+// WithRetryOnUnhealthy sets the RetryOnUnhealthy field of the
+// PostgresContainerConfig. This is useful on heavily loaded CI machines, where
+// a container occasionally goes unhealthy before Postgres had a chance to come
+// up. The error returned after a failed retry includes the logs of both
+// attempts.
+func WithRetryOnUnhealthy() Option {
+	return func(c *PostgresContainerConfig) {
+		c.RetryOnUnhealthy = true
+	}
+}
+
+// WithStartupRetry sets the StartupRetry field of the PostgresContainerConfig.
+// StartPostgresContainer then retries pulling the image, and creating,
+// starting and waiting for the container, with the backoff of policy, when
+// they fail with a transient error: the daemon failing or being unavailable,
+// e.g. during a flaky pull, a published port being taken, or the container
+// turning unhealthy or not becoming ready in time. A failed container is
+// removed before the next attempt. The error of every attempt is returned if
+// all fail. Containers reused with WithReuse or WithContainerName aren't
+// retried.
+func WithStartupRetry(policy RetryPolicy) Option {
+	return func(c *PostgresContainerConfig) {
+		c.StartupRetry = &policy
+	}
+}
+
+// WithStartupTimeout sets the StartupTimeout field of the
+// PostgresContainerConfig.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(c *PostgresContainerConfig) {
+		c.StartupTimeout = timeout
+	}
+}
+
+// WithReadyQuery sets the ReadyQuery field of the PostgresContainerConfig.
+// Once Postgres accepts connections, the query is run repeatedly until it
+// returns a row, within the startup timeout, e.g.
+// "SELECT 1 FROM pg_extension WHERE extname = 'postgis'" to wait for init
+// scripts or extensions that must be in place before tests run.
+func WithReadyQuery(query string) Option {
+	return func(c *PostgresContainerConfig) {
+		c.ReadyQuery = query
+	}
+}
+
+// WithLogger sets the Logger field of the PostgresContainerConfig. The image
+// pull, the creation, start, health transitions, readiness and shutdown of the
+// container are logged with it, failures at error level, health transitions
+// at debug level and the rest at info level, e.g. to route them into t.Log
+// through a slog handler or into structured CI logs. Without it, nothing is
+// logged but errors that can't be returned, such as failing to remove a
+// container after a failed startup, which go to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *PostgresContainerConfig) {
+		c.Logger = logger
+	}
+}
+
+// WithTracerProvider sets the TracerProvider field of the
+// PostgresContainerConfig. StartPostgresContainer then records a span for the
+// whole startup, with a child span for each phase: pulling the image, creating
+// and starting the container, and waiting for it to be healthy and
+// connectable, so CI traces show where the time of a test suite goes. The
+// spans are children of the span in the context passed to
+// StartPostgresContainer, if any.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *PostgresContainerConfig) {
+		c.TracerProvider = provider
+	}
+}
+
+// WithWaitStrategy sets the WaitStrategy field of the
+// PostgresContainerConfig, e.g. WaitForPostgresLog() for custom images without
+// pg_isready, whose healthcheck never passes, or
+// WaitForAll(WaitForHealthcheck(), WaitForPostgresLog()) to wait for both.
+func WithWaitStrategy(strategy WaitStrategy) Option {
+	return func(c *PostgresContainerConfig) {
+		c.WaitStrategy = strategy
+	}
+}
+
+// WithHealthcheckInterval sets the HealthcheckInterval field of the
+// PostgresContainerConfig, the time between two healthchecks.
+func WithHealthcheckInterval(interval time.Duration) Option {
+	return func(c *PostgresContainerConfig) {
+		c.HealthcheckInterval = interval
+	}
+}
+
+// WithHealthcheckTimeout sets the HealthcheckTimeout field of the
+// PostgresContainerConfig, how long one healthcheck may take before it counts
+// as failed, e.g. longer on slow CI runners or for emulated images.
+func WithHealthcheckTimeout(timeout time.Duration) Option {
+	return func(c *PostgresContainerConfig) {
+		c.HealthcheckTimeout = timeout
+	}
+}
+
+// WithHealthcheckRetries sets the HealthcheckRetries field of the
+// PostgresContainerConfig, the number of healthchecks in a row that must fail
+// for the container to turn unhealthy.
+func WithHealthcheckRetries(retries int) Option {
+	return func(c *PostgresContainerConfig) {
+		c.HealthcheckRetries = retries
+	}
+}
+
+// postgresImage returns the image to run for the given version and config.
+func postgresImage(version string, config *PostgresContainerConfig) string {
+	if config.Image != "" {
+		return config.Image
+	}
+	if config.PostGIS {
+		return "postgis/postgis:" + version + "-" + PostGISVersion
+	}
+	if config.PgVector {
+		return "pgvector/pgvector:pg" + version
+	}
+	return "postgres:" + version
+}
+
+// startupTimeout returns StartupTimeout, or def if it isn't set.
+func (c *PostgresContainerConfig) startupTimeout(def time.Duration) time.Duration {
+	if c.StartupTimeout > 0 {
+		return c.StartupTimeout
+	}
+	return def
+}
+
+// Validate checks the configuration for problems that would otherwise only
+// surface later as an opaque startup or driver error. All problems found are
+// returned together.
+func (c *PostgresContainerConfig) Validate() error {
+	var errs []error
+	if c.DBName == "" {
+		errs = append(errs, errors.New("DBName must not be empty"))
+	}
+	if c.DBUser == "" {
+		errs = append(errs, errors.New("DBUser must not be empty"))
+	}
+	if c.DBPassword == "" {
+		errs = append(errs, errors.New("DBPassword must not be empty"))
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("invalid TimeZone %q: %w", c.TimeZone, err))
+	}
+	switch c.SSLMode {
+	case "disable", "allow", "prefer":
+	case "require", "verify-ca", "verify-full":
+		errs = append(errs, fmt.Errorf("SSLMode %q requires TLS, which the container does not serve", c.SSLMode))
+	default:
+		errs = append(errs, fmt.Errorf("invalid SSLMode %q", c.SSLMode))
+	}
+	if c.SharedBuffers < 0 {
+		errs = append(errs, fmt.Errorf("SharedBuffers must not be negative, got %d", c.SharedBuffers))
+	} else if c.SharedBuffers > 0 && c.SharedBuffers < minSharedBuffers {
+		errs = append(errs, fmt.Errorf(
+			"SharedBuffers is in bytes and must be at least %d (128kB), got %d; use e.g. 256*1024*1024 for 256MB",
+			minSharedBuffers,
+			c.SharedBuffers,
+		))
+	}
+	if _, err := parsePlatform(c.Platform); err != nil {
+		errs = append(errs, err)
+	}
+	if c.HealthcheckInterval < 0 || c.HealthcheckTimeout < 0 || c.HealthcheckRetries < 0 {
+		errs = append(errs, errors.New("healthcheck interval, timeout and retries must not be negative"))
+	}
+	if c.ShmSize < 0 {
+		errs = append(errs, fmt.Errorf("ShmSize must not be negative, got %d", c.ShmSize))
+	}
+	for _, kv := range c.Env {
+		key, _, _ := strings.Cut(kv, "=")
+		if reservedEnv[key] {
+			errs = append(errs, fmt.Errorf("environment variable %s is set from the config and can't be overridden", key))
+		}
+	}
+	if initDBArgs(c) != "" && slices.ContainsFunc(c.Env, func(kv string) bool {
+		return strings.HasPrefix(kv, initDBArgsEnv+"=")
+	}) {
+		errs = append(errs, fmt.Errorf("%s is set with WithEnv and with InitDBArgs or Locale", initDBArgsEnv))
+	}
+	for _, u := range c.Ulimits {
+		if err := validateUlimit(u); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, v := range c.Volumes {
+		if err := validateVolume(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Maintenance != nil && c.ReuseKey == "" && c.ContainerName == "" {
+		errs = append(errs, errors.New("Maintenance requires ReuseKey or ContainerName"))
+	}
+	if len(c.NetworkAliases) > 0 && c.Network == "" {
+		errs = append(errs, errors.New("NetworkAliases require a Network"))
+	}
+	if c.HostPort < 0 || c.HostPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid HostPort %d", c.HostPort))
+	}
+	for name := range c.Settings {
+		if !settingName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid setting name %q", name))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid postgres container config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 // PostgresContainer is a Docker container running Postgres. It can be used to
 // cheaply start a throwaway Postgres instance for testing.
 type PostgresContainer struct {
 	id       string
+	user     string
 	password string
-	port     string
-	connStr  string
+	dbName   string
+	// mu guards port, connStr and networkConnStr, which change when
+	// WatchHealth restarts the container.
+	mu      sync.RWMutex
+	port    string
+	connStr string
+	// networkConnStr reaches the container from other containers on its
+	// network, see NetworkConnectionString.
+	networkConnStr string
+	// viaIP is set when the container is reached on its IP address on network
+	// rather than on a published port, see WithAddressing.
+	viaIP   bool
+	network string
+
+	serverVersion string
+
+	docker dockerConfig
+	// pod is set when Postgres runs in a Kubernetes pod instead of a Docker
+	// container, see StartPostgresPod.
+	pod *kubePod
+	// external is set for a server connected to with ConnectPostgres.
+	external bool
+	// reused is set for a container started with WithReuse, attached to with
+	// AttachPostgresContainer or loaded with LoadPostgresContainer, which
+	// Shutdown leaves running unless WithRemoveReused is passed.
+	reused  bool
+	profile Profile
+	// logger is the Logger of the config, see WithLogger.
+	logger *slog.Logger
+	// passwordFunc is the PasswordFunc ConnectPostgres was called with.
+	passwordFunc func(ctx context.Context) (string, error)
 }
 
 // StartPostgresContainer starts a new Postgres Docker container. The version
 // parameter is the tagged version of Postgres image to use, e.g. to use
-// postgres:12 pass "12". Creation involes a few steps:
+// postgres:12 pass "12"; WithImage runs a different Postgres-compatible image
+// instead. Creation involves a few steps, which are also available
+// individually through PostgresContainerBuilder:
 //
 // 1. Pull the image if it isn't already cached locally
 // 2. Start the container
@@ -132,169 +1032,171 @@ func StartPostgresContainer(
 	version string,
 	options ...Option,
 ) (*PostgresContainer, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	b, err := NewPostgresContainerBuilder(version, options...)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer cli.Close()
+	defer b.Close()
 
-	image := "postgres:" + version
-	_, _, err = cli.ImageInspectWithRaw(ctx, image)
-	if err != nil {
-		_, notFound := err.(interface {
-			NotFound()
-		})
-		if !notFound {
-			return nil, err
-		}
-		pullReader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
-		if err != nil {
-			return nil, err
-		}
-		_, err = io.Copy(io.Discard, pullReader)
-		pullReader.Close()
-		if err != nil {
-			return nil, err
-		}
-	}
+	ctx, end := startSpan(ctx, b.config.TracerProvider, "sqltestutil.StartPostgresContainer",
+		attribute.String("postgres.version", version),
+		attribute.String("container.image.name", b.image),
+	)
+	pg, err := b.start(ctx, options)
+	end(err)
+	return pg, err
+}
 
-	password, err := randomPassword()
-	if err != nil {
+// start pulls the image if needed, then starts or reuses the container.
+func (b *PostgresContainerBuilder) start(ctx context.Context, options []Option) (*PostgresContainer, error) {
+	policy, transient := b.config.startupRetry()
+	if err := policy.doIf(ctx, transient, func() error {
+		return b.EnsureImage(ctx)
+	}); err != nil {
 		return nil, err
 	}
-
-	config := &PostgresContainerConfig{
-		DBName:     "pgtest",
-		DBUser:     "pgtest",
-		DBPassword: password,
-		TimeZone:   "UTC",
-		SSLMode:    "disable",
-	}
-
-	for _, option := range options {
-		option(config)
+	if b.config.ReuseKey != "" || b.config.ContainerName != "" {
+		return b.startOrReuse(ctx, options)
 	}
+	return b.buildWithRetry(ctx)
+}
 
-	port, err := randomPort()
-	if err != nil {
-		return nil, err
+// startupRetry returns the retry policy of the startup and which errors it
+// retries: those of isTransientStartupError with StartupRetry, or an unhealthy
+// container once with RetryOnUnhealthy. The policy is nil without either.
+func (c *PostgresContainerConfig) startupRetry() (*RetryPolicy, func(error) bool) {
+	if c.StartupRetry != nil {
+		return c.StartupRetry, isTransientStartupError
 	}
-
-	createResp, errCnr := cli.ContainerCreate(ctx, &container.Config{
-		Image: image,
-		Env: []string{
-			"POSTGRES_DB=" + config.DBName,
-			"POSTGRES_PASSWORD=" + config.DBPassword,
-			"POSTGRES_USER=" + config.DBUser,
-			"TZ=" + config.TimeZone,
-		},
-		Healthcheck: &container.HealthConfig{
-			Test:     []string{"CMD-SHELL", "pg_isready -U " + config.DBUser},
-			Interval: time.Second,
-			Timeout:  time.Second,
-			Retries:  10,
-		},
-	}, &container.HostConfig{
-		PortBindings: nat.PortMap{
-			"5432/tcp": []nat.PortBinding{
-				{HostPort: port},
-			},
-		},
-	}, nil, nil, "")
-	if errCnr != nil {
-		return nil, errCnr
-	}
-
-	defer func() {
-		// remove the container if there's an error
-		if errCnr != nil {
-			removeErr := cli.ContainerRemove(ctx, createResp.ID, types.ContainerRemoveOptions{})
-			if removeErr != nil {
-				fmt.Println("error removing container:", removeErr)
-				return
-			}
+	if c.RetryOnUnhealthy {
+		return &RetryPolicy{MaxAttempts: 2}, func(err error) bool {
+			return errors.Is(err, errUnhealthy)
 		}
-	}()
-
-	errCnr = cli.ContainerStart(ctx, createResp.ID, types.ContainerStartOptions{})
-	if errCnr != nil {
-		return nil, errCnr
-	}
-	defer func() {
-		// stop the container if there's an error
-		if errCnr != nil {
-			stopErr := cli.ContainerStop(ctx, createResp.ID, nil)
-			if stopErr != nil {
-				fmt.Println("error stopping container:", stopErr)
-				return
-			}
-		}
-	}()
-
-	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
-	defer cancel()
-
-	// wait until the container is healthy
-	errCnr = waitUntilHealthy(ctx, cli, createResp.ID)
-	if errCnr != nil {
-		return nil, errCnr
 	}
+	return nil, nil
+}
 
-	connStr := fmt.Sprintf(
-		"postgres://%s:%s@127.0.0.1:%s/%s?sslmode=%s",
-		config.DBUser,
-		password,
-		port,
-		config.DBName,
-		config.SSLMode,
-	)
+// buildWithRetry builds the container, retrying as the startupRetry of the
+// config decides.
+func (b *PostgresContainerBuilder) buildWithRetry(ctx context.Context) (*PostgresContainer, error) {
+	policy, transient := b.config.startupRetry()
 
-	// wait until the container is connectable
-	errCnr = waitUntilConnectable(ctx, connStr)
-	if errCnr != nil {
-		return nil, errCnr
+	var pg *PostgresContainer
+	var attemptErrs []error
+	err := policy.doIf(ctx, transient, func() error {
+		var err error
+		pg, err = b.build(ctx)
+		if err != nil {
+			attemptErrs = append(attemptErrs, fmt.Errorf("attempt %d: %w", len(attemptErrs)+1, err))
+		}
+		return err
+	})
+	if err == nil {
+		return pg, nil
 	}
-
-	return &PostgresContainer{
-		id:       createResp.ID,
-		password: password,
-		port:     port,
-		connStr:  connStr,
-	}, nil
+	if len(attemptErrs) == 1 {
+		return nil, err
+	}
+	return nil, errors.Join(attemptErrs...)
 }
 
 // ConnectionString returns a connection URL string that can be used to connect
 // to the running Postgres container.
 func (c *PostgresContainer) ConnectionString() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.connStr
 }
 
-// ID returns the Docker container ID of the running Postgres container.
+// NetworkConnectionString returns a connection URL string that other containers
+// on the network set with WithNetwork can use to connect to the Postgres
+// container, on its first network alias or else its IP address, and port 5432.
+// ConnectionString is the one to use from the host. It's empty for a container
+// not started with WithNetwork.
+func (c *PostgresContainer) NetworkConnectionString() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.networkConnStr
+}
+
+// ID returns the Docker container ID of the running Postgres container, or the
+// pod name for StartPostgresPod.
 func (c *PostgresContainer) ID() string {
 	return c.id
 }
 
+// User returns the name of the database user, as set with WithDBUser.
+func (c *PostgresContainer) User() string {
+	return c.user
+}
+
+// Password returns the password of the database user. Unless set with
+// WithDBPassword, it's randomly generated.
+func (c *PostgresContainer) Password() string {
+	return c.password
+}
+
+// Database returns the name of the database created on startup, as set with
+// WithDBName.
+func (c *PostgresContainer) Database() string {
+	return c.dbName
+}
+
 // Shutdown cleans up the Postgres container by stopping and removing it. This
 // should be called each time a PostgresContainer is created to avoid orphaned
-// containers.
-func (c *PostgresContainer) Shutdown(ctx context.Context) error {
-	cli, err := client.NewClientWithOpts(client.FromEnv)
-	if err != nil {
-		return err
+// containers. The options control how the container is stopped and removed,
+// e.g. WithRemoveVolumes also removes its data volume. A container started
+// with WithReuse or attached to with AttachPostgresContainer is left running
+// unless WithRemoveReused is passed.
+func (c *PostgresContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	if c.external {
+		return nil
 	}
-	defer cli.Close()
-	err = cli.ContainerStop(ctx, c.id, nil)
-	if err != nil {
-		return err
+	if c.reused && !shutdownOptions(options).removeReused {
+		return nil
 	}
-	err = cli.ContainerRemove(ctx, c.id, types.ContainerRemoveOptions{})
-	if err != nil {
+	if c.pod != nil {
+		start := time.Now()
+		err := c.pod.delete(ctx, shutdownOptions(options).force)
+		emitTo(c.logger, Event{Type: EventContainerShutdown, ContainerID: c.id, Duration: time.Since(start), Err: err})
 		return err
 	}
-	return nil
+	return shutdownContainer(ctx, c.docker, c.id, append([]ShutdownOption{withShutdownLogger(c.logger)}, options...)...)
+}
+
+// runShutdownTimeout bounds the shutdown done by Run once its context is done.
+const runShutdownTimeout = time.Minute
+
+// Run blocks until ctx is done and then shuts the container down with the
+// given options, returning the error of Shutdown. It makes the container an
+// actor of errgroup or oklog/run based harnesses that manage several
+// services:
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	g.Go(func() error { return pg.Run(ctx) })
+//	g.Go(func() error { return api.Run(ctx) })
+//	err := g.Wait()
+//
+// The shutdown isn't canceled along with ctx, but is bounded by a timeout.
+func (c *PostgresContainer) Run(ctx context.Context, options ...ShutdownOption) error {
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), runShutdownTimeout)
+	defer cancel()
+	return c.Shutdown(shutdownCtx, options...)
 }
 
-func waitUntilHealthy(ctx context.Context, cli *client.Client, containerID string) error {
+// errUnhealthy is returned by waitUntilHealthy when Docker reports the
+// container as unhealthy.
+var errUnhealthy = errors.New("container unhealthy")
+
+// waitUntilHealthy waits until Docker reports the container as healthy. If
+// onStatus isn't nil, it's called whenever the health status changes.
+func waitUntilHealthy(ctx context.Context, cli *client.Client, containerID string, onStatus func(status string)) error {
+	if isPodman(ctx, cli) {
+		return waitUntilHealthyExec(ctx, cli, containerID)
+	}
+	var lastStatus string
 	for {
 		// Check if the context has been cancelled before each health check
 		select {
@@ -307,10 +1209,18 @@ func waitUntilHealthy(ctx context.Context, cli *client.Client, containerID strin
 		if err != nil {
 			return fmt.Errorf("error inspecting container: %w", err)
 		}
+		if inspect.State.Health == nil {
+			// no healthcheck to wait for
+			return nil
+		}
 		status := inspect.State.Health.Status
+		if status != lastStatus && onStatus != nil {
+			onStatus(status)
+		}
+		lastStatus = status
 		switch status {
 		case "unhealthy":
-			return errors.New("container unhealthy")
+			return errUnhealthy
 		case "healthy":
 			return nil
 		default:
@@ -325,10 +1235,63 @@ func waitUntilConnectable(ctx context.Context, connStr string) error {
 		return err
 	}
 	defer db.Close()
+	return waitUntilPingable(ctx, db)
+}
+
+// waitUntilReadyQuery waits until query returns a row on the database at
+// connStr.
+func waitUntilReadyQuery(ctx context.Context, connStr, query string) error {
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return waitUntilQueryReturnsRows(ctx, db, query)
+}
+
+// waitUntilQueryReturnsRows waits until query returns a row on db, see
+// WithReadyQuery.
+func waitUntilQueryReturnsRows(ctx context.Context, db *sql.DB, query string) error {
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("ready query error: %w; last error: %v", ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("ready query returned no rows: %w", ctx.Err())
+		default:
+		}
 
+		ok, err := queryReturnsRows(ctx, db, query)
+		if ok {
+			return nil
+		}
+		lastErr = err
+		time.Sleep(waitInterval)
+	}
+}
+
+// queryReturnsRows reports whether query returns a row on db.
+func queryReturnsRows(ctx context.Context, db *sql.DB, query string) (bool, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	ok := rows.Next()
+	return ok, rows.Err()
+}
+
+// waitUntilPingable waits until db can be pinged.
+func waitUntilPingable(ctx context.Context, db *sql.DB) error {
+	var lastErr error
 	for {
 		select {
 		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w; last error: %v", ctx.Err(), lastErr)
+			}
 			return ctx.Err()
 		default:
 		}
@@ -337,10 +1300,163 @@ func waitUntilConnectable(ctx context.Context, connStr string) error {
 		if err == nil {
 			return nil
 		}
+		lastErr = err
 		time.Sleep(waitInterval)
 	}
 }
 
+// settingName matches the names of server settings, including the
+// two-part names of extension settings such as pg_stat_statements.max.
+var settingName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// fastSettings are the server settings of WithFastSettings.
+var fastSettings = map[string]string{
+	"fsync":              "off",
+	"synchronous_commit": "off",
+	"full_page_writes":   "off",
+	"jit":                "off",
+}
+
+// postgresCmd returns the container command for the given config, or nil to
+// use the image default.
+func postgresCmd(config *PostgresContainerConfig) []string {
+	if len(config.Cmd) > 0 {
+		return config.Cmd
+	}
+	settings := map[string]string{}
+	if config.FastSettings {
+		for name, value := range fastSettings {
+			settings[name] = value
+		}
+	}
+	if config.SharedBuffers > 0 {
+		settings["shared_buffers"] = fmt.Sprintf("%dkB", config.SharedBuffers/1024)
+	}
+	for name, value := range config.Settings {
+		settings[name] = value
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmd := []string{"postgres"}
+	for _, name := range names {
+		cmd = append(cmd, "-c", name+"="+settings[name])
+	}
+	return cmd
+}
+
+// reservedEnv are the environment variables postgresEnv sets from the config.
+var reservedEnv = map[string]bool{
+	"POSTGRES_DB":       true,
+	"POSTGRES_PASSWORD": true,
+	"POSTGRES_USER":     true,
+	"TZ":                true,
+	"PGDATA":            true,
+}
+
+// healthcheck returns the healthcheck of the container for the given config.
+func healthcheck(config *PostgresContainerConfig) *container.HealthConfig {
+	h := &container.HealthConfig{
+		Test:     []string{"CMD-SHELL", DefaultQueries.healthcheckCommandFor(config.DBUser)},
+		Interval: time.Second,
+		Timeout:  time.Second,
+		Retries:  10,
+	}
+	if config.HealthcheckInterval > 0 {
+		h.Interval = config.HealthcheckInterval
+	}
+	if config.HealthcheckTimeout > 0 {
+		h.Timeout = config.HealthcheckTimeout
+	}
+	if config.HealthcheckRetries > 0 {
+		h.Retries = config.HealthcheckRetries
+	}
+	return h
+}
+
+// postgresEnv returns the environment variables of the container for the
+// given config.
+func postgresEnv(config *PostgresContainerConfig) []string {
+	env := []string{
+		"POSTGRES_DB=" + config.DBName,
+		"POSTGRES_PASSWORD=" + config.DBPassword,
+		"POSTGRES_USER=" + config.DBUser,
+		"TZ=" + config.TimeZone,
+	}
+	if config.TmpfsData {
+		env = append(env, "PGDATA="+tmpfsDataDir)
+	}
+	if args := initDBArgs(config); args != "" {
+		env = append(env, initDBArgsEnv+"="+args)
+	}
+	return append(env, config.Env...)
+}
+
+// initDBArgsEnv is the environment variable the image passes to initdb.
+const initDBArgsEnv = "POSTGRES_INITDB_ARGS"
+
+// initDBArgs returns the value of initDBArgsEnv for the given config, or an
+// empty string to leave it unset.
+func initDBArgs(config *PostgresContainerConfig) string {
+	var args []string
+	if config.Locale != "" {
+		args = append(args, "--locale="+config.Locale)
+	}
+	return strings.Join(append(args, config.InitDBArgs...), " ")
+}
+
+// tmpfsDataDir is where the data directory is put with WithTmpfsData. PGDATA
+// is set to it, since images differ in their default.
+const tmpfsDataDir = "/var/lib/postgresql/tmpfs"
+
+// tmpfsMounts returns the tmpfs mounts of the container for the given config.
+func tmpfsMounts(config *PostgresContainerConfig) map[string]string {
+	if !config.TmpfsData {
+		return nil
+	}
+	options := "rw"
+	if config.TmpfsSize > 0 {
+		options += fmt.Sprintf(",size=%d", config.TmpfsSize)
+	}
+	return map[string]string{tmpfsDataDir: options}
+}
+
+// shmSize returns the size of the container's /dev/shm: ShmSize if set, and
+// otherwise room for SharedBuffers on top of Docker's default, but at least
+// minShmSize.
+func shmSize(config *PostgresContainerConfig) int64 {
+	if config.ShmSize > 0 {
+		return config.ShmSize
+	}
+	return max(config.SharedBuffers+defaultShmSize, minShmSize)
+}
+
+// containerLogs returns the last tail lines of the container's stdout and
+// stderr.
+func containerLogs(ctx context.Context, cli *client.Client, containerID string, tail int) (string, error) {
+	reader, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var logs bytes.Buffer
+	if _, err := stdcopy.StdCopy(&logs, &logs, reader); err != nil {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
 var passwordLetters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
 func randomPassword() (string, error) {
@@ -356,12 +1472,18 @@ func randomPassword() (string, error) {
 	return string(b), nil
 }
 
-func randomPort() (string, error) {
-	l, err := net.Listen("tcp", "127.0.0.1:0")
+// hostPort returns the configured host port after checking that it's free,
+// or "" if none is configured, which makes Docker assign a free one when the
+// container starts.
+func hostPort(config *PostgresContainerConfig) (string, error) {
+	if config.HostPort == 0 {
+		return "", nil
+	}
+	port := strconv.Itoa(config.HostPort)
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("host port %s is not available: %w", port, err)
 	}
-	defer l.Close()
-	_, port, err := net.SplitHostPort(l.Addr().String())
-	return port, err
+	l.Close()
+	return port, nil
 }
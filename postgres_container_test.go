@@ -3,13 +3,21 @@ package sqltestutil
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/docker/docker/client"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/goleak"
 )
@@ -163,12 +171,705 @@ func ExampleRunMigrations() {
 }
 
 func BenchmarkStartPostgresContainer(b *testing.B) {
-	ctx := context.Background()
 	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 		container, err := StartPostgresContainer(ctx, "15")
 		if err != nil {
+			cancel()
 			b.Fatalf("could not start container: %v", err)
 		}
-		_ = container.Shutdown(ctx)
+		_ = container.Shutdown(ctx, WithForce())
+		cancel()
+	}
+}
+
+func BenchmarkContainerPoolGet(b *testing.B) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	pool, err := NewContainerPool(ctx, 4, "15")
+	if err != nil {
+		b.Fatalf("could not create pool: %v", err)
+	}
+	defer pool.Close(context.Background())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		container, err := pool.Get(ctx)
+		if err != nil {
+			b.Fatalf("could not get container: %v", err)
+		}
+		b.StopTimer()
+		_ = container.Shutdown(ctx, WithForce())
+		b.StartTimer()
+	}
+}
+
+func TestSharedBuffers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		size        int64
+		shm         int64
+		fast        bool
+		settings    map[string]string
+		cmd         []string
+		wantCmd     []string
+		wantShmSize int64
+	}{
+		{
+			name:        "default",
+			wantShmSize: 256 * 1024 * 1024,
+		},
+		{
+			name:        "shm size",
+			size:        256 * 1024 * 1024,
+			shm:         1024 * 1024 * 1024,
+			wantCmd:     []string{"postgres", "-c", "shared_buffers=262144kB"},
+			wantShmSize: 1024 * 1024 * 1024,
+		},
+		{
+			name:        "256MB",
+			size:        256 * 1024 * 1024,
+			wantCmd:     []string{"postgres", "-c", "shared_buffers=262144kB"},
+			wantShmSize: 320 * 1024 * 1024,
+		},
+		{
+			name: "fast",
+			fast: true,
+			wantCmd: []string{
+				"postgres",
+				"-c", "fsync=off",
+				"-c", "full_page_writes=off",
+				"-c", "jit=off",
+				"-c", "synchronous_commit=off",
+			},
+			wantShmSize: 256 * 1024 * 1024,
+		},
+		{
+			name: "fast with 1MB",
+			size: 1024 * 1024,
+			fast: true,
+			wantCmd: []string{
+				"postgres",
+				"-c", "fsync=off",
+				"-c", "full_page_writes=off",
+				"-c", "jit=off",
+				"-c", "shared_buffers=1024kB",
+				"-c", "synchronous_commit=off",
+			},
+			wantShmSize: 256 * 1024 * 1024,
+		},
+		{
+			name:        "custom command",
+			fast:        true,
+			settings:    map[string]string{"max_connections": "500"},
+			cmd:         []string{"postgres", "-c", "log_statement=all"},
+			wantCmd:     []string{"postgres", "-c", "log_statement=all"},
+			wantShmSize: 256 * 1024 * 1024,
+		},
+		{
+			name:     "settings override fast",
+			fast:     true,
+			settings: map[string]string{"fsync": "on", "max_connections": "500"},
+			wantCmd: []string{
+				"postgres",
+				"-c", "fsync=on",
+				"-c", "full_page_writes=off",
+				"-c", "jit=off",
+				"-c", "max_connections=500",
+				"-c", "synchronous_commit=off",
+			},
+			wantShmSize: 256 * 1024 * 1024,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{}
+			WithSharedBuffers(tt.size)(config)
+			WithShmSize(tt.shm)(config)
+			if tt.fast {
+				WithFastSettings()(config)
+			}
+			WithPostgresConfig(tt.settings)(config)
+			WithCmd(tt.cmd...)(config)
+
+			if got := postgresCmd(config); !reflect.DeepEqual(got, tt.wantCmd) {
+				t.Errorf("postgresCmd() = %v, want %v", got, tt.wantCmd)
+			}
+			if got := shmSize(config); got != tt.wantShmSize {
+				t.Errorf("shmSize() = %v, want %v", got, tt.wantShmSize)
+			}
+		})
+	}
+}
+
+func TestTmpfsMounts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    map[string]string
+	}{
+		{name: "default"},
+		{
+			name:    "unbounded",
+			options: []Option{WithTmpfsData(0)},
+			want:    map[string]string{tmpfsDataDir: "rw"},
+		},
+		{
+			name:    "1GB",
+			options: []Option{WithTmpfsData(1 << 30)},
+			want:    map[string]string{tmpfsDataDir: "rw,size=1073741824"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{}
+			for _, option := range tt.options {
+				option(config)
+			}
+			if got := tmpfsMounts(config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tmpfsMounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresImage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{
+			name: "default",
+			want: "postgres:15",
+		},
+		{
+			name:    "custom",
+			options: []Option{WithImage("timescale/timescaledb:2.14-pg15")},
+			want:    "timescale/timescaledb:2.14-pg15",
+		},
+		{
+			name:    "postgis",
+			options: []Option{WithPostGIS()},
+			want:    "postgis/postgis:15-" + PostGISVersion,
+		},
+		{
+			name:    "postgis with custom image",
+			options: []Option{WithPostGIS(), WithImage("mirror.local/postgis:15")},
+			want:    "mirror.local/postgis:15",
+		},
+		{
+			name:    "pgvector",
+			options: []Option{WithPgVector()},
+			want:    "pgvector/pgvector:pg15",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{}
+			for _, option := range tt.options {
+				option(config)
+			}
+			if got := postgresImage("15", config); got != tt.want {
+				t.Errorf("postgresImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	t.Parallel()
+
+	config := &PostgresContainerConfig{}
+	WithDockerClientOpts(client.WithVersion("1.30"))(config)
+
+	cli, err := newDockerClient(config.DockerClientOpts)
+	if err != nil {
+		t.Fatalf("newDockerClient() error = %v", err)
+	}
+	defer cli.Close()
+	if got := cli.ClientVersion(); got != "1.30" {
+		t.Errorf("ClientVersion() = %s, want 1.30", got)
+	}
+}
+
+func TestInjectedClient(t *testing.T) {
+	t.Parallel()
+
+	injected, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:2375"))
+	if err != nil {
+		t.Fatalf("NewClientWithOpts() error = %v", err)
+	}
+	defer injected.Close()
+
+	config := &PostgresContainerConfig{}
+	WithDockerClientOpts(client.WithVersion("1.30"))(config)
+	WithDockerClient(injected)(config)
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if cli != injected {
+		t.Errorf("open() returned a new client, want the injected one")
+	}
+	if err := release(); err != nil {
+		t.Errorf("release() error = %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	pg := &PostgresContainer{external: true}
+	go func() {
+		done <- pg.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run() returned %v before the context was done", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestPostgresContainerConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *PostgresContainerConfig {
+		return &PostgresContainerConfig{
+			DBName:     "pgtest",
+			DBUser:     "pgtest",
+			DBPassword: "secret",
+			TimeZone:   "UTC",
+			SSLMode:    "disable",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "multiple problems",
+			options: []Option{
+				WithDBUser(""),
+				WithTimeZone("Nowhere/Special"),
+				WithSSLMode("require"),
+			},
+			want: []string{
+				"DBUser must not be empty",
+				`invalid TimeZone "Nowhere/Special"`,
+				`SSLMode "require" requires TLS`,
+			},
+		},
+		{
+			name: "shared buffers below the minimum",
+			options: []Option{
+				WithSharedBuffers(512),
+			},
+			want: []string{
+				"SharedBuffers is in bytes and must be at least 131072 (128kB), got 512",
+			},
+		},
+		{
+			name: "volumes",
+			options: []Option{
+				WithVolume("", "/seed", true),
+				WithVolume("pgdata", "data", false),
+			},
+			want: []string{
+				"volume for /seed has no HostPath",
+				`volume ContainerPath "data" must be absolute`,
+			},
+		},
+		{
+			name:    "maintenance without reuse",
+			options: []Option{WithMaintenance(MaintenancePolicy{Interval: time.Hour})},
+			want:    []string{"Maintenance requires ReuseKey or ContainerName"},
+		},
+		{
+			name: "initdb args twice",
+			options: []Option{
+				WithLocale("de_DE.UTF-8"),
+				WithEnv("POSTGRES_INITDB_ARGS", "--data-checksums"),
+			},
+			want: []string{"POSTGRES_INITDB_ARGS is set with WithEnv and with InitDBArgs or Locale"},
+		},
+		{
+			name: "ulimits",
+			options: []Option{
+				WithUlimit("", 1, 1),
+				WithUlimit("nofile", 2048, 1024),
+			},
+			want: []string{
+				"ulimit has no Name",
+				"ulimit nofile must have 0 <= Soft <= Hard, got 2048 and 1024",
+			},
+		},
+		{
+			name:    "platform",
+			options: []Option{WithPlatform("amd64")},
+			want:    []string{`invalid platform "amd64"`},
+		},
+		{
+			name:    "network aliases without network",
+			options: []Option{WithNetworkAliases("postgres")},
+			want:    []string{"NetworkAliases require a Network"},
+		},
+		{
+			name:    "unknown sslmode",
+			options: []Option{WithSSLMode("sometimes")},
+			want:    []string{`invalid SSLMode "sometimes"`},
+		},
+		{
+			name: "setting names",
+			options: []Option{WithPostgresConfig(map[string]string{
+				"max_connections":        "500",
+				"pg_stat_statements.max": "1000",
+				"work_mem=1GB -c fsync":  "off",
+			})},
+			want: []string{`invalid setting name "work_mem=1GB -c fsync"`},
+		},
+		{
+			name: "reserved env",
+			options: []Option{
+				WithEnv("POSTGRES_INITDB_ARGS", "--data-checksums"),
+				WithEnv("POSTGRES_USER", "root"),
+			},
+			want: []string{"environment variable POSTGRES_USER is set from the config"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := valid()
+			for _, option := range tt.options {
+				option(config)
+			}
+			err := config.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresEnv(t *testing.T) {
+	t.Parallel()
+
+	config := &PostgresContainerConfig{DBName: "app", DBUser: "app", DBPassword: "secret", TimeZone: "UTC"}
+	WithTmpfsData(0)(config)
+	WithEnv("POSTGRES_INITDB_ARGS", "--data-checksums")(config)
+	WithEnv("PGOPTIONS", "-c search_path=app")(config)
+
+	want := []string{
+		"POSTGRES_DB=app",
+		"POSTGRES_PASSWORD=secret",
+		"POSTGRES_USER=app",
+		"TZ=UTC",
+		"PGDATA=" + tmpfsDataDir,
+		"POSTGRES_INITDB_ARGS=--data-checksums",
+		"PGOPTIONS=-c search_path=app",
+	}
+	if got := postgresEnv(config); !reflect.DeepEqual(got, want) {
+		t.Errorf("postgresEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		options      []Option
+		wantInterval time.Duration
+		wantTimeout  time.Duration
+		wantRetries  int
+	}{
+		{
+			name:         "default",
+			wantInterval: time.Second,
+			wantTimeout:  time.Second,
+			wantRetries:  10,
+		},
+		{
+			name: "configured",
+			options: []Option{
+				WithHealthcheckInterval(500 * time.Millisecond),
+				WithHealthcheckTimeout(5 * time.Second),
+				WithHealthcheckRetries(30),
+			},
+			wantInterval: 500 * time.Millisecond,
+			wantTimeout:  5 * time.Second,
+			wantRetries:  30,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{DBUser: "app"}
+			for _, option := range tt.options {
+				option(config)
+			}
+			got := healthcheck(config)
+			if want := []string{"CMD-SHELL", "pg_isready -U 'app'"}; !reflect.DeepEqual(got.Test, want) {
+				t.Errorf("healthcheck().Test = %v, want %v", got.Test, want)
+			}
+			if got.Interval != tt.wantInterval || got.Timeout != tt.wantTimeout || got.Retries != tt.wantRetries {
+				t.Errorf("healthcheck() = %v, %v, %d, want %v, %v, %d",
+					got.Interval, got.Timeout, got.Retries, tt.wantInterval, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestWaitUntilQueryReturnsRows(t *testing.T) {
+	t.Parallel()
+
+	const query = "SELECT 1 FROM pg_extension WHERE extname = 'postgis'"
+	tests := []struct {
+		name    string
+		expect  func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		{
+			name: "eventually",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnError(fmt.Errorf("relation does not exist"))
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+			},
+		},
+		{
+			name: "no rows",
+			expect: func(mock sqlmock.Sqlmock) {
+				for i := 0; i < 10; i++ {
+					mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+				}
+			},
+			wantErr: "ready query returned no rows",
+		},
+		{
+			name: "error",
+			expect: func(mock sqlmock.Sqlmock) {
+				for i := 0; i < 10; i++ {
+					mock.ExpectQuery(query).WillReturnError(fmt.Errorf("relation does not exist"))
+				}
+			},
+			wantErr: "last error: relation does not exist",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+			tt.expect(mock)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+			err = waitUntilQueryReturnsRows(ctx, db, query)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("waitUntilQueryReturnsRows() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("waitUntilQueryReturnsRows() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppendLogs(t *testing.T) {
+	t.Parallel()
+
+	err := appendLogs(errUnhealthy, "FATAL:  invalid value for parameter \"max_connections\"\n", nil)
+	if !errors.Is(err, errUnhealthy) {
+		t.Errorf("appendLogs() = %v, want it to wrap %v", err, errUnhealthy)
+	}
+	want := "container unhealthy\ncontainer logs:\nFATAL:  invalid value for parameter \"max_connections\"\n"
+	if err.Error() != want {
+		t.Errorf("appendLogs() = %q, want %q", err, want)
+	}
+
+	err = appendLogs(context.DeadlineExceeded, "", errors.New("no such container"))
+	if !strings.Contains(err.Error(), "(could not read logs: no such container)") {
+		t.Errorf("appendLogs() = %q, want the logs error", err)
+	}
+}
+
+func TestWaitUntilPingable(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 10; i++ {
+		mock.ExpectPing().WillReturnError(errors.New("the database system is starting up"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	err = waitUntilPingable(ctx, db)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "last error: the database system is starting up") {
+		t.Errorf("waitUntilPingable() error = %v, want the deadline and the last ping error", err)
+	}
+}
+
+func TestInitDBArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{name: "default"},
+		{
+			name:    "locale",
+			options: []Option{WithLocale("de_DE.UTF-8")},
+			want:    "--locale=de_DE.UTF-8",
+		},
+		{
+			name: "locale and args",
+			options: []Option{
+				WithInitDBArgs("--data-checksums"),
+				WithLocale("de_DE.UTF-8"),
+				WithInitDBArgs("--encoding=UTF8"),
+			},
+			want: "--locale=de_DE.UTF-8 --data-checksums --encoding=UTF8",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{}
+			for _, option := range tt.options {
+				option(config)
+			}
+			if got := initDBArgs(config); got != tt.want {
+				t.Errorf("initDBArgs() = %q, want %q", got, tt.want)
+			}
+			env := postgresEnv(config)
+			if got := slices.Contains(env, "POSTGRES_INITDB_ARGS="+tt.want); got != (tt.want != "") {
+				t.Errorf("postgresEnv() = %v, want POSTGRES_INITDB_ARGS=%s", env, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	t.Parallel()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { taken.Close() })
+	_, takenPort, _ := net.SplitHostPort(taken.Addr().String())
+
+	free, err := randomPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		port    string
+		wantErr bool
+	}{
+		{name: "assigned by docker"},
+		{name: "free", port: free},
+		{name: "taken", port: takenPort, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &PostgresContainerConfig{}
+			if tt.port != "" {
+				port, _ := strconv.Atoi(tt.port)
+				WithHostPort(port)(config)
+			}
+			got, err := hostPort(config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hostPort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.port {
+				t.Errorf("hostPort() = %q, want %q", got, tt.port)
+			}
+		})
+	}
+}
+
+// randomPort returns a host port that was free a moment ago.
+func randomPort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
 	}
+	defer l.Close()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	return port, err
 }
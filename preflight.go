@@ -0,0 +1,83 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker/api/types/versions"
+)
+
+// minFreeDiskSpace is the free space Preflight requires in Docker's data
+// directory, enough to pull a database image and initialize a data directory.
+const minFreeDiskSpace = 1 << 30
+
+// minDockerAPIVersion is the oldest Docker API version the client negotiates
+// down to.
+const minDockerAPIVersion = "1.25"
+
+// Preflight checks that containers can be started before anything heavy
+// happens: that the Docker daemon is reachable, that its API version isn't too
+// old, and that its data directory has at least 1GB free (when the daemon runs
+// on this machine). All problems found are returned together as one error. Of
+// the options only WithDockerClientOpts and WithDockerClient apply.
+//
+// It can be used in TestMain to skip a suite cleanly where Docker isn't
+// available:
+//
+//	func TestMain(m *testing.M) {
+//	    if err := sqltestutil.Preflight(context.Background()); err != nil {
+//	        fmt.Println("skipping database tests:", err)
+//	        os.Exit(0)
+//	    }
+//	    os.Exit(m.Run())
+//	}
+func Preflight(ctx context.Context, options ...Option) error {
+	var errs []error
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot create Docker client: %w", err))
+		return preflightError(errs)
+	}
+	defer release()
+
+	ping, err := cli.Ping(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot reach the Docker daemon at %s, is it running? %w; %s", cli.DaemonHost(), err, daemonHint(os.Getenv)))
+		return preflightError(errs)
+	}
+	if ping.APIVersion != "" && versions.LessThan(ping.APIVersion, minDockerAPIVersion) {
+		errs = append(errs, fmt.Errorf(
+			"the Docker daemon supports API version %s, older than the minimum %s; upgrade Docker",
+			ping.APIVersion,
+			minDockerAPIVersion,
+		))
+	}
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot query Docker daemon info: %w", err))
+	} else if free, ok := freeDiskSpace(info.DockerRootDir); ok && free < minFreeDiskSpace {
+		errs = append(errs, fmt.Errorf(
+			"only %dMB free in Docker's data directory %s, at least %dMB are needed",
+			free>>20,
+			info.DockerRootDir,
+			minFreeDiskSpace>>20,
+		))
+	}
+
+	return preflightError(errs)
+}
+
+func preflightError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight failed: %w", errors.Join(errs...))
+}
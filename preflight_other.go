@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sqltestutil
+
+// freeDiskSpace isn't implemented on this platform, so the disk space check is
+// skipped.
+func freeDiskSpace(path string) (free uint64, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,34 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPreflightError(t *testing.T) {
+	t.Parallel()
+
+	if err := preflightError(nil); err != nil {
+		t.Errorf("preflightError(nil) = %v, want nil", err)
+	}
+	err := preflightError([]error{errors.New("a"), errors.New("b")})
+	if err == nil || !strings.HasPrefix(err.Error(), "preflight failed: a\nb") {
+		t.Errorf("preflightError() = %v, want both problems", err)
+	}
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := freeDiskSpace("/nonexistent/docker/root"); ok {
+		t.Error("freeDiskSpace() ok for a missing path")
+	}
+}
+
+func TestPreflightDocker(t *testing.T) {
+	if err := Preflight(context.Background()); err != nil {
+		t.Skip(err)
+	}
+}
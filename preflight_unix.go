@@ -0,0 +1,16 @@
+//go:build unix
+
+package sqltestutil
+
+import "syscall"
+
+// freeDiskSpace returns the space available to unprivileged users in the file
+// system holding path. ok is false if path doesn't exist on this machine, e.g.
+// because Docker runs in a VM.
+func freeDiskSpace(path string) (free uint64, ok bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
@@ -0,0 +1,89 @@
+package sqltestutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PullProgress is a progress update of an image pull, as reported by the
+// Docker daemon.
+type PullProgress struct {
+	// ID is the layer the update is about, if any.
+	ID string
+	// Status is e.g. "Downloading", "Pull complete" or the final digest line.
+	Status string
+	// Current and Total are the bytes processed so far and in total, when
+	// known.
+	Current int64
+	Total   int64
+}
+
+// PullPostgresImage pulls the Postgres image of the given version, as
+// StartPostgresContainer would with the same options, and calls progressFn
+// with every progress update from the daemon. It's meant to be called from
+// TestMain so that slow pulls on cold CI caches are visible instead of
+// counting against the startup of the first test. progressFn may be nil.
+//
+// Like StartPostgresContainer, it honors WithPullPolicy, so with the default
+// policy an image that's already cached isn't pulled again.
+func PullPostgresImage(
+	ctx context.Context,
+	version string,
+	progressFn func(PullProgress),
+	options ...Option,
+) error {
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	pull := config.imagePull()
+	pull.progress = progressFn
+	return ensureImage(ctx, cli, postgresImage(version, config), pull)
+}
+
+// pullMessage is a message of the JSON stream the daemon sends while pulling.
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+// readPullProgress consumes the JSON stream of an image pull, calling
+// progressFn, if not nil, for every message. It returns the error the daemon
+// reports in the stream, if any, which the pull request itself doesn't.
+func readPullProgress(r io.Reader, progressFn func(PullProgress)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg pullMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read pull progress error: %w", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull image error: %s", msg.Error)
+		}
+		if progressFn != nil {
+			progressFn(PullProgress{
+				ID:      msg.ID,
+				Status:  msg.Status,
+				Current: msg.ProgressDetail.Current,
+				Total:   msg.ProgressDetail.Total,
+			})
+		}
+	}
+}
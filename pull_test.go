@@ -0,0 +1,64 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPullProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		stream  string
+		want    []PullProgress
+		wantErr string
+	}{
+		{
+			name: "progress",
+			stream: `{"status":"Pulling from library/postgres","id":"16"}
+{"status":"Downloading","progressDetail":{"current":512,"total":1024},"id":"a1b2"}
+{"status":"Pull complete","progressDetail":{},"id":"a1b2"}
+`,
+			want: []PullProgress{
+				{ID: "16", Status: "Pulling from library/postgres"},
+				{ID: "a1b2", Status: "Downloading", Current: 512, Total: 1024},
+				{ID: "a1b2", Status: "Pull complete"},
+			},
+		},
+		{
+			name: "error in stream",
+			stream: `{"status":"Pulling from library/postgres","id":"99"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`,
+			want:    []PullProgress{{ID: "99", Status: "Pulling from library/postgres"}},
+			wantErr: "pull image error: manifest unknown",
+		},
+		{
+			name:    "malformed",
+			stream:  `{"status":`,
+			wantErr: "read pull progress error",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got []PullProgress
+			err := readPullProgress(strings.NewReader(tt.stream), func(p PullProgress) {
+				got = append(got, p)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readPullProgress() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("readPullProgress() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPullProgress() progress = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
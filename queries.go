@@ -0,0 +1,173 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Queries holds the SQL, and the healthcheck command, that this package runs
+// on its own as opposed to the SQL of migrations and scenarios. The defaults
+// are written for Postgres; users of Postgres variants such as CockroachDB,
+// Redshift or Aurora can adjust the statements their database doesn't support
+// through DefaultQueries instead of forking the package.
+type Queries struct {
+	// Healthcheck is the shell command that checks whether Postgres accepts
+	// connections in the container. A %s in it is replaced by the database
+	// user, quoted for the shell.
+	Healthcheck string
+	// ServerVersion returns the version of the server as a single string,
+	// e.g. "16.2 (Debian 16.2-1.pgdg120+2)".
+	ServerVersion string
+	// InspectColumns returns table name, column name, data type, whether the
+	// column is nullable and whether it has a default, for the columns of the
+	// current schema. See InspectSchema.
+	InspectColumns string
+	// InspectForeignKeys returns constraint name, table, referenced table, and
+	// the comma separated columns and referenced columns of the foreign keys of
+	// the current schema.
+	InspectForeignKeys string
+	// TextColumns returns table and column name of the text, varchar, json
+	// and jsonb columns of the current schema. See AssertNoPlaintextSecrets.
+	TextColumns string
+	// AvailableExtensions returns the names of the extensions that can be
+	// created.
+	AvailableExtensions string
+	// ListDatabases returns the names of the databases that maintenance runs
+	// on, and ListOwnedDatabases those the user owns, for ProfileManaged.
+	ListDatabases      string
+	ListOwnedDatabases string
+	// IsSuperuser returns "on" if the user is a superuser.
+	IsSuperuser string
+	// VacuumFull and ResetStats are run in every database, and
+	// ResetSharedStats once, by the maintenance methods of PostgresContainer.
+	VacuumFull       string
+	ResetStats       string
+	ResetSharedStats string
+	// DatabaseComment returns the comment of the database named by $1, with
+	// which PrepareDatabase tags loaded databases.
+	DatabaseComment string
+	// MatchingDatabases returns the names of the databases matching the LIKE
+	// pattern $1, except the templates. See DropDatabasesMatching.
+	MatchingDatabases string
+	// TerminateBackends terminates the other connections to the database
+	// named by $1, so that it can be dropped. See TestDB.
+	TerminateBackends string
+	// ListTables returns the quoted, schema-qualified names of the tables of
+	// the database, except the system tables. See TruncateAll.
+	ListTables string
+	// CreateMaintenanceTable, LastMaintenance, ClearMaintenance and
+	// RecordMaintenance keep the time of the last run of
+	// PostgresContainer.Maintain in the maintenance database.
+	CreateMaintenanceTable string
+	LastMaintenance        string
+	ClearMaintenance       string
+	RecordMaintenance      string
+	// BackendPID returns the process ID of the server process of the
+	// connection.
+	BackendPID string
+	// ExclusiveLocks returns relation name and lock mode of the exclusive
+	// relation locks held by the backend with process ID $1. See
+	// WithLockBudget.
+	ExclusiveLocks string
+	// OwnLocks returns relation name and lock mode of the relation locks held
+	// by the current connection. See CheckMigrations.
+	OwnLocks string
+	// CreateMigrationTable, AppliedMigrations and RecordMigration maintain the
+	// table of WithMigrationTracking.
+	CreateMigrationTable string
+	AppliedMigrations    string
+	RecordMigration      string
+}
+
+// DefaultQueries are the queries used throughout the package. Override fields
+// before starting containers or calling other functions, e.g. in TestMain:
+//
+//	sqltestutil.DefaultQueries.ServerVersion = "SELECT split_part(version(), ' ', 3)"
+var DefaultQueries = Queries{
+	Healthcheck:   "pg_isready -U %s",
+	ServerVersion: "SHOW server_version",
+	InspectColumns: `
+SELECT table_name, column_name, data_type, is_nullable = 'YES',
+	column_default IS NOT NULL OR is_identity = 'YES' OR is_generated <> 'NEVER'
+FROM information_schema.columns
+WHERE table_schema = current_schema()
+ORDER BY table_name, ordinal_position`,
+	InspectForeignKeys: `
+SELECT c.conname, t.relname, r.relname,
+	(SELECT string_agg(a.attname, ',' ORDER BY k.i)
+		FROM unnest(c.conkey) WITH ORDINALITY k(n, i)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.n),
+	(SELECT string_agg(a.attname, ',' ORDER BY k.i)
+		FROM unnest(c.confkey) WITH ORDINALITY k(n, i)
+		JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.n)
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_class r ON r.oid = c.confrelid
+WHERE c.contype = 'f' AND t.relnamespace = current_schema()::regnamespace
+ORDER BY t.relname, c.conname`,
+	TextColumns: `
+SELECT c.table_name, c.column_name
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+	AND c.data_type IN ('text', 'character varying', 'character', 'json', 'jsonb')
+ORDER BY c.table_name, c.ordinal_position`,
+	AvailableExtensions: "SELECT name FROM pg_available_extensions ORDER BY name",
+	ListDatabases:       "SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY datname",
+	ListOwnedDatabases:  "SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate AND pg_get_userbyid(datdba) = current_user ORDER BY datname",
+	IsSuperuser:         "SHOW is_superuser",
+	VacuumFull:          "VACUUM FULL ANALYZE",
+	ResetStats:          "SELECT pg_stat_reset()",
+	ResetSharedStats:    "SELECT pg_stat_reset_shared('bgwriter')",
+	DatabaseComment:     "SELECT shobj_description(oid, 'pg_database') FROM pg_database WHERE datname = $1",
+	MatchingDatabases:   "SELECT datname FROM pg_database WHERE datname LIKE $1 AND NOT datistemplate ORDER BY datname",
+	TerminateBackends:   "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()",
+	ListTables: `
+SELECT quote_ident(schemaname) || '.' || quote_ident(tablename)
+FROM pg_tables
+WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY 1`,
+	CreateMaintenanceTable: "CREATE TABLE IF NOT EXISTS " + maintenanceTable + " (last_run timestamptz NOT NULL)",
+	LastMaintenance:        "SELECT max(last_run) FROM " + maintenanceTable,
+	ClearMaintenance:       "DELETE FROM " + maintenanceTable,
+	RecordMaintenance:      "INSERT INTO " + maintenanceTable + " (last_run) VALUES (now())",
+	BackendPID:             "SELECT pg_backend_pid()",
+	ExclusiveLocks: `
+SELECT c.relname, l.mode
+FROM pg_locks l
+JOIN pg_class c ON c.oid = l.relation
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE l.pid = $1 AND l.granted AND l.locktype = 'relation'
+	AND l.mode IN ('ShareRowExclusiveLock', 'ExclusiveLock', 'AccessExclusiveLock')
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')`,
+	OwnLocks: `
+SELECT c.relname, l.mode
+FROM pg_locks l
+JOIN pg_class c ON c.oid = l.relation
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE l.pid = pg_backend_pid() AND l.granted AND l.locktype = 'relation'
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY c.relname, l.mode`,
+	CreateMigrationTable: `
+CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+	filename text PRIMARY KEY,
+	version text NOT NULL,
+	checksum text NOT NULL,
+	applied_at timestamptz NOT NULL,
+	duration_ms bigint NOT NULL
+)`,
+	AppliedMigrations: `
+SELECT version, filename, checksum, applied_at, duration_ms
+FROM ` + migrationTable + `
+ORDER BY applied_at, filename`,
+	RecordMigration: "INSERT INTO " + migrationTable + " (filename, version, checksum, applied_at, duration_ms) VALUES ($1, $2, $3, $4, $5)",
+}
+
+// healthcheckCommandFor returns the healthcheck shell command for the given
+// database user.
+func (q Queries) healthcheckCommandFor(user string) string {
+	if strings.Contains(q.Healthcheck, "%s") {
+		return fmt.Sprintf(q.Healthcheck, "'"+strings.ReplaceAll(user, "'", `'\''`)+"'")
+	}
+	return q.Healthcheck
+}
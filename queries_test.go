@@ -0,0 +1,40 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHealthcheckCommandFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		healthcheck string
+		want        string
+	}{
+		{name: "default", healthcheck: DefaultQueries.Healthcheck, want: "pg_isready -U 'app'"},
+		{name: "no user", healthcheck: "cockroach node status --insecure", want: "cockroach node status --insecure"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			q := Queries{Healthcheck: tt.healthcheck}
+			if got := q.healthcheckCommandFor("app"); got != tt.want {
+				t.Errorf("healthcheckCommandFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultQueriesComplete(t *testing.T) {
+	t.Parallel()
+
+	v := reflect.ValueOf(DefaultQueries)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == "" {
+			t.Errorf("DefaultQueries.%s is empty", v.Type().Field(i).Name)
+		}
+	}
+}
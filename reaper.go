@@ -0,0 +1,145 @@
+package sqltestutil
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// ReaperImage is the image of the reaper started by WithReaper. It speaks the
+// protocol of Testcontainers' Ryuk, so any Ryuk version or a mirror of it
+// works.
+var ReaperImage = "testcontainers/ryuk:0.7.0"
+
+// reaperTimeout bounds the wait for the reaper to accept its connection.
+const reaperTimeout = 30 * time.Second
+
+// sessionID returns the ID of this test process, which the containers it
+// starts are labeled with.
+var sessionID = sync.OnceValue(func() string {
+	id, err := randomPassword()
+	if err != nil {
+		return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+	return strings.ToLower(id[:16])
+})
+
+// reaper holds the connection to the reaper of this process. It's never
+// closed: the reaper removes the containers of the session once the process
+// exits, however it exits, and the connection drops.
+var reaper struct {
+	sync.Mutex
+	conn net.Conn
+}
+
+// ensureReaper starts the reaper container for this process if it isn't
+// running yet, and registers the session label with it.
+func ensureReaper(ctx context.Context, cli *client.Client, host string) error {
+	reaper.Lock()
+	defer reaper.Unlock()
+	if reaper.conn != nil {
+		return nil
+	}
+
+	if err := ensureImage(ctx, cli, ReaperImage, imagePull{}); err != nil {
+		return fmt.Errorf("pull reaper image error: %w", err)
+	}
+	// Docker assigns the host port, which can't race with other processes
+	// like a port picked up front
+	createResp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: ReaperImage,
+		ExposedPorts: nat.PortSet{
+			"8080/tcp": struct{}{},
+		},
+	}, &container.HostConfig{
+		AutoRemove: true,
+		Binds:      []string{reaperSocket(cli.DaemonHost()) + ":/var/run/docker.sock"},
+		PortBindings: nat.PortMap{
+			"8080/tcp": []nat.PortBinding{
+				{},
+			},
+		},
+	}, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("create reaper error: %w", err)
+	}
+	if err := cli.ContainerStart(ctx, createResp.ID, types.ContainerStartOptions{}); err != nil {
+		_ = cli.ContainerRemove(ctx, createResp.ID, types.ContainerRemoveOptions{Force: true})
+		return fmt.Errorf("start reaper error: %w", err)
+	}
+	port, err := publishedHostPort(ctx, cli, createResp.ID, "8080/tcp")
+	if err != nil {
+		_ = cli.ContainerRemove(ctx, createResp.ID, types.ContainerRemoveOptions{Force: true})
+		return fmt.Errorf("reaper port error: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, reaperTimeout)
+	defer cancel()
+	conn, err := connectReaper(waitCtx, net.JoinHostPort(host, port), sessionLabel+"="+sessionID())
+	if err != nil {
+		_ = cli.ContainerRemove(ctx, createResp.ID, types.ContainerRemoveOptions{Force: true})
+		return fmt.Errorf("connect reaper error: %w", err)
+	}
+	reaper.conn = conn
+	return nil
+}
+
+// connectReaper connects to the reaper at addr, retrying until it's up, and
+// registers the filter for the containers to remove.
+func connectReaper(ctx context.Context, addr, label string) (net.Conn, error) {
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			err = registerReaperFilter(conn, label)
+			if err == nil {
+				return conn, nil
+			}
+			conn.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// registerReaperFilter sends a label filter to the reaper and waits for its
+// acknowledgement.
+func registerReaperFilter(conn net.Conn, label string) error {
+	if err := conn.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "label=%s\n", label); err != nil {
+		return err
+	}
+	ack, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(ack) != "ACK" {
+		return fmt.Errorf("unexpected reaper response %q", ack)
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+// reaperSocket returns the path of the daemon socket to mount into the
+// reaper: the socket the client uses if it's a Unix socket, and the default
+// socket of the daemon's host otherwise.
+func reaperSocket(daemonHost string) string {
+	if path, ok := strings.CutPrefix(daemonHost, "unix://"); ok {
+		return path
+	}
+	return defaultDockerSocket
+}
@@ -0,0 +1,66 @@
+package sqltestutil
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReaperSocket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		daemonHost string
+		want       string
+	}{
+		{daemonHost: "unix:///var/run/docker.sock", want: "/var/run/docker.sock"},
+		{daemonHost: "unix:///run/user/1000/podman/podman.sock", want: "/run/user/1000/podman/podman.sock"},
+		{daemonHost: "tcp://10.0.0.5:2375", want: defaultDockerSocket},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.daemonHost, func(t *testing.T) {
+			t.Parallel()
+			if got := reaperSocket(tt.daemonHost); got != tt.want {
+				t.Errorf("reaperSocket(%q) = %q, want %q", tt.daemonHost, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectReaper(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+		_, _ = conn.Write([]byte("ACK\n"))
+		// hold the connection like the reaper does
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := connectReaper(ctx, l.Addr().String(), "sqltestutil.session=abc")
+	if err != nil {
+		t.Fatalf("connectReaper() error = %v", err)
+	}
+	defer conn.Close()
+	if got := <-received; got != "label=sqltestutil.session=abc\n" {
+		t.Errorf("reaper received %q", got)
+	}
+}
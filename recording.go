@@ -0,0 +1,364 @@
+package sqltestutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// RecordedStatement is a statement captured by WrapDB.
+type RecordedStatement struct {
+	Query string        `json:"query"`
+	Args  []interface{} `json:"args,omitempty"`
+	// RowsAffected is the result of an Exec.
+	RowsAffected int64 `json:"rows_affected,omitempty"`
+	// Columns and Rows are the result of a Query.
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+	// Err is the message of the error the statement failed with, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// Recorder collects the statements executed through a RecordingDB. It's safe
+// for concurrent use.
+type Recorder struct {
+	mu         sync.Mutex
+	statements []RecordedStatement
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Statements returns the statements recorded so far, in execution order.
+func (r *Recorder) Statements() []RecordedStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedStatement(nil), r.statements...)
+}
+
+// Save writes the recorded statements to path as JSON, to be replayed with
+// LoadRecording and NewReplayDB.
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Statements(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal recording error: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) record(statement RecordedStatement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, statement)
+}
+
+// LoadRecording reads statements saved by Recorder.Save. Values go through
+// JSON, so numbers come back as int64 or float64 and timestamps as strings.
+func LoadRecording(path string) ([]RecordedStatement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var statements []RecordedStatement
+	if err := dec.Decode(&statements); err != nil {
+		return nil, fmt.Errorf("parse recording %s error: %w", path, err)
+	}
+	return statements, nil
+}
+
+// RecordingDB wraps a *sql.DB and records every statement executed through it,
+// see WrapDB.
+type RecordingDB struct {
+	db       *sql.DB
+	recorder *Recorder
+	// results serves the rows of recorded queries back to the caller.
+	results *sql.DB
+}
+
+// WrapDB returns a RecordingDB that executes statements against db and records
+// them, together with their arguments and results, in recorder. It implements
+// ExecerContext and QueryerContext, so it can be passed to RunMigrations,
+// LoadScenario and the code under test in an integration run. The recording
+// can then be saved and replayed with NewReplayDB in a fast run without a
+// database.
+//
+// This is experimental. Query results are read completely before they're
+// returned, and transactions aren't recorded.
+func WrapDB(db *sql.DB, recorder *Recorder) *RecordingDB {
+	return &RecordingDB{
+		db:       db,
+		recorder: recorder,
+		results:  sql.OpenDB(&replayConnector{}),
+	}
+}
+
+// ExecContext executes a statement against the wrapped db and records it.
+func (r *RecordingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	statement := RecordedStatement{Query: query, Args: args}
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		statement.Err = err.Error()
+	} else if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+		statement.RowsAffected = n
+	}
+	r.recorder.record(statement)
+	return result, err
+}
+
+// QueryContext executes a query against the wrapped db and records it along
+// with all of its rows.
+func (r *RecordingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	statement := RecordedStatement{Query: query, Args: args}
+	err := r.query(ctx, &statement)
+	if err != nil {
+		statement.Err = err.Error()
+	}
+	r.recorder.record(statement)
+	if err != nil {
+		return nil, err
+	}
+	return r.results.QueryContext(context.WithValue(ctx, servedStatementKey{}, &statement), query, args...)
+}
+
+func (r *RecordingDB) query(ctx context.Context, statement *RecordedStatement) error {
+	rows, err := r.db.QueryContext(ctx, statement.Query, statement.Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	statement.Columns, err = rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(statement.Columns))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		statement.Rows = append(statement.Rows, values)
+	}
+	return rows.Err()
+}
+
+// Close releases the resources of the RecordingDB. It doesn't close the
+// wrapped db.
+func (r *RecordingDB) Close() error {
+	return r.results.Close()
+}
+
+// ReplayDB is a *sql.DB that answers statements from a recording instead of a
+// database, see NewReplayDB.
+type ReplayDB struct {
+	*sql.DB
+	state *replayState
+}
+
+// NewReplayDB returns a ReplayDB that expects the recorded statements to be
+// executed again, in the same order and with the same arguments, and answers
+// them with the recorded results. A statement that doesn't match the next
+// recorded one fails. Call Verify at the end of the test to also catch
+// recorded statements that were never executed.
+func NewReplayDB(statements []RecordedStatement) *ReplayDB {
+	state := &replayState{statements: statements}
+	return &ReplayDB{
+		DB:    sql.OpenDB(&replayConnector{state: state}),
+		state: state,
+	}
+}
+
+// Verify returns an error if a statement didn't match the recording or if not
+// all recorded statements were executed.
+func (r *ReplayDB) Verify() error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if r.state.err != nil {
+		return r.state.err
+	}
+	if remaining := len(r.state.statements) - r.state.next; remaining > 0 {
+		return fmt.Errorf(
+			"%d recorded statements were not executed, next: %s",
+			remaining,
+			r.state.statements[r.state.next].Query,
+		)
+	}
+	return nil
+}
+
+type replayState struct {
+	mu         sync.Mutex
+	statements []RecordedStatement
+	next       int
+	// err is the first mismatch.
+	err error
+}
+
+// take returns the next recorded statement if it matches query and args.
+func (s *replayState) take(query string, args []driver.NamedValue) (*RecordedStatement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= len(s.statements) {
+		return nil, s.fail(fmt.Errorf("unexpected statement after the end of the recording: %s", query))
+	}
+	statement := &s.statements[s.next]
+	if statement.Query != query {
+		return nil, s.fail(fmt.Errorf("statement %d: got %s, want %s", s.next+1, query, statement.Query))
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	if !sameJSON(values, statement.Args) {
+		return nil, s.fail(fmt.Errorf("statement %d: got args %v, want %v", s.next+1, values, statement.Args))
+	}
+	s.next++
+	return statement, nil
+}
+
+func (s *replayState) fail(err error) error {
+	if s.err == nil {
+		s.err = err
+	}
+	return err
+}
+
+// sameJSON reports whether a and b have the same JSON encoding, which compares
+// live values with values loaded from a recording.
+func sameJSON(a, b []interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// servedStatementKey is the context key under which RecordingDB passes a
+// recorded statement to its results db.
+type servedStatementKey struct{}
+
+type replayConnector struct {
+	state *replayState
+}
+
+func (c *replayConnector) Connect(context.Context) (driver.Conn, error) {
+	return &replayConn{state: c.state}, nil
+}
+
+func (c *replayConnector) Driver() driver.Driver {
+	return replayDriver{}
+}
+
+type replayDriver struct{}
+
+func (replayDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("replay driver must be used through NewReplayDB")
+}
+
+type replayConn struct {
+	state *replayState
+}
+
+func (c *replayConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements aren't supported when replaying")
+}
+
+func (c *replayConn) Close() error {
+	return nil
+}
+
+func (c *replayConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions aren't supported when replaying")
+}
+
+func (c *replayConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement, err := c.statement(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if statement.Err != "" {
+		return nil, errors.New(statement.Err)
+	}
+	return driver.RowsAffected(statement.RowsAffected), nil
+}
+
+func (c *replayConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	statement, err := c.statement(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if statement.Err != "" {
+		return nil, errors.New(statement.Err)
+	}
+	return &replayRows{statement: statement}, nil
+}
+
+func (c *replayConn) statement(ctx context.Context, query string, args []driver.NamedValue) (*RecordedStatement, error) {
+	if statement, ok := ctx.Value(servedStatementKey{}).(*RecordedStatement); ok {
+		return statement, nil
+	}
+	if c.state == nil {
+		return nil, errors.New("no recorded statement to serve")
+	}
+	return c.state.take(query, args)
+}
+
+type replayRows struct {
+	statement *RecordedStatement
+	next      int
+}
+
+func (r *replayRows) Columns() []string {
+	return r.statement.Columns
+}
+
+func (r *replayRows) Close() error {
+	return nil
+}
+
+func (r *replayRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.statement.Rows) {
+		return io.EOF
+	}
+	for i, value := range r.statement.Rows[r.next] {
+		dest[i] = replayValue(value)
+	}
+	r.next++
+	return nil
+}
+
+// replayValue converts a recorded value to a value a driver may return.
+func replayValue(value interface{}) driver.Value {
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err == nil {
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return data
+}
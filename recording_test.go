@@ -0,0 +1,111 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	mock.ExpectExec("DELETE FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, username FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "user2"))
+
+	// record against the "real" database
+	recorder := NewRecorder()
+	wrapped := WrapDB(db, recorder)
+	if _, err := wrapped.ExecContext(ctx, "DELETE FROM users WHERE id = $1", 1); err != nil {
+		t.Fatalf("ExecContext() error = %v", err)
+	}
+	rows, err := wrapped.QueryContext(ctx, "SELECT id, username FROM users")
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	assertUsers(t, rows, 2, "user2")
+	rows.Close()
+	if err := wrapped.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	statements, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("LoadRecording() error = %v", err)
+	}
+
+	// replay without a database
+	replay := NewReplayDB(statements)
+	defer replay.Close()
+	result, err := replay.ExecContext(ctx, "DELETE FROM users WHERE id = $1", 1)
+	if err != nil {
+		t.Fatalf("replayed ExecContext() error = %v", err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("replayed RowsAffected() = %d, want 1", n)
+	}
+	rows, err = replay.QueryContext(ctx, "SELECT id, username FROM users")
+	if err != nil {
+		t.Fatalf("replayed QueryContext() error = %v", err)
+	}
+	assertUsers(t, rows, 2, "user2")
+	rows.Close()
+	if err := replay.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	replay := NewReplayDB([]RecordedStatement{
+		{Query: "DELETE FROM users WHERE id = $1", Args: []interface{}{1}},
+		{Query: "DELETE FROM posts"},
+	})
+	defer replay.Close()
+
+	if _, err := replay.ExecContext(ctx, "DELETE FROM users WHERE id = $1", 2); err == nil {
+		t.Error("ExecContext() with other args error = nil, want mismatch")
+	}
+	if err := replay.Verify(); err == nil {
+		t.Error("Verify() error = nil, want mismatch")
+	}
+}
+
+func assertUsers(t *testing.T, rows *sql.Rows, wantID int64, wantName string) {
+	t.Helper()
+
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	var id int64
+	var name string
+	if err := rows.Scan(&id, &name); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if id != wantID || name != wantName {
+		t.Errorf("row = (%d, %s), want (%d, %s)", id, name, wantID, wantName)
+	}
+	if rows.Next() {
+		t.Error("more rows than expected")
+	}
+}
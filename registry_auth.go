@@ -0,0 +1,204 @@
+package sqltestutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// errCredentialHelper is returned when a Docker credential helper is missing
+// or fails.
+var errCredentialHelper = errors.New("credential helper error")
+
+// dockerHubConfigKey is the key Docker Hub credentials are stored under in the
+// Docker config file.
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// imagePull holds the settings for pulling an image.
+type imagePull struct {
+	// username and password are the registry credentials. When empty, the
+	// credentials from the Docker config file are used, if any.
+	username string
+	password string
+	policy   PullPolicy
+	// platform is the platform to pull, if not empty, see WithPlatform.
+	platform string
+	// logger logs the pull, if not nil, see WithLogger.
+	logger *slog.Logger
+	// progress is called with the progress updates of the pull, if not nil.
+	progress func(PullProgress)
+}
+
+// imagePull returns the image pull settings of the config.
+func (c *PostgresContainerConfig) imagePull() imagePull {
+	return imagePull{
+		username: c.RegistryUsername,
+		password: c.RegistryPassword,
+		policy:   c.PullPolicy,
+		platform: c.Platform,
+		logger:   c.Logger,
+	}
+}
+
+// registryAuth returns the encoded credentials to pull image with, or an
+// empty string to pull anonymously. A credential helper that is configured but
+// missing or failing, which is common on CI runners sharing a developer's
+// Docker config, is logged and the image pulled anonymously, since public
+// images don't need credentials.
+func (p imagePull) registryAuth(image string) (string, error) {
+	host := registryHost(image)
+	auth := types.AuthConfig{
+		Username:      p.username,
+		Password:      p.password,
+		ServerAddress: host,
+	}
+	if p.username == "" && p.password == "" {
+		found, ok, err := dockerConfigAuth(dockerConfigDir(), host)
+		if errors.Is(err, errCredentialHelper) {
+			orDefault(p.logger).Warn("pulling anonymously without registry credentials", "image", image, "err", err)
+			return "", nil
+		}
+		if err != nil || !ok {
+			return "", err
+		}
+		auth = found
+	}
+
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// registryHost returns the registry an image reference points to, e.g.
+// "registry.example.com:5000" for "registry.example.com:5000/postgres:16" and
+// "docker.io" for "postgres:16".
+func registryHost(image string) string {
+	first, _, ok := strings.Cut(image, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
+
+// dockerConfigFile is the subset of ~/.docker/config.json that holds
+// credentials.
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// dockerConfigDir returns the directory of the Docker config file,
+// $DOCKER_CONFIG or ~/.docker.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// dockerConfigAuth looks up the credentials for a registry host in the Docker
+// config file in dir the way the docker CLI does, including credential
+// helpers. ok is false if there are none.
+func dockerConfigAuth(dir, host string) (auth types.AuthConfig, ok bool, err error) {
+	if dir == "" {
+		return auth, false, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return auth, false, nil
+	}
+	if err != nil {
+		return auth, false, fmt.Errorf("read docker config error: %w", err)
+	}
+	var config dockerConfigFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return auth, false, fmt.Errorf("parse docker config error: %w", err)
+	}
+
+	key := host
+	if host == "docker.io" {
+		key = dockerHubConfigKey
+	}
+	helper := config.CredHelpers[host]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		return credentialHelperAuth(helper, key)
+	}
+
+	for _, k := range []string{key, "https://" + host, "http://" + host} {
+		entry, found := config.Auths[k]
+		if !found {
+			continue
+		}
+		auth = types.AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+			ServerAddress: host,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return auth, false, fmt.Errorf("decode docker config auth for %s error: %w", k, err)
+			}
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		return auth, true, nil
+	}
+	return auth, false, nil
+}
+
+// credentialHelperAuth gets the credentials for serverURL from the
+// docker-credential-<helper> program.
+func credentialHelperAuth(helper, serverURL string) (types.AuthConfig, bool, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stdout.String()+stderr.String(), "credentials not found") {
+			return types.AuthConfig{}, false, nil
+		}
+		return types.AuthConfig{}, false, fmt.Errorf("%w: docker-credential-%s: %w: %s", errCredentialHelper, helper, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return types.AuthConfig{}, false, fmt.Errorf("%w: parse docker-credential-%s output: %w", errCredentialHelper, helper, err)
+	}
+	auth := types.AuthConfig{ServerAddress: serverURL}
+	if creds.Username == "<token>" {
+		auth.IdentityToken = creds.Secret
+	} else {
+		auth.Username = creds.Username
+		auth.Password = creds.Secret
+	}
+	return auth, true, nil
+}
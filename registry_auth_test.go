@@ -0,0 +1,126 @@
+package sqltestutil
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestRegistryHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "postgres:16", want: "docker.io"},
+		{image: "timescale/timescaledb:2.14-pg15", want: "docker.io"},
+		{image: "registry.example.com/db/postgres:16", want: "registry.example.com"},
+		{image: "localhost:5000/postgres:16", want: "localhost:5000"},
+		{image: "localhost/postgres:16", want: "localhost"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.image, func(t *testing.T) {
+			t.Parallel()
+			if got := registryHost(tt.image); got != tt.want {
+				t.Errorf("registryHost(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerConfigAuth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := `{
+  "auths": {
+    "registry.example.com": {"auth": "dXNlcjpzZWNyZXQ="},
+    "https://index.docker.io/v1/": {"username": "hub", "password": "token"}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		host   string
+		want   types.AuthConfig
+		wantOK bool
+	}{
+		{
+			name:   "encoded auth",
+			dir:    dir,
+			host:   "registry.example.com",
+			want:   types.AuthConfig{Username: "user", Password: "secret", ServerAddress: "registry.example.com"},
+			wantOK: true,
+		},
+		{
+			name:   "docker hub",
+			dir:    dir,
+			host:   "docker.io",
+			want:   types.AuthConfig{Username: "hub", Password: "token", ServerAddress: "docker.io"},
+			wantOK: true,
+		},
+		{
+			name: "unknown registry",
+			dir:  dir,
+			host: "other.example.com",
+		},
+		{
+			name: "no config file",
+			dir:  t.TempDir(),
+			host: "registry.example.com",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok, err := dockerConfigAuth(tt.dir, tt.host)
+			if err != nil {
+				t.Fatalf("dockerConfigAuth() error = %v", err)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("dockerConfigAuth() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistryAuthMissingCredentialHelper(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"credsStore": "sqltestutil-missing"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	_, _, err := dockerConfigAuth(dir, "docker.io")
+	if !errors.Is(err, errCredentialHelper) {
+		t.Fatalf("dockerConfigAuth() error = %v, want %v", err, errCredentialHelper)
+	}
+
+	var logs bytes.Buffer
+	pull := imagePull{logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	auth, err := pull.registryAuth("postgres:16")
+	if err != nil {
+		t.Fatalf("registryAuth() error = %v", err)
+	}
+	if auth != "" {
+		t.Errorf("registryAuth() = %q, want anonymous", auth)
+	}
+	if !strings.Contains(logs.String(), "pulling anonymously") {
+		t.Errorf("registryAuth() logged %q, want a warning", logs.String())
+	}
+}
@@ -0,0 +1,110 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// MigrationEvent describes a migration file applied by RunMigrations.
+type MigrationEvent struct {
+	Filename string
+	Duration time.Duration
+	// Err is the error the migration failed with, if any.
+	Err error
+}
+
+// ScenarioEvent describes a table of a scenario file loaded by LoadScenario.
+type ScenarioEvent struct {
+	Filename string
+	Table    string
+	// Rows is the number of rows inserted.
+	Rows     int
+	Duration time.Duration
+	// Err is the error loading the table failed with, if any.
+	Err error
+}
+
+// Reporter receives progress reports from RunMigrations and LoadScenario, see
+// WithMigrationReporter and WithScenarioReporter.
+type Reporter interface {
+	MigrationApplied(event MigrationEvent)
+	ScenarioTableLoaded(event ScenarioEvent)
+}
+
+// PrettyReporter is a Reporter that prints a line per migration file and per
+// scenario table. When writing to a terminal the lines are colored, and when
+// writing anywhere else, e.g. to a CI log, they are plain text.
+type PrettyReporter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	color bool
+}
+
+// NewPrettyReporter returns a PrettyReporter writing to w. Colors are used if
+// w is a terminal and the NO_COLOR environment variable is not set.
+func NewPrettyReporter(w io.Writer) *PrettyReporter {
+	return &PrettyReporter{
+		w:     w,
+		color: isTerminal(w) && os.Getenv("NO_COLOR") == "",
+	}
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiDim   = "\x1b[2m"
+)
+
+// MigrationApplied implements Reporter.
+func (r *PrettyReporter) MigrationApplied(event MigrationEvent) {
+	r.report(event.Err, "migration "+event.Filename, "", event.Duration)
+}
+
+// ScenarioTableLoaded implements Reporter.
+func (r *PrettyReporter) ScenarioTableLoaded(event ScenarioEvent) {
+	rows := fmt.Sprintf("%d rows", event.Rows)
+	if event.Rows == 1 {
+		rows = "1 row"
+	}
+	r.report(event.Err, "scenario "+event.Filename+" "+event.Table, rows, event.Duration)
+}
+
+func (r *PrettyReporter) report(err error, subject, detail string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status, statusColor := "ok", ansiGreen
+	if err != nil {
+		status, statusColor = "FAIL", ansiRed
+	}
+	if detail != "" {
+		subject += " (" + detail + ")"
+	}
+	elapsed := duration.Round(time.Millisecond).String()
+
+	if r.color {
+		fmt.Fprintf(r.w, "%s%-4s%s %s %s%s%s\n", statusColor, status, ansiReset, subject, ansiDim, elapsed, ansiReset)
+	} else {
+		fmt.Fprintf(r.w, "%-4s %s %s\n", status, subject, elapsed)
+	}
+	if err != nil {
+		fmt.Fprintf(r.w, "     %v\n", err)
+	}
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,69 @@
+package sqltestutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrettyReporter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	r := NewPrettyReporter(&buf)
+	r.MigrationApplied(MigrationEvent{
+		Filename: "0001-init.up.sql",
+		Duration: 12 * time.Millisecond,
+	})
+	r.ScenarioTableLoaded(ScenarioEvent{
+		Filename: "scenario.yml",
+		Table:    "users",
+		Rows:     1,
+		Duration: 3 * time.Millisecond,
+		Err:      errors.New("boom"),
+	})
+
+	want := "ok   migration 0001-init.up.sql 12ms\n" +
+		"FAIL scenario scenario.yml users (1 row) 3ms\n" +
+		"     boom\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestReporterOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := &recordingReporter{}
+	db := &mockExecerContext{}
+
+	if err := RunMigrations(ctx, db, "testdata", WithMigrationReporter(r)); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	if err := LoadScenario(ctx, db, "testdata/scenario.yml", WithScenarioReporter(r)); err != nil {
+		t.Fatalf("LoadScenario() error = %v", err)
+	}
+
+	if len(r.migrations) != 1 || r.migrations[0].Filename != "0001-init.up.sql" {
+		t.Errorf("migrations = %+v, want 0001-init.up.sql", r.migrations)
+	}
+	if len(r.tables) != 1 || r.tables[0].Table != "users" || r.tables[0].Rows != 3 {
+		t.Errorf("tables = %+v, want users with 3 rows", r.tables)
+	}
+}
+
+type recordingReporter struct {
+	migrations []MigrationEvent
+	tables     []ScenarioEvent
+}
+
+func (r *recordingReporter) MigrationApplied(event MigrationEvent) {
+	r.migrations = append(r.migrations, event)
+}
+
+func (r *recordingReporter) ScenarioTableLoaded(event ScenarioEvent) {
+	r.tables = append(r.tables, event)
+}
@@ -0,0 +1,100 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures the retries of statements that fail with a transient
+// error, see WithScenarioRetry and WithMigrationRetry, and of container
+// startups, see WithStartupRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles with
+	// every further retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy makes up to 5 attempts, waiting 100ms, 200ms, 400ms and
+// 800ms in between.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// transientSQLStates are the SQLSTATE codes of errors that may go away when
+// the statement is retried.
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P03": true, // cannot_connect_now, e.g. while the server starts up
+	"08000": true, // connection_exception
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+}
+
+// isTransientError reports whether err may go away when the statement is
+// retried: a serialization failure, a deadlock, or a connection problem such
+// as those that occur while a container warms up.
+func isTransientError(err error) bool {
+	var stateErr interface {
+		SQLState() string
+	}
+	if errors.As(err, &stateErr) {
+		return transientSQLStates[stateErr.SQLState()]
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientStartupError reports whether a container startup that failed
+// with err may succeed when retried: the container turned unhealthy or was
+// slow to become ready, the daemon was unavailable or failed internally, e.g.
+// while pulling, or a published port was taken in the meantime.
+func isTransientStartupError(err error) bool {
+	var unavailable interface{ Unavailable() }
+	var system interface{ System() }
+	return errors.Is(err, errUnhealthy) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &unavailable) ||
+		errors.As(err, &system) ||
+		strings.Contains(err.Error(), "port is already allocated") ||
+		isTransientError(err)
+}
+
+// do calls fn until it succeeds, fails with an error that isn't transient, or
+// the attempts of the policy are used up. A nil policy calls fn once.
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	return p.doIf(ctx, isTransientError, fn)
+}
+
+// doIf is do with transient deciding which errors are retried.
+func (p *RetryPolicy) doIf(ctx context.Context, transient func(error) bool, fn func() error) error {
+	err := fn()
+	if p == nil {
+		return err
+	}
+	backoff := p.InitialBackoff
+	for attempt := 1; attempt < p.MaxAttempts && err != nil && transient(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, p.MaxBackoff)
+		err = fn()
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsTransientError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: sqlStateError("40001"), want: true},
+		{name: "deadlock", err: fmt.Errorf("insert error: %w", sqlStateError("40P01")), want: true},
+		{name: "starting up", err: sqlStateError("57P03"), want: true},
+		{name: "unique violation", err: sqlStateError("23505")},
+		{name: "bad conn", err: driver.ErrBadConn, want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "other", err: errors.New("syntax error")},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTransientStartupError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unhealthy", err: fmt.Errorf("%w\ncontainer logs:\n", errUnhealthy), want: true},
+		{name: "slow", err: context.DeadlineExceeded, want: true},
+		{name: "daemon unavailable", err: errdefs.Unavailable(errors.New("pull access flaked")), want: true},
+		{name: "daemon error", err: fmt.Errorf("create: %w", errdefs.System(errors.New("internal"))), want: true},
+		{name: "port taken", err: errors.New("Bind for 0.0.0.0:5433 failed: port is already allocated"), want: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: true},
+		{name: "missing image", err: errdefs.NotFound(errors.New("no such image"))},
+		{name: "version mismatch", err: errors.New("expected postgres 16, got 15.4")},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTransientStartupError(tt.err); got != tt.want {
+				t.Errorf("isTransientStartupError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartupRetry(t *testing.T) {
+	t.Parallel()
+
+	config := &PostgresContainerConfig{}
+	if policy, _ := config.startupRetry(); policy != nil {
+		t.Errorf("startupRetry() = %+v, want nil", policy)
+	}
+
+	WithRetryOnUnhealthy()(config)
+	policy, transient := config.startupRetry()
+	if policy == nil || policy.MaxAttempts != 2 {
+		t.Errorf("startupRetry() = %+v, want 2 attempts", policy)
+	}
+	if !transient(errUnhealthy) || transient(context.DeadlineExceeded) {
+		t.Error("startupRetry() with RetryOnUnhealthy should only retry unhealthy containers")
+	}
+
+	WithStartupRetry(DefaultRetryPolicy)(config)
+	policy, transient = config.startupRetry()
+	if policy == nil || *policy != DefaultRetryPolicy {
+		t.Errorf("startupRetry() = %+v, want %+v", policy, DefaultRetryPolicy)
+	}
+	if !transient(context.DeadlineExceeded) {
+		t.Error("startupRetry() with StartupRetry should retry slow startups")
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	t.Parallel()
+
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	tests := []struct {
+		name     string
+		policy   *RetryPolicy
+		errs     []error
+		wantErr  bool
+		wantRuns int
+	}{
+		{
+			name:     "no policy",
+			errs:     []error{sqlStateError("40001")},
+			wantErr:  true,
+			wantRuns: 1,
+		},
+		{
+			name:     "succeeds after transient error",
+			policy:   policy,
+			errs:     []error{sqlStateError("40001"), nil},
+			wantRuns: 2,
+		},
+		{
+			name:     "permanent error",
+			policy:   policy,
+			errs:     []error{sqlStateError("23505")},
+			wantErr:  true,
+			wantRuns: 1,
+		},
+		{
+			name:     "attempts used up",
+			policy:   policy,
+			errs:     []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, nil},
+			wantErr:  true,
+			wantRuns: 3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			runs := 0
+			err := tt.policy.do(context.Background(), func() error {
+				runs++
+				return tt.errs[runs-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("do() ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
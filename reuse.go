@@ -0,0 +1,124 @@
+package sqltestutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// reuseLabel marks a container started with WithReuse, with the reuse key as
+// its value.
+const reuseLabel = "sqltestutil.reuse"
+
+// reuseContainerName returns the name of the container reused for key. The
+// name is derived from the key and the settings a container can't be reused
+// across, and Docker keeps names unique, so concurrent test processes end up
+// with the same container.
+func reuseContainerName(key, image string, config *PostgresContainerConfig) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{key, image, config.DBUser, config.DBName}, "\x00")))
+	return "sqltestutil-reuse-" + hex.EncodeToString(sum[:8])
+}
+
+// startOrReuse returns the container reused for the builder's reuse key or
+// container name, starting it if it's stopped, or builds a new one under that
+// name if there's none.
+func (b *PostgresContainerBuilder) startOrReuse(ctx context.Context, options []Option) (*PostgresContainer, error) {
+	b.name = b.config.ContainerName
+	if b.name == "" {
+		b.name = reuseContainerName(b.config.ReuseKey, b.image, b.config)
+	}
+
+	pg, err := b.reuse(ctx, options)
+	if err == nil || !client.IsErrNotFound(err) {
+		return pg, err
+	}
+	pg, err = b.buildWithRetry(ctx)
+	if isConflict(err) {
+		// another process created the container in the meantime
+		return b.reuse(ctx, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pg.reused = true
+	return pg, nil
+}
+
+// reuse attaches to the existing container named b.name, starting it first if
+// it isn't running, e.g. after the Docker daemon restarted, and runs the
+// maintenance of the config on it.
+func (b *PostgresContainerBuilder) reuse(ctx context.Context, options []Option) (*PostgresContainer, error) {
+	inspect, err := b.cli.ContainerInspect(ctx, b.name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReusable(inspect, b.image, b.config); err != nil {
+		return nil, fmt.Errorf("reuse container %s error: %w", b.name, err)
+	}
+	if inspect.State != nil && !inspect.State.Running {
+		if err := b.cli.ContainerStart(ctx, inspect.ID, types.ContainerStartOptions{}); err != nil {
+			return nil, fmt.Errorf("start reused container error: %w", err)
+		}
+	}
+	pg, err := AttachPostgresContainer(ctx, inspect.ID, options...)
+	if err != nil {
+		return nil, fmt.Errorf("reuse container %s error: %w", b.name, err)
+	}
+	pg.reused = true
+	if b.config.Maintenance != nil {
+		if _, err := pg.Maintain(ctx, *b.config.Maintenance); err != nil {
+			return nil, fmt.Errorf("maintain reused container %s error: %w", b.name, err)
+		}
+	}
+	return pg, nil
+}
+
+// labels returns the labels of the container the builder creates. A reused
+// container outlives the test process, so it isn't labeled with the session,
+// which would make the reaper remove it.
+func (b *PostgresContainerBuilder) labels() map[string]string {
+	labels := containerLabels()
+	if b.config.ReuseKey != "" || b.config.ContainerName != "" {
+		delete(labels, sessionLabel)
+		labels[reuseLabel] = b.config.ReuseKey
+		if b.config.ReuseKey == "" {
+			labels[reuseLabel] = b.config.ContainerName
+		}
+	}
+	return labels
+}
+
+// checkReusable returns an error if the existing container doesn't run image
+// with the user and database name of config.
+func checkReusable(inspect types.ContainerJSON, image string, config *PostgresContainerConfig) error {
+	if inspect.Config == nil {
+		return errors.New("container has no config")
+	}
+	if inspect.Config.Image != image {
+		return fmt.Errorf("container runs image %s, want %s", inspect.Config.Image, image)
+	}
+	existing := &PostgresContainerConfig{}
+	postgresEnvDefaults(existing, inspect.Config.Env)
+	if existing.DBUser != config.DBUser {
+		return fmt.Errorf("container has user %s, want %s", existing.DBUser, config.DBUser)
+	}
+	if existing.DBName != config.DBName {
+		return fmt.Errorf("container has database %s, want %s", existing.DBName, config.DBName)
+	}
+	return nil
+}
+
+// isConflict reports whether err, or an error it wraps, is a Docker conflict
+// error, e.g. because a container name is taken.
+func isConflict(err error) bool {
+	var conflict interface {
+		Conflict()
+	}
+	return errors.As(err, &conflict)
+}
@@ -0,0 +1,103 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+)
+
+func TestReuseName(t *testing.T) {
+	t.Parallel()
+
+	base := &PostgresContainerConfig{DBUser: "pgtest", DBName: "pgtest"}
+	name := reuseContainerName("dev", "postgres:16", base)
+
+	tests := []struct {
+		name   string
+		key    string
+		image  string
+		config *PostgresContainerConfig
+		same   bool
+	}{
+		{name: "same settings", key: "dev", image: "postgres:16", config: &PostgresContainerConfig{DBUser: "pgtest", DBName: "pgtest", DBPassword: "other"}, same: true},
+		{name: "other key", key: "ci", image: "postgres:16", config: base},
+		{name: "other image", key: "dev", image: "postgres:15", config: base},
+		{name: "other database", key: "dev", image: "postgres:16", config: &PostgresContainerConfig{DBUser: "pgtest", DBName: "app"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := reuseContainerName(tt.key, tt.image, tt.config)
+			if (got == name) != tt.same {
+				t.Errorf("reuseContainerName() = %s, compared to %s: same = %v, want %v", got, name, got == name, tt.same)
+			}
+		})
+	}
+}
+
+func TestReusedShutdown(t *testing.T) {
+	t.Parallel()
+
+	// no daemon is needed, since nothing must be removed
+	pg := &PostgresContainer{reused: true}
+	if err := pg.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestIsConflict(t *testing.T) {
+	t.Parallel()
+
+	conflict := errdefs.Conflict(fmt.Errorf("name in use"))
+	if !isConflict(fmt.Errorf("attempt 1: %w", conflict)) {
+		t.Error("isConflict() = false for a wrapped conflict")
+	}
+	if isConflict(fmt.Errorf("other")) {
+		t.Error("isConflict() = true for another error")
+	}
+}
+
+func TestCheckReusable(t *testing.T) {
+	t.Parallel()
+
+	config := &PostgresContainerConfig{DBUser: "pgtest", DBName: "pgtest"}
+	env := []string{"POSTGRES_USER=pgtest", "POSTGRES_DB=pgtest", "POSTGRES_PASSWORD=secret"}
+	tests := []struct {
+		name    string
+		inspect types.ContainerJSON
+		wantErr bool
+	}{
+		{
+			name:    "matching",
+			inspect: types.ContainerJSON{Config: &container.Config{Image: "postgres:16", Env: env}},
+		},
+		{
+			name:    "other image",
+			inspect: types.ContainerJSON{Config: &container.Config{Image: "postgres:15", Env: env}},
+			wantErr: true,
+		},
+		{
+			name:    "default user",
+			inspect: types.ContainerJSON{Config: &container.Config{Image: "postgres:16"}},
+			wantErr: true,
+		},
+		{
+			name:    "no config",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := checkReusable(tt.inspect, "postgres:16", config); (err != nil) != tt.wantErr {
+				t.Errorf("checkReusable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
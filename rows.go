@@ -0,0 +1,94 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// InsertRows inserts rows into table, so that existing model structs can be
+// used as fixtures directly. T must be a struct or a pointer to one, and its
+// fields are mapped to columns by their `db` struct tag:
+//
+//	type User struct {
+//	    ID       int64   `db:"id,omitempty"`
+//	    Name     string  `db:"name"`
+//	    Nickname *string `db:"nickname"`
+//	    Cache    string  // not a column
+//	}
+//
+// Fields without a tag, or tagged `db:"-"`, are ignored. The fields of an
+// untagged embedded struct are mapped as if they were fields of T, and a nil
+// embedded pointer maps to no columns. The semantics match scenario files: a
+// field is inserted as is, so a nil pointer becomes NULL, unless it's tagged
+// omitempty and has its zero value, in which case it's left out of the INSERT
+// like a column missing from a scenario row, and the column's default
+// applies.
+func InsertRows[T any](ctx context.Context, db ExecerContext, table string, rows []T) error {
+	for i, row := range rows {
+		columns, values, err := structColumns(reflect.ValueOf(row))
+		if err != nil {
+			return fmt.Errorf("row %d of %s: %w", i, table, err)
+		}
+		if err := InsertRow(ctx, db, table, columns, values); err != nil {
+			return fmt.Errorf("insert row %d into %s error: %w", i, table, err)
+		}
+	}
+	return nil
+}
+
+// structColumns returns the columns and values of a struct with `db` tags, as
+// described on InsertRows.
+func structColumns(v reflect.Value) ([]string, []interface{}, error) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil, fmt.Errorf("nil %s", v.Type())
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s is not a struct", v.Type())
+	}
+
+	var columns []string
+	var values []interface{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("db")
+		if !ok && field.Anonymous {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				embeddedColumns, embeddedValues, err := structColumns(embedded)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", field.Name, err)
+				}
+				columns = append(columns, embeddedColumns...)
+				values = append(values, embeddedValues...)
+				continue
+			}
+		}
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			return nil, nil, fmt.Errorf("field %s has an empty column name", field.Name)
+		}
+		value := v.Field(i)
+		if slices.Contains(strings.Split(opts, ","), "omitempty") && value.IsZero() {
+			continue
+		}
+		columns = append(columns, name)
+		values = append(values, value.Interface())
+	}
+	return columns, values, nil
+}
@@ -0,0 +1,118 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+type rowsTestUser struct {
+	ID       int64   `db:"id,omitempty"`
+	Name     string  `db:"name"`
+	Nickname *string `db:"nickname"`
+	Cache    string
+	Ignored  string `db:"-"`
+}
+
+type rowsTestTimestamps struct {
+	CreatedAt string `db:"created_at,omitempty,readonly"`
+}
+
+type rowsTestAuditInfo struct {
+	Author string `db:"author"`
+}
+
+type rowsTestPost struct {
+	rowsTestTimestamps
+	*rowsTestAuditInfo
+	Title string `db:"title"`
+}
+
+func TestInsertRows(t *testing.T) {
+	t.Parallel()
+
+	nick := "al"
+	tests := []struct {
+		name      string
+		insert    func(db ExecerContext) error
+		wantQuery []string
+		wantArgs  [][]interface{}
+		wantErr   bool
+	}{
+		{
+			name: "values",
+			insert: func(db ExecerContext) error {
+				return InsertRows(context.Background(), db, "users", []rowsTestUser{
+					{ID: 1, Name: "Alice", Nickname: &nick},
+				})
+			},
+			wantQuery: []string{`INSERT INTO "users" (id, name, nickname) VALUES ($1, $2, $3)`},
+			wantArgs:  [][]interface{}{{int64(1), "Alice", &nick}},
+		},
+		{
+			name: "omitempty and nil",
+			insert: func(db ExecerContext) error {
+				return InsertRows(context.Background(), db, "users", []*rowsTestUser{
+					{Name: "Bob"},
+				})
+			},
+			wantQuery: []string{`INSERT INTO "users" (name, nickname) VALUES ($1, $2)`},
+			wantArgs:  [][]interface{}{{"Bob", (*string)(nil)}},
+		},
+		{
+			name: "embedded",
+			insert: func(db ExecerContext) error {
+				return InsertRows(context.Background(), db, "posts", []rowsTestPost{
+					{rowsTestTimestamps{CreatedAt: "2024-01-08"}, &rowsTestAuditInfo{Author: "alice"}, "Hello"},
+					{Title: "Draft"},
+				})
+			},
+			wantQuery: []string{
+				`INSERT INTO "posts" (created_at, author, title) VALUES ($1, $2, $3)`,
+				`INSERT INTO "posts" (title) VALUES ($1)`,
+			},
+			wantArgs: [][]interface{}{{"2024-01-08", "alice", "Hello"}, {"Draft"}},
+		},
+		{
+			name: "not a struct",
+			insert: func(db ExecerContext) error {
+				return InsertRows(context.Background(), db, "users", []int{1})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := &recordingExecer{}
+			err := tt.insert(db)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InsertRows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(db.queries, tt.wantQuery) {
+				t.Errorf("queries = %q, want %q", db.queries, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(db.args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", db.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+type recordingExecer struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (r *recordingExecer) ExecContext(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args)
+	return nil, nil
+}
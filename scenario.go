@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -28,40 +30,398 @@ import (
 //	     title: Goodbye, world!
 //	     is_draft: true
 //
-// The above would populate the users and posts tables. Fields that are missing
-// from the YAML are left out of the INSERT statement, and so are populated with
-// the default value for that column.
-func LoadScenario(ctx context.Context, db ExecerContext, filename string) error {
+// The above would populate the users and posts tables. Tables and rows are
+// inserted in the order they appear in the file, so referenced rows should come
+// first. Fields that are missing from the YAML are left out of the INSERT
+// statement, and so are populated with the default value for that column.
+//
+// The top-level key _queries is reserved for assertions about the loaded data,
+// which are evaluated once all tables are loaded:
+//
+//	_queries:
+//	   - sql: SELECT count(*) FROM users
+//	     expect: 2
+//
+// Each query must return at least one row, and the first column of the first
+// row must equal expect, or LoadScenario returns an error. Queries require db
+// to implement QueryerContext, as *sql.DB and *sql.Tx do.
+//
+// A row with the key _expect_error is expected to fail to insert, which makes
+// scenarios usable for testing constraints themselves:
+//
+//	users:
+//	   - id: 1
+//	     email: alice@example.com
+//	   - id: 2
+//	     email: alice@example.com
+//	     _expect_error: unique_violation
+//
+// The value is a Postgres condition name such as unique_violation,
+// foreign_key_violation, not_null_violation or check_violation, a SQLSTATE
+// code, or, for drivers without SQLSTATE codes, text the error message
+// contains. LoadScenario fails if the insert succeeds or fails differently,
+// and otherwise carries on with the next row. On Postgres, a failed insert
+// aborts the surrounding transaction, so such rows can't be loaded through a
+// *sql.Tx.
+func LoadScenario(
+	ctx context.Context,
+	db ExecerContext,
+	filename string,
+	options ...ScenarioOption,
+) error {
+	config := &scenarioConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return err
 	}
-	var result map[string][]map[string]interface{}
-	err = yaml.Unmarshal(data, &result)
+	tables, queries, err := parseScenario(data)
 	if err != nil {
 		return err
 	}
-	for table, rows := range result {
-		for _, row := range rows {
-			var columns []string
-			var placeholders []string
-			var values []interface{}
-			for column := range row {
-				columns = append(columns, column)
-				placeholders = append(placeholders, fmt.Sprintf("$%d", len(placeholders)+1))
-				values = append(values, row[column])
+	if err := loadTables(ctx, db, filename, tables, config); err != nil {
+		return err
+	}
+	return checkQueries(ctx, db, filename, queries)
+}
+
+// loadTables inserts the rows of the scenario tables in order, reporting each
+// table to the configured reporter.
+func loadTables(ctx context.Context, db ExecerContext, filename string, tables []scenarioTable, config *scenarioConfig) error {
+	for _, table := range tables {
+		start := time.Now()
+		rows, err := loadTable(ctx, db, config, table)
+		duration := time.Since(start)
+		if config.reporter != nil {
+			config.reporter.ScenarioTableLoaded(ScenarioEvent{
+				Filename: filepath.Base(filename),
+				Table:    table.name,
+				Rows:     rows,
+				Duration: duration,
+				Err:      err,
+			})
+		}
+		emit(Event{Type: EventScenarioLoaded, Filename: filepath.Base(filename), Table: table.name, Count: rows, Duration: duration, Err: err})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQueries evaluates the _queries section of a scenario file against db.
+func checkQueries(ctx context.Context, db ExecerContext, filename string, queries []scenarioQuery) error {
+	if len(queries) == 0 {
+		return nil
+	}
+	queryer, ok := db.(QueryerContext)
+	if !ok {
+		return fmt.Errorf("queries in %s require db to implement QueryerContext", filepath.Base(filename))
+	}
+	for _, query := range queries {
+		if err := checkQuery(ctx, queryer, query); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(filename), err)
+		}
+	}
+	return nil
+}
+
+// loadTable inserts the rows of a scenario table and returns how many were
+// inserted.
+func loadTable(ctx context.Context, db ExecerContext, config *scenarioConfig, table scenarioTable) (int, error) {
+	inserted := 0
+	for _, row := range table.rows {
+		query, values := insertStatement(config.dialect, table.name, row)
+		err := config.retry.do(ctx, func() error {
+			_, err := db.ExecContext(ctx, query, values...)
+			return err
+		})
+		if row.expectError != "" {
+			if err == nil {
+				return inserted, fmt.Errorf("line %d: insert into %s succeeded, want %s", row.line, table.name, row.expectError)
+			}
+			if !matchesExpectedError(err, row.expectError) {
+				return inserted, fmt.Errorf("line %d: insert into %s: want %s, got: %w", row.line, table.name, row.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			return inserted, err
+		}
+		inserted++
+	}
+	return inserted, nil
+}
+
+type scenarioConfig struct {
+	reporter Reporter
+	dialect  Dialect
+	retry    *RetryPolicy
+}
+
+// ScenarioOption configures LoadScenario.
+type ScenarioOption func(*scenarioConfig)
+
+// WithScenarioReporter makes LoadScenario report each loaded table to r, e.g.
+// a PrettyReporter.
+func WithScenarioReporter(r Reporter) ScenarioOption {
+	return func(c *scenarioConfig) {
+		c.reporter = r
+	}
+}
+
+// WithScenarioRetry makes LoadScenario retry inserts that fail with a
+// transient error, such as a serialization failure, a deadlock or a dropped
+// connection while the container warms up, as policy allows, e.g.
+// DefaultRetryPolicy. Each row is retried on its own.
+func WithScenarioRetry(policy RetryPolicy) ScenarioOption {
+	return func(c *scenarioConfig) {
+		c.retry = &policy
+	}
+}
+
+// Dialect is the SQL dialect that scenario rows are inserted in.
+type Dialect int
+
+const (
+	// DialectPostgres quotes table names with double quotes and uses $1, $2,
+	// ... placeholders. It's the default.
+	DialectPostgres Dialect = iota
+	// DialectMySQL quotes table names with backticks and uses ? placeholders,
+	// for MySQL, MariaDB and TiDB.
+	DialectMySQL
+)
+
+// WithDialect makes LoadScenario write its INSERT statements in the given
+// dialect.
+func WithDialect(dialect Dialect) ScenarioOption {
+	return func(c *scenarioConfig) {
+		c.dialect = dialect
+	}
+}
+
+// scenarioTable is a table of a parsed scenario file.
+type scenarioTable struct {
+	name string
+	line int
+	rows []scenarioRow
+}
+
+// scenarioRow is a row of a parsed scenario file. columns and values are kept
+// in the order they appear in the file.
+type scenarioRow struct {
+	line    int
+	columns []string
+	values  []interface{}
+	// lines holds the line number of each column.
+	lines []int
+	// expectError is the error inserting the row should fail with, as
+	// declared with _expect_error.
+	expectError string
+}
+
+// value returns the value of the given column, and whether the row has it.
+func (r scenarioRow) value(column string) (interface{}, bool) {
+	for i, c := range r.columns {
+		if c == column {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// parseScenario parses the contents of a scenario file into its tables and
+// the assertions of its _queries section. Unlike unmarshaling into a map, this
+// keeps tables, rows and columns in document order.
+func parseScenario(data []byte) ([]scenarioTable, []scenarioQuery, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("line %d: scenario must be a mapping of table names to rows", root.Line)
+	}
+
+	var tables []scenarioTable
+	var queries []scenarioQuery
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == queriesKey {
+			q, err := parseQueries(value)
+			if err != nil {
+				return nil, nil, err
 			}
-			query := fmt.Sprintf(
-				"INSERT INTO %q (%s) VALUES (%s)",
-				table,
-				strings.Join(columns, ", "),
-				strings.Join(placeholders, ", "),
-			)
-			_, err = db.ExecContext(ctx, query, values...)
+			queries = append(queries, q...)
+			continue
+		}
+		table := scenarioTable{
+			name: key.Value,
+			line: key.Line,
+		}
+		value = resolveAlias(value)
+		if value.Tag == "!!null" {
+			tables = append(tables, table)
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			return nil, nil, fmt.Errorf("line %d: rows of table %q must be a sequence", value.Line, table.name)
+		}
+		for _, rowNode := range value.Content {
+			line := rowNode.Line
+			rowNode = resolveAlias(rowNode)
+			if rowNode.Kind != yaml.MappingNode {
+				return nil, nil, fmt.Errorf("line %d: row of table %q must be a mapping", line, table.name)
+			}
+			pairs, err := mappingPairs(rowNode)
 			if err != nil {
-				return err
+				return nil, nil, err
+			}
+			row := scenarioRow{
+				line: line,
 			}
+			for _, pair := range pairs {
+				column, valueNode := pair[0], pair[1]
+				if column.Value == expectErrorColumn {
+					row.expectError = resolveAlias(valueNode).Value
+					continue
+				}
+				v, err := decodeScenarioValue(valueNode)
+				if err != nil {
+					return nil, nil, err
+				}
+				row.columns = append(row.columns, column.Value)
+				row.values = append(row.values, v)
+				row.lines = append(row.lines, column.Line)
+			}
+			table.rows = append(table.rows, row)
 		}
+		tables = append(tables, table)
 	}
-	return nil
+	return tables, queries, nil
+}
+
+// resolveAlias returns the node an alias refers to, or node itself if it isn't
+// an alias.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// mappingPairs returns the keys and values of a mapping node in document
+// order, with merge keys (<<) expanded in their place. As in YAML, the keys of
+// the mapping itself take precedence over merged ones, and earlier merged
+// mappings over later ones.
+func mappingPairs(node *yaml.Node) ([][2]*yaml.Node, error) {
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !isMergeKey(node.Content[i]) {
+			explicit[node.Content[i].Value] = true
+		}
+	}
+
+	var pairs [][2]*yaml.Node
+	merged := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		if !isMergeKey(key) {
+			pairs = append(pairs, [2]*yaml.Node{key, value})
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			source = resolveAlias(source)
+			if source.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: merge key must refer to a mapping or a sequence of mappings", source.Line)
+			}
+			sourcePairs, err := mappingPairs(source)
+			if err != nil {
+				return nil, err
+			}
+			for _, pair := range sourcePairs {
+				if name := pair[0].Value; !explicit[name] && !merged[name] {
+					merged[name] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// isMergeKey reports whether node is the merge key <<.
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!merge"
+}
+
+// scenarioTags are the custom YAML tags of scenario values, which convert the
+// tagged node into the value to insert.
+var scenarioTags = map[string]func(node *yaml.Node) (interface{}, error){
+	"!geometry": decodeGeometry,
+	"!vector":   decodeVector,
+}
+
+// decodeScenarioValue returns the value to insert for a node of a scenario
+// row.
+func decodeScenarioValue(node *yaml.Node) (interface{}, error) {
+	node = resolveAlias(node)
+	if decode, ok := scenarioTags[node.Tag]; ok {
+		v, err := decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", node.Line, node.Tag, err)
+		}
+		return v, nil
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// InsertRow inserts a row into table, setting the given columns to values in
+// the same way LoadScenario inserts the rows of a scenario file. It's used by
+// the code that `sqltestutil gen` generates, see GenerateFactories.
+func InsertRow(ctx context.Context, db ExecerContext, table string, columns []string, values []interface{}) error {
+	if len(columns) != len(values) {
+		return fmt.Errorf("insert into %s: %d columns but %d values", table, len(columns), len(values))
+	}
+	query, args := insertStatement(DialectPostgres, table, scenarioRow{columns: columns, values: values})
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// insertStatement returns the INSERT statement and its arguments for a row of
+// the given table.
+func insertStatement(dialect Dialect, table string, row scenarioRow) (string, []interface{}) {
+	placeholders := make([]string, len(row.columns))
+	for i := range placeholders {
+		if dialect == DialectMySQL {
+			placeholders[i] = "?"
+		} else {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+	quotedTable := fmt.Sprintf("%q", table)
+	if dialect == DialectMySQL {
+		quotedTable = "`" + strings.ReplaceAll(table, "`", "``") + "`"
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quotedTable,
+		strings.Join(row.columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	return query, row.values
 }
@@ -0,0 +1,40 @@
+package sqltestutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// expectErrorColumn is the scenario row key that declares the error inserting
+// the row is expected to fail with.
+const expectErrorColumn = "_expect_error"
+
+// sqlStates maps the Postgres condition names of integrity constraint
+// violations to their SQLSTATE codes.
+var sqlStates = map[string]string{
+	"integrity_constraint_violation": "23000",
+	"restrict_violation":             "23001",
+	"not_null_violation":             "23502",
+	"foreign_key_violation":          "23503",
+	"unique_violation":               "23505",
+	"check_violation":                "23514",
+	"exclusion_violation":            "23P01",
+}
+
+// matchesExpectedError reports whether err is the error declared with
+// _expect_error: a Postgres condition name such as unique_violation, a
+// SQLSTATE code such as 23505, or otherwise text the error message contains,
+// for drivers that don't report SQLSTATE codes.
+func matchesExpectedError(err error, expected string) bool {
+	code := expected
+	if c, ok := sqlStates[expected]; ok {
+		code = c
+	}
+	var stateErr interface {
+		SQLState() string
+	}
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState() == code
+	}
+	return strings.Contains(err.Error(), expected)
+}
@@ -0,0 +1,101 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stateError string
+
+func (e stateError) Error() string    { return "sql error " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestMatchesExpectedError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+		want     bool
+	}{
+		{name: "condition name", err: stateError("23505"), expected: "unique_violation", want: true},
+		{name: "wrapped", err: fmt.Errorf("exec: %w", stateError("23503")), expected: "foreign_key_violation", want: true},
+		{name: "code", err: stateError("23P01"), expected: "23P01", want: true},
+		{name: "other condition", err: stateError("23502"), expected: "unique_violation"},
+		{name: "message", err: errors.New("Error 1062: Duplicate entry"), expected: "Duplicate entry", want: true},
+		{name: "message mismatch", err: errors.New("Error 1452: foreign key"), expected: "Duplicate entry"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := matchesExpectedError(tt.err, tt.expected); got != tt.want {
+				t.Errorf("matchesExpectedError(%v, %q) = %v, want %v", tt.err, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScenarioExpectError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		insertErr error
+		wantErr   string
+	}{
+		{
+			name:      "fails as expected",
+			insertErr: stateError("23505"),
+		},
+		{
+			name:    "succeeds",
+			wantErr: "line 4: insert into users succeeded, want unique_violation",
+		},
+		{
+			name:      "fails differently",
+			insertErr: stateError("23514"),
+			wantErr:   "line 4: insert into users: want unique_violation, got: sql error 23514",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+			expectation := mock.ExpectExec("INSERT INTO").WithArgs("user1", "password2")
+			if tt.insertErr != nil {
+				expectation.WillReturnError(tt.insertErr)
+			} else {
+				expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.wantErr == "" {
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			err = LoadScenario(context.Background(), db, "testdata/expect_error.yml")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadScenario() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadScenario() error = %v, want %q", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
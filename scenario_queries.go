@@ -0,0 +1,110 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// queriesKey is the top-level key of a scenario file that holds assertions
+// instead of the rows of a table. Like expectErrorColumn, it starts with an
+// underscore so that it doesn't collide with a table named queries.
+const queriesKey = "_queries"
+
+// scenarioQuery is an assertion of the _queries section of a scenario file.
+type scenarioQuery struct {
+	line   int
+	sql    string
+	expect interface{}
+}
+
+// parseQueries parses the value of the _queries section of a scenario file.
+func parseQueries(node *yaml.Node) ([]scenarioQuery, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: _queries must be a sequence", node.Line)
+	}
+	var queries []scenarioQuery
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: query must be a mapping with sql and expect", item.Line)
+		}
+		query := scenarioQuery{line: item.Line}
+		var hasExpect bool
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			key, value := item.Content[j], item.Content[j+1]
+			switch key.Value {
+			case "sql":
+				query.sql = value.Value
+			case "expect":
+				if err := value.Decode(&query.expect); err != nil {
+					return nil, err
+				}
+				hasExpect = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown query key %q", key.Line, key.Value)
+			}
+		}
+		if query.sql == "" || !hasExpect {
+			return nil, fmt.Errorf("line %d: query must have sql and expect", item.Line)
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// checkQuery runs query and compares the first column of the first row it
+// returns to the expected value.
+func checkQuery(ctx context.Context, db QueryerContext, query scenarioQuery) error {
+	rows, err := db.QueryContext(ctx, query.sql)
+	if err != nil {
+		return fmt.Errorf("line %d: query error: %w", query.line, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("line %d: query error: %w", query.line, err)
+		}
+		return fmt.Errorf("line %d: %s: no rows, want %v", query.line, query.sql, query.expect)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return fmt.Errorf("line %d: scan error: %w", query.line, err)
+	}
+	if !sameValue(values[0], query.expect) {
+		return fmt.Errorf("line %d: %s: got %v, want %v", query.line, query.sql, printable(values[0]), query.expect)
+	}
+	return nil
+}
+
+// sameValue reports whether a value scanned from the database equals a value
+// from a scenario file. Values are compared by their text, since drivers and
+// YAML don't agree on Go types, e.g. int64 vs. int.
+func sameValue(got, want interface{}) bool {
+	got = printable(got)
+	if got == nil || want == nil {
+		return got == nil && want == nil
+	}
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
+
+// printable converts the []byte some drivers scan text into to a string.
+func printable(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
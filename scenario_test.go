@@ -2,7 +2,11 @@ package sqltestutil
 
 import (
 	"context"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
 func TestLoadScenario(t *testing.T) {
@@ -41,3 +45,178 @@ func TestLoadScenario(t *testing.T) {
 		})
 	}
 }
+
+func TestParseScenarioAliases(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`users:
+  - &alice
+    name: alice
+    role: admin
+  - <<: *alice
+    name: bob
+  - *alice
+  - <<: [{role: guest, active: true}, *alice]
+    name: carol
+`)
+	tables, _, err := parseScenario(data)
+	if err != nil {
+		t.Fatalf("parseScenario() error = %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("parseScenario() returned %d tables, want 1", len(tables))
+	}
+	tests := []struct {
+		columns []string
+		values  []interface{}
+	}{
+		{
+			columns: []string{"name", "role"},
+			values:  []interface{}{"alice", "admin"},
+		},
+		{
+			columns: []string{"role", "name"},
+			values:  []interface{}{"admin", "bob"},
+		},
+		{
+			columns: []string{"name", "role"},
+			values:  []interface{}{"alice", "admin"},
+		},
+		{
+			columns: []string{"role", "active", "name"},
+			values:  []interface{}{"guest", true, "carol"},
+		},
+	}
+	rows := tables[0].rows
+	if len(rows) != len(tests) {
+		t.Fatalf("parseScenario() returned %d rows, want %d", len(rows), len(tests))
+	}
+	for i, tt := range tests {
+		if !reflect.DeepEqual(rows[i].columns, tt.columns) {
+			t.Errorf("row %d columns = %v, want %v", i, rows[i].columns, tt.columns)
+		}
+		if !reflect.DeepEqual(rows[i].values, tt.values) {
+			t.Errorf("row %d values = %v, want %v", i, rows[i].values, tt.values)
+		}
+	}
+}
+
+func TestParseScenarioQueriesTable(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`queries:
+  - sql: SELECT 1
+_queries:
+  - sql: SELECT count(*) FROM queries
+    expect: 1
+`)
+	tables, queries, err := parseScenario(data)
+	if err != nil {
+		t.Fatalf("parseScenario() error = %v", err)
+	}
+	if len(tables) != 1 || tables[0].name != "queries" || len(tables[0].rows) != 1 {
+		t.Errorf("parseScenario() tables = %+v, want the table queries with 1 row", tables)
+	}
+	if len(queries) != 1 || queries[0].sql != "SELECT count(*) FROM queries" {
+		t.Errorf("parseScenario() queries = %+v, want 1 query", queries)
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	t.Parallel()
+
+	row := scenarioRow{
+		columns: []string{"id", "name"},
+		values:  []interface{}{1, "Alice"},
+	}
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{
+			name:    "postgres",
+			dialect: DialectPostgres,
+			want:    `INSERT INTO "users" (id, name) VALUES ($1, $2)`,
+		},
+		{
+			name:    "mysql",
+			dialect: DialectMySQL,
+			want:    "INSERT INTO `users` (id, name) VALUES (?, ?)",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got, _ := insertStatement(tt.dialect, "users", row); got != tt.want {
+				t.Errorf("insertStatement() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScenarioQueries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		count    *sqlmock.Rows
+		username *sqlmock.Rows
+		wantErr  string
+	}{
+		{
+			name:     "pass",
+			count:    sqlmock.NewRows([]string{"count"}).AddRow(int64(2)),
+			username: sqlmock.NewRows([]string{"username"}).AddRow([]byte("user1")),
+		},
+		{
+			name:    "mismatch",
+			count:   sqlmock.NewRows([]string{"count"}).AddRow(int64(3)),
+			wantErr: "queries.yml: line 8: SELECT count(*) FROM users: got 3, want 2",
+		},
+		{
+			name:    "no rows",
+			count:   sqlmock.NewRows([]string{"count"}),
+			wantErr: "no rows, want 2",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("SELECT count").WillReturnRows(tt.count)
+			if tt.username != nil {
+				mock.ExpectQuery("SELECT username").WillReturnRows(tt.username)
+			}
+
+			err = LoadScenario(context.Background(), db, "testdata/queries.yml")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadScenario() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadScenario() error = %v, want %q", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("execer only", func(t *testing.T) {
+		t.Parallel()
+		err := LoadScenario(context.Background(), &mockExecerContext{}, "testdata/queries.yml")
+		if err == nil || !strings.Contains(err.Error(), "QueryerContext") {
+			t.Errorf("LoadScenario() error = %v, want QueryerContext error", err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// QueryerContext is an interface used by functions that read from the
+// database, such as InspectSchema.
+type QueryerContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Schema describes the tables of a database, as far as is needed to validate
+// scenario files against it.
+type Schema struct {
+	Tables map[string]*Table `json:"tables"`
+}
+
+// Table describes a database table.
+type Table struct {
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+}
+
+// Column describes a table column.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the data type as reported by information_schema, e.g. "integer"
+	// or "character varying".
+	Type       string `json:"type"`
+	Nullable   bool   `json:"nullable,omitempty"`
+	HasDefault bool   `json:"has_default,omitempty"`
+}
+
+// ForeignKey describes a foreign key constraint of a table.
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+// Column returns the column with the given name, or nil if the table has no
+// such column.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// TableNames returns the names of all tables in lexicographical order.
+func (s *Schema) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InspectSchema reads the tables, columns and foreign keys of the current
+// schema of a Postgres database.
+func InspectSchema(ctx context.Context, db QueryerContext) (*Schema, error) {
+	schema := &Schema{
+		Tables: map[string]*Table{},
+	}
+
+	rows, err := db.QueryContext(ctx, DefaultQueries.InspectColumns)
+	if err != nil {
+		return nil, fmt.Errorf("query columns error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName string
+		var column Column
+		err := rows.Scan(&tableName, &column.Name, &column.Type, &column.Nullable, &column.HasDefault)
+		if err != nil {
+			return nil, fmt.Errorf("scan column error: %w", err)
+		}
+		table := schema.Tables[tableName]
+		if table == nil {
+			table = &Table{Name: tableName}
+			schema.Tables[tableName] = table
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query columns error: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx, DefaultQueries.InspectForeignKeys)
+	if err != nil {
+		return nil, fmt.Errorf("query foreign keys error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName, columns, refColumns string
+		var fk ForeignKey
+		err := rows.Scan(&fk.Name, &tableName, &fk.RefTable, &columns, &refColumns)
+		if err != nil {
+			return nil, fmt.Errorf("scan foreign key error: %w", err)
+		}
+		fk.Columns = strings.Split(columns, ",")
+		fk.RefColumns = strings.Split(refColumns, ",")
+		if table := schema.Tables[tableName]; table != nil {
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query foreign keys error: %w", err)
+	}
+
+	return schema, nil
+}
+
+// SaveSchemaSnapshot inspects the schema of db and writes it to path as JSON.
+// Committing the snapshot lets LintScenario validate fixtures in fast unit
+// tests and CI jobs that have no Docker available; see LoadSchemaSnapshot.
+func SaveSchemaSnapshot(ctx context.Context, db QueryerContext, path string) error {
+	schema, err := InspectSchema(ctx, db)
+	if err != nil {
+		return err
+	}
+	return writeSchemaSnapshot(schema, path)
+}
+
+// LoadSchemaSnapshot reads a schema snapshot written by SaveSchemaSnapshot.
+func LoadSchemaSnapshot(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("parse schema snapshot error: %w", err)
+	}
+	if schema.Tables == nil {
+		schema.Tables = map[string]*Table{}
+	}
+	return &schema, nil
+}
+
+func writeSchemaSnapshot(schema *Schema, path string) error {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,24 @@
+package sqltestutil
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaSnapshot(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := writeSchemaSnapshot(lintSchema, path); err != nil {
+		t.Fatalf("writeSchemaSnapshot() error = %v", err)
+	}
+
+	got, err := LoadSchemaSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSchemaSnapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, lintSchema) {
+		t.Errorf("LoadSchemaSnapshot() = %+v, want %+v", got, lintSchema)
+	}
+}
@@ -0,0 +1,186 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// SecretPattern describes values that look like secrets, for
+// AssertNoPlaintextSecrets.
+type SecretPattern struct {
+	// Name describes the secret in failure messages, e.g. "Stripe API key".
+	Name string
+	// Regexp matches candidate values within a column value.
+	Regexp *regexp.Regexp
+	// Valid, if set, filters out matches that aren't actually secrets, e.g.
+	// digit runs that fail the Luhn check of card numbers.
+	Valid func(match string) bool
+}
+
+// DefaultSecretPatterns are the patterns AssertNoPlaintextSecrets uses when
+// none are given: common API keys and tokens, private keys and card numbers
+// (PANs).
+var DefaultSecretPatterns = []SecretPattern{
+	{Name: "Stripe API key", Regexp: regexp.MustCompile(`\b[sr]k_(?:test|live)_[0-9A-Za-z]{16,}`)},
+	{Name: "AWS access key ID", Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "GitHub token", Regexp: regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36,}`)},
+	{Name: "Slack token", Regexp: regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z-]{10,}`)},
+	{Name: "private key", Regexp: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{Name: "card number", Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: luhnValid},
+}
+
+// SecretFinding is a column value that matched a SecretPattern.
+type SecretFinding struct {
+	Table   string
+	Column  string
+	Pattern string
+	// Match is the matched text, masked but for its first and last characters.
+	Match string
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s.%s contains a %s: %s", f.Table, f.Column, f.Pattern, f.Match)
+}
+
+// AssertNoPlaintextSecrets fails t for every text, varchar, json and jsonb
+// column of the current schema that holds a value matching one of patterns,
+// or DefaultSecretPatterns if patterns is empty. It's meant to run at the end
+// of an integration test, to catch code that stores API keys or card numbers
+// that should have been encrypted, hashed or masked:
+//
+//	t.Cleanup(func() {
+//	    sqltestutil.AssertNoPlaintextSecrets(t, db, nil)
+//	})
+//
+// Each column is reported once per pattern, with the match masked. Every
+// table is read in full, so it's only suited to the small databases of tests.
+func AssertNoPlaintextSecrets(t testing.TB, db QueryerContext, patterns []SecretPattern) {
+	t.Helper()
+
+	findings, err := FindPlaintextSecrets(context.Background(), db, patterns)
+	if err != nil {
+		t.Fatalf("could not scan for secrets: %v", err)
+	}
+	for _, finding := range findings {
+		t.Errorf("plaintext secret: %s", finding)
+	}
+}
+
+// FindPlaintextSecrets returns the findings AssertNoPlaintextSecrets reports.
+func FindPlaintextSecrets(ctx context.Context, db QueryerContext, patterns []SecretPattern) ([]SecretFinding, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultSecretPatterns
+	}
+	columns, err := textColumns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []SecretFinding
+	for _, column := range columns {
+		found, err := scanColumn(ctx, db, column[0], column[1], patterns)
+		if err != nil {
+			return findings, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
+
+// textColumns returns table and column name of the columns to scan.
+func textColumns(ctx context.Context, db QueryerContext) ([][2]string, error) {
+	rows, err := db.QueryContext(ctx, DefaultQueries.TextColumns)
+	if err != nil {
+		return nil, fmt.Errorf("query text columns error: %w", err)
+	}
+	defer rows.Close()
+
+	var columns [][2]string
+	for rows.Next() {
+		var column [2]string
+		if err := rows.Scan(&column[0], &column[1]); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// scanColumn returns the first match of each pattern in the values of a
+// column.
+func scanColumn(ctx context.Context, db QueryerContext, table, column string, patterns []SecretPattern) ([]SecretFinding, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %q::text FROM %q WHERE %q IS NOT NULL", column, table, column))
+	if err != nil {
+		return nil, fmt.Errorf("scan %s.%s error: %w", table, column, err)
+	}
+	defer rows.Close()
+
+	var findings []SecretFinding
+	reported := map[string]bool{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		for _, pattern := range patterns {
+			if reported[pattern.Name] {
+				continue
+			}
+			if match, ok := matchSecret(value, pattern); ok {
+				reported[pattern.Name] = true
+				findings = append(findings, SecretFinding{
+					Table:   table,
+					Column:  column,
+					Pattern: pattern.Name,
+					Match:   maskSecret(match),
+				})
+			}
+		}
+	}
+	return findings, rows.Err()
+}
+
+// matchSecret returns the first match of pattern in value that passes its
+// Valid check.
+func matchSecret(value string, pattern SecretPattern) (string, bool) {
+	for _, match := range pattern.Regexp.FindAllString(value, -1) {
+		if pattern.Valid == nil || pattern.Valid(match) {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+// maskSecret keeps the first and last 4 characters of a match, so that it can
+// be recognized in a failure message without being repeated there.
+func maskSecret(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return match[:4] + strings.Repeat("*", len(match)-8) + match[len(match)-4:]
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check that card
+// numbers satisfy, ignoring spaces and dashes.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
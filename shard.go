@@ -0,0 +1,99 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Shard is the position of the current job among parallel CI jobs that split
+// a test suite between them.
+type Shard struct {
+	// Index is the zero-based index of this job.
+	Index int
+	// Total is the number of jobs.
+	Total int
+}
+
+// shardEnvVars lists the environment variables ShardFromEnv checks, in order.
+// oneBased is set for CI systems that count jobs from 1.
+var shardEnvVars = []struct {
+	index    string
+	total    string
+	oneBased bool
+}{
+	{index: "SQLTESTUTIL_SHARD_INDEX", total: "SQLTESTUTIL_SHARD_TOTAL"},
+	{index: "CIRCLE_NODE_INDEX", total: "CIRCLE_NODE_TOTAL"},
+	{index: "BUILDKITE_PARALLEL_JOB", total: "BUILDKITE_PARALLEL_JOB_COUNT"},
+	{index: "CI_NODE_INDEX", total: "CI_NODE_TOTAL", oneBased: true},
+}
+
+// ShardFromEnv detects the shard of the current job from the environment. It
+// understands SQLTESTUTIL_SHARD_INDEX and SQLTESTUTIL_SHARD_TOTAL (zero-based)
+// as well as the variables set by CircleCI, Buildkite and GitLab CI. The
+// second return value is false when no shard is configured.
+//
+// GOFLAGS isn't consulted: go test has no flag for shards, and the go command
+// rejects unknown flags in GOFLAGS, so a shard can't be passed that way. Set
+// SQLTESTUTIL_SHARD_INDEX and SQLTESTUTIL_SHARD_TOTAL instead.
+func ShardFromEnv() (Shard, bool) {
+	return shardFromEnv(os.Getenv)
+}
+
+func shardFromEnv(getenv func(string) string) (Shard, bool) {
+	for _, vars := range shardEnvVars {
+		index, err := strconv.Atoi(getenv(vars.index))
+		if err != nil {
+			continue
+		}
+		total, err := strconv.Atoi(getenv(vars.total))
+		if err != nil {
+			continue
+		}
+		if vars.oneBased {
+			index--
+		}
+		if index < 0 || index >= total {
+			continue
+		}
+		return Shard{Index: index, Total: total}, true
+	}
+	return Shard{}, false
+}
+
+// DatabaseName returns a database name that is unique to the shard, so that
+// parallel jobs can share one database server without interfering with each
+// other, e.g. "app_test_shard_2_of_4". A zero Shard returns base unchanged.
+func (s Shard) DatabaseName(base string) string {
+	if s.Total == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s_shard_%d_of_%d", base, s.Index+1, s.Total)
+}
+
+// Tables returns the tables assigned to the shard; see ShardTables.
+func (s Shard) Tables(tables []string) []string {
+	return ShardTables(tables, s.Index, s.Total)
+}
+
+// ShardTables returns the tables assigned to the shard with the given
+// zero-based index out of shardTotal shards. The tables are sorted and then
+// dealt out round-robin, so every job computes the same assignment regardless
+// of the input order and each table belongs to exactly one shard. A shardTotal
+// below 2 returns all tables.
+func ShardTables(tables []string, shardIndex, shardTotal int) []string {
+	sorted := append([]string(nil), tables...)
+	sort.Strings(sorted)
+	if shardTotal < 2 {
+		return sorted
+	}
+
+	var shard []string
+	for i, table := range sorted {
+		if i%shardTotal == shardIndex {
+			shard = append(shard, table)
+		}
+	}
+	return shard
+}
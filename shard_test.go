@@ -0,0 +1,113 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShardTables(t *testing.T) {
+	t.Parallel()
+
+	tables := []string{"users", "posts", "comments", "tags", "likes"}
+
+	tests := []struct {
+		name       string
+		shardIndex int
+		shardTotal int
+		want       []string
+	}{
+		{
+			name:       "unsharded",
+			shardIndex: 0,
+			shardTotal: 1,
+			want:       []string{"comments", "likes", "posts", "tags", "users"},
+		},
+		{
+			name:       "first of two",
+			shardIndex: 0,
+			shardTotal: 2,
+			want:       []string{"comments", "posts", "users"},
+		},
+		{
+			name:       "second of two",
+			shardIndex: 1,
+			shardTotal: 2,
+			want:       []string{"likes", "tags"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ShardTables(tables, tt.shardIndex, tt.shardTotal)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShardTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShardFromEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   Shard
+		wantOK bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "sqltestutil",
+			env: map[string]string{
+				"SQLTESTUTIL_SHARD_INDEX": "1",
+				"SQLTESTUTIL_SHARD_TOTAL": "3",
+			},
+			want:   Shard{Index: 1, Total: 3},
+			wantOK: true,
+		},
+		{
+			name: "gitlab",
+			env: map[string]string{
+				"CI_NODE_INDEX": "1",
+				"CI_NODE_TOTAL": "2",
+			},
+			want:   Shard{Index: 0, Total: 2},
+			wantOK: true,
+		},
+		{
+			name: "out of range",
+			env: map[string]string{
+				"CIRCLE_NODE_INDEX": "2",
+				"CIRCLE_NODE_TOTAL": "2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := shardFromEnv(func(key string) string { return tt.env[key] })
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("shardFromEnv() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestShardDatabaseName(t *testing.T) {
+	t.Parallel()
+
+	if got := (Shard{}).DatabaseName("app_test"); got != "app_test" {
+		t.Errorf("DatabaseName() = %s, want app_test", got)
+	}
+	if got := (Shard{Index: 1, Total: 4}).DatabaseName("app_test"); got != "app_test_shard_2_of_4" {
+		t.Errorf("DatabaseName() = %s, want app_test_shard_2_of_4", got)
+	}
+}
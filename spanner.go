@@ -0,0 +1,102 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// spannerStartupTimeout is the default StartupTimeout for the Spanner
+// emulator with PGAdapter.
+const spannerStartupTimeout = time.Minute
+
+// SpannerPGContainer is a Docker container running the Cloud Spanner emulator
+// behind PGAdapter, which gives it a Postgres wire protocol interface. It can
+// be used to test code that targets Spanner's PostgreSQL dialect with the
+// usual Postgres drivers, RunMigrations and LoadScenario.
+type SpannerPGContainer struct {
+	id      string
+	dbName  string
+	port    string
+	connStr string
+
+	docker dockerConfig
+}
+
+// StartSpannerPGContainer starts a new container from the
+// gcr.io/cloud-spanner-pg-adapter/pgadapter-emulator image, which runs the
+// Spanner emulator and PGAdapter together. The version parameter is the image
+// tag, e.g. "latest". The options for the Docker client, image pulls, the
+// reaper and the host apply; of those configuring the database only WithDBName
+// and WithStartupTimeout do, and the database defaults to "test". The emulator
+// creates the instance and the database on the first connection, and doesn't
+// authenticate.
+func StartSpannerPGContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*SpannerPGContainer, error) {
+	config := &PostgresContainerConfig{
+		DBName: "test",
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image:  "gcr.io/cloud-spanner-pg-adapter/pgadapter-emulator:" + version,
+		port:   "5432/tcp",
+		pull:   config.imagePull(),
+		reaper: config.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host := containerHost(cli, config.HostOverride)
+	connStr := fmt.Sprintf("postgres://%s/%s?sslmode=disable", net.JoinHostPort(host, port), config.DBName)
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(spannerStartupTimeout))
+	defer cancel()
+
+	if err := waitUntilConnectable(waitCtx, connStr); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &SpannerPGContainer{
+		id:      containerID,
+		dbName:  config.DBName,
+		port:    port,
+		connStr: connStr,
+		docker:  config.docker(),
+	}, nil
+}
+
+// ConnectionString returns a Postgres connection string that can be used to
+// connect to the database through PGAdapter.
+func (c *SpannerPGContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running container.
+func (c *SpannerPGContainer) ID() string {
+	return c.id
+}
+
+// Database returns the name of the Spanner database.
+func (c *SpannerPGContainer) Database() string {
+	return c.dbName
+}
+
+// Shutdown cleans up the container by stopping and removing it.
+func (c *SpannerPGContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
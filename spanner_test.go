@@ -0,0 +1,38 @@
+package sqltestutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStartSpannerPGContainer(t *testing.T) {
+	t.Parallel()
+
+	const image = "gcr.io/cloud-spanner-pg-adapter/pgadapter-emulator:latest"
+	port := fakePostgres(t)
+	var created container.Config
+	cli, _ := newFakeDocker(t, runRoutes(image, "5432/tcp", port, &created))
+
+	c, err := StartSpannerPGContainer(context.Background(), "latest",
+		WithDockerClient(cli),
+		WithDBName("shop"),
+	)
+	if err != nil {
+		t.Fatalf("StartSpannerPGContainer() error = %v", err)
+	}
+	if created.Image != image {
+		t.Errorf("image = %s, want %s", created.Image, image)
+	}
+	if len(created.Env) != 0 {
+		t.Errorf("env = %v, want none", created.Env)
+	}
+	if _, ok := created.ExposedPorts["5432/tcp"]; !ok {
+		t.Errorf("exposed ports = %v, want 5432/tcp", created.ExposedPorts)
+	}
+	want := "postgres://127.0.0.1:" + port + "/shop?sslmode=disable"
+	if got := c.ConnectionString(); got != want {
+		t.Errorf("ConnectionString() = %s, want %s", got, want)
+	}
+}
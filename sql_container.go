@@ -0,0 +1,164 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// defaultSQLContainerStartupTimeout is the default StartupTimeout of a
+// ContainerSpec.
+const defaultSQLContainerStartupTimeout = time.Minute
+
+// ContainerSpec describes a database container for StartSQLContainer.
+type ContainerSpec struct {
+	// Image is the image to run, including its tag, e.g. "clickhouse/clickhouse-server:24".
+	Image string
+	// Env holds environment variables in the form KEY=value.
+	Env []string
+	// Cmd overrides the image's command. Optional.
+	Cmd []string
+	// Port is the container port the database listens on, e.g. "8123/tcp". It's
+	// published on a random host port.
+	Port string
+	// Wait decides when the database is ready. Optional; without it the
+	// container is returned as soon as it's started.
+	Wait WaitStrategy
+	// StartupTimeout bounds Wait. Defaults to one minute.
+	StartupTimeout time.Duration
+	// ConnectionString builds the connection string from the host and port the
+	// database is published on. Optional.
+	ConnectionString func(host, port string) string
+	// DockerClientOpts are applied to the Docker clients used for the
+	// container, see WithDockerClientOpts.
+	DockerClientOpts []client.Opt
+	// Reaper starts the reaper before the container, see WithReaper.
+	Reaper bool
+	// DockerClient is used instead of creating Docker clients, see
+	// WithDockerClient.
+	DockerClient *client.Client
+	// RegistryUsername and RegistryPassword are the credentials to pull Image
+	// with, see WithRegistryAuth.
+	RegistryUsername string
+	RegistryPassword string
+	// PullPolicy decides when Image is pulled, see WithPullPolicy.
+	PullPolicy PullPolicy
+	// HostOverride is the host the published port is reached on, see
+	// WithHostOverride.
+	HostOverride string
+}
+
+// SQLContainer is a database container started by StartSQLContainer.
+type SQLContainer struct {
+	id      string
+	host    string
+	port    string
+	connStr string
+
+	// cli is set while the container is waited on, for wait strategies that
+	// need Docker.
+	cli *client.Client
+
+	docker dockerConfig
+}
+
+// StartSQLContainer starts a container for any database image described by
+// spec, for databases this package has no first-class support for. It pulls
+// the image if needed, publishes spec.Port on a random host port, and waits
+// for spec.Wait before returning. The container is removed again if it never
+// becomes ready.
+//
+//	c, err := sqltestutil.StartSQLContainer(ctx, sqltestutil.ContainerSpec{
+//	    Image: "cockroachdb/cockroach:v23.2.0",
+//	    Cmd:   []string{"start-single-node", "--insecure"},
+//	    Port:  "26257/tcp",
+//	    Wait:  sqltestutil.WaitForSQL("pgx"),
+//	    ConnectionString: func(host, port string) string {
+//	        return "postgres://root@" + host + ":" + port + "/defaultdb?sslmode=disable"
+//	    },
+//	})
+func StartSQLContainer(ctx context.Context, spec ContainerSpec) (*SQLContainer, error) {
+	if spec.Image == "" || spec.Port == "" {
+		return nil, errors.New("container spec requires an image and a port")
+	}
+	timeout := spec.StartupTimeout
+	if timeout <= 0 {
+		timeout = defaultSQLContainerStartupTimeout
+	}
+
+	docker := dockerConfig{client: spec.DockerClient, opts: spec.DockerClientOpts}
+	cli, release, err := docker.open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image: spec.Image,
+		env:   spec.Env,
+		cmd:   spec.Cmd,
+		port:  nat.Port(spec.Port),
+		pull: imagePull{
+			username: spec.RegistryUsername,
+			password: spec.RegistryPassword,
+			policy:   spec.PullPolicy,
+		},
+		reaper: spec.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SQLContainer{
+		id:   containerID,
+		host: containerHost(cli, spec.HostOverride),
+		port: port,
+		cli:  cli,
+
+		docker: docker,
+	}
+	if spec.ConnectionString != nil {
+		c.connStr = spec.ConnectionString(c.host, port)
+	}
+
+	if spec.Wait != nil {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if err := spec.Wait.WaitUntilReady(waitCtx, c); err != nil {
+			_ = shutdownContainer(ctx, docker, containerID, WithForce())
+			return nil, err
+		}
+	}
+	c.cli = nil
+	return c, nil
+}
+
+// ConnectionString returns the connection string built by
+// ContainerSpec.ConnectionString, or an empty string if it wasn't set.
+func (c *SQLContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running container.
+func (c *SQLContainer) ID() string {
+	return c.id
+}
+
+// Host returns the host the database port is published on.
+func (c *SQLContainer) Host() string {
+	return c.host
+}
+
+// Port returns the host port the database port is published on.
+func (c *SQLContainer) Port() string {
+	return c.port
+}
+
+// Shutdown cleans up the container by stopping and removing it.
+func (c *SQLContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
@@ -0,0 +1,33 @@
+package sqltestutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStartSQLSpecValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		spec ContainerSpec
+	}{
+		{
+			name: "no image",
+			spec: ContainerSpec{Port: "5432/tcp"},
+		},
+		{
+			name: "no port",
+			spec: ContainerSpec{Image: "postgres:16"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := StartSQLContainer(context.Background(), tt.spec); err == nil {
+				t.Error("StartSQLContainer() error = nil, want invalid spec")
+			}
+		})
+	}
+}
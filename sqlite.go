@@ -0,0 +1,117 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultSQLiteDriver = "sqlite"
+
+// SQLiteTestDBOptions configures NewSQLiteTestDB.
+type SQLiteTestDBOptions struct {
+	// DriverName is the database/sql driver to open the database with. The
+	// driver isn't imported by this package, so the caller must import one,
+	// e.g. modernc.org/sqlite ("sqlite") or github.com/mattn/go-sqlite3
+	// ("sqlite3"). Defaults to "sqlite".
+	DriverName string
+	// InMemory creates a shared-cache in-memory database instead of a
+	// temporary file.
+	InMemory bool
+	// Migrations is a directory of migrations to run, see RunMigrations.
+	// Optional.
+	Migrations string
+	// Scenario is a scenario file to load after the migrations, see
+	// LoadScenario. Optional.
+	Scenario string
+}
+
+// SQLiteTestDB is a throwaway SQLite database for fast unit tests that don't
+// need a container. It embeds the *sql.DB connected to it.
+type SQLiteTestDB struct {
+	*sql.DB
+
+	// dir is the temporary directory holding the database file, if any.
+	dir string
+	// keepAlive holds the in-memory database open between uses of the pool.
+	keepAlive *sql.Conn
+}
+
+// NewSQLiteTestDB creates a SQLite database in a temporary file, or in memory
+// if opts.InMemory is set, and populates it with opts.Migrations and
+// opts.Scenario. The database is deleted by Close.
+//
+// Migrations and scenarios are the same files used with Postgres, so they must
+// stick to SQL that SQLite understands.
+func NewSQLiteTestDB(ctx context.Context, opts SQLiteTestDBOptions) (*SQLiteTestDB, error) {
+	if opts.DriverName == "" {
+		opts.DriverName = defaultSQLiteDriver
+	}
+
+	d := &SQLiteTestDB{}
+	dsn, err := d.dsn(opts.InMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	d.DB, err = sql.Open(opts.DriverName, dsn)
+	if err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("open sqlite error: %w", err)
+	}
+	if opts.InMemory {
+		d.keepAlive, err = d.DB.Conn(ctx)
+		if err != nil {
+			_ = d.Close()
+			return nil, fmt.Errorf("connect sqlite error: %w", err)
+		}
+	}
+
+	var scenarioFiles []string
+	if opts.Scenario != "" {
+		scenarioFiles = append(scenarioFiles, opts.Scenario)
+	}
+	if err := applyFixtures(ctx, d.DB, opts.Migrations, scenarioFiles); err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// dsn returns the data source name of a new database, creating its temporary
+// directory if it's file-based.
+func (d *SQLiteTestDB) dsn(inMemory bool) (string, error) {
+	if inMemory {
+		name, err := randomPassword()
+		if err != nil {
+			return "", err
+		}
+		return "file:sqltestutil_" + strings.ToLower(name[:16]) + "?mode=memory&cache=shared", nil
+	}
+
+	dir, err := os.MkdirTemp("", "sqltestutil-sqlite-")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir error: %w", err)
+	}
+	d.dir = dir
+	return "file:" + filepath.Join(dir, "test.db"), nil
+}
+
+// Close closes the database and deletes it.
+func (d *SQLiteTestDB) Close() error {
+	var errs []error
+	if d.keepAlive != nil {
+		errs = append(errs, d.keepAlive.Close())
+	}
+	if d.DB != nil {
+		errs = append(errs, d.DB.Close())
+	}
+	if d.dir != "" {
+		errs = append(errs, os.RemoveAll(d.dir))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package sqltestutil
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteTestDBDSN(t *testing.T) {
+	t.Parallel()
+
+	d := &SQLiteTestDB{}
+	dsn, err := d.dsn(true)
+	if err != nil {
+		t.Fatalf("dsn() error = %v", err)
+	}
+	if !strings.HasPrefix(dsn, "file:sqltestutil_") || !strings.HasSuffix(dsn, "?mode=memory&cache=shared") {
+		t.Errorf("in-memory dsn = %q", dsn)
+	}
+	if d.dir != "" {
+		t.Errorf("in-memory dir = %q, want none", d.dir)
+	}
+
+	d = &SQLiteTestDB{}
+	dsn, err = d.dsn(false)
+	if err != nil {
+		t.Fatalf("dsn() error = %v", err)
+	}
+	if !strings.HasPrefix(dsn, "file:"+d.dir) {
+		t.Errorf("file dsn = %q, want it inside %q", dsn, d.dir)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(d.dir); !os.IsNotExist(err) {
+		t.Errorf("temp dir still exists after Close: %v", err)
+	}
+}
+
+func TestNewSQLiteTestDBUnknownDriver(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSQLiteTestDB(context.Background(), SQLiteTestDBOptions{
+		DriverName: "sqltestutil-missing",
+	})
+	if err == nil {
+		t.Fatal("NewSQLiteTestDB() error = nil, want unknown driver error")
+	}
+}
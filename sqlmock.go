@@ -0,0 +1,78 @@
+package sqltestutil
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// ExpectMigrations registers the statements RunMigrations would execute for
+// migrationDir as ordered expectations on mock, so a unit test using
+// github.com/DATA-DOG/go-sqlmock can verify that code under test applies the
+// same migrations the integration path does. Each migration is expected as a
+// single Exec of the file's contents.
+func ExpectMigrations(mock sqlmock.Sqlmock, migrationDir string) error {
+	filenames, err := migrationFilenames(migrationDir)
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read file error: %w", err)
+		}
+		mock.ExpectExec(regexp.QuoteMeta(string(data))).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	return nil
+}
+
+// ExpectScenario registers the INSERT statements LoadScenario would execute
+// for a scenario file, with their arguments, as ordered expectations on mock.
+// It's the go-sqlmock counterpart of LoadScenario and takes the same options,
+// of which only WithDialect has an effect. The queries of the scenario's
+// queries section are expected too, each returning its expected value, and
+// rows with _expect_error fail with the declared error.
+func ExpectScenario(mock sqlmock.Sqlmock, filename string, options ...ScenarioOption) error {
+	config := &scenarioConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	tables, queries, err := parseScenario(data)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for _, row := range table.rows {
+			query, values := insertStatement(config.dialect, table.name, row)
+			// go-sqlmock converts expected arguments like database/sql
+			// converts actual ones, so e.g. a YAML int matches an int64
+			args := make([]driver.Value, len(values))
+			for i, value := range values {
+				args[i] = value
+			}
+			expectation := mock.ExpectExec(regexp.QuoteMeta(query)).
+				WithArgs(args...)
+			if row.expectError != "" {
+				expectation.WillReturnError(errors.New(row.expectError))
+			} else {
+				expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+		}
+	}
+	for _, query := range queries {
+		// the assertion holds, as it would against the real database
+		mock.ExpectQuery(regexp.QuoteMeta(query.sql)).
+			WillReturnRows(sqlmock.NewRows([]string{"expect"}).AddRow(query.expect))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sqltestutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExpectMigrationsAndScenario(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		scenario string
+		options  []ScenarioOption
+	}{
+		{
+			name:     "postgres",
+			scenario: "testdata/scenario.yml",
+		},
+		{
+			name:     "mysql",
+			scenario: "testdata/scenario.yml",
+			options:  []ScenarioOption{WithDialect(DialectMySQL)},
+		},
+		{
+			name:     "queries",
+			scenario: "testdata/queries.yml",
+		},
+		{
+			name:     "expect error",
+			scenario: "testdata/expect_error.yml",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+
+			if err := ExpectMigrations(mock, "testdata"); err != nil {
+				t.Fatalf("ExpectMigrations() error = %v", err)
+			}
+			if err := ExpectScenario(mock, tt.scenario, tt.options...); err != nil {
+				t.Fatalf("ExpectScenario() error = %v", err)
+			}
+
+			ctx := context.Background()
+			if err := RunMigrations(ctx, db, "testdata"); err != nil {
+				t.Fatalf("RunMigrations() error = %v", err)
+			}
+			if err := LoadScenario(ctx, db, tt.scenario, tt.options...); err != nil {
+				t.Fatalf("LoadScenario() error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package sqltestutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// containerState is the file format of SaveState.
+type containerState struct {
+	ID             string  `json:"id"`
+	User           string  `json:"user"`
+	Password       string  `json:"password"`
+	Database       string  `json:"database"`
+	Port           string  `json:"port"`
+	ConnStr        string  `json:"conn_str"`
+	ViaIP          bool    `json:"via_ip,omitempty"`
+	Network        string  `json:"network,omitempty"`
+	NetworkConnStr string  `json:"network_conn_str,omitempty"`
+	ServerVersion  string  `json:"server_version"`
+	External       bool    `json:"external,omitempty"`
+	Profile        Profile `json:"profile,omitempty"`
+}
+
+// SaveState writes what another process needs to adopt the container with
+// LoadPostgresContainer to path, including its password, so the file is only
+// readable by the current user. This lets one container be shared across the
+// test binaries of a multi-module repository, e.g. started by a Makefile
+// target or the TestMain of one package. The container must outlive the
+// process that started it, so it mustn't be started with WithReaper, and that
+// process mustn't shut it down while others use it.
+func (c *PostgresContainer) SaveState(path string) error {
+	if c.pod != nil {
+		return errors.New("the state of a Kubernetes pod can't be saved")
+	}
+	if c.passwordFunc != nil {
+		return errors.New("the state of a server connected to with a PasswordFunc can't be saved")
+	}
+	c.mu.RLock()
+	port, connStr, networkConnStr := c.port, c.connStr, c.networkConnStr
+	c.mu.RUnlock()
+	data, err := json.MarshalIndent(containerState{
+		ID:             c.id,
+		User:           c.user,
+		Password:       c.password,
+		Database:       c.dbName,
+		Port:           port,
+		ConnStr:        connStr,
+		ViaIP:          c.viaIP,
+		Network:        c.network,
+		NetworkConnStr: networkConnStr,
+		ServerVersion:  c.serverVersion,
+		External:       c.external,
+		Profile:        c.profile,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state error: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write state error: %w", err)
+	}
+	return nil
+}
+
+// LoadPostgresContainer adopts the container whose state was saved to path
+// with SaveState, after checking that it's still reachable. The adopted
+// container belongs to the process that started it, so Shutdown leaves it
+// running unless WithRemoveReused is passed. Of the options only
+// WithDockerClientOpts and WithDockerClient apply.
+func LoadPostgresContainer(ctx context.Context, path string, options ...Option) (*PostgresContainer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read state error: %w", err)
+	}
+	pg, err := decodeState(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := pg.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("container %s from %s is not reachable: %w", pg.id, path, err)
+	}
+	return pg, nil
+}
+
+// decodeState returns the container of the state written by SaveState.
+func decodeState(data []byte, options []Option) (*PostgresContainer, error) {
+	var state containerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode state error: %w", err)
+	}
+	if state.ConnStr == "" {
+		return nil, errors.New("state has no connection string")
+	}
+
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	return &PostgresContainer{
+		id:             state.ID,
+		user:           state.User,
+		password:       state.Password,
+		dbName:         state.Database,
+		port:           state.Port,
+		connStr:        state.ConnStr,
+		viaIP:          state.ViaIP,
+		network:        state.Network,
+		networkConnStr: state.NetworkConnStr,
+		serverVersion:  state.ServerVersion,
+		docker:         config.docker(),
+		external:       state.External,
+		reused:         true,
+		profile:        state.Profile,
+		logger:         config.Logger,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// LoadScenarioForTenants loads a scenario file once per tenant, setting
+// tenantColumn of every row to the tenant's ID, so that one fixture file can
+// populate several tenants of a multi-tenant schema:
+//
+//	err := sqltestutil.LoadScenarioForTenants(ctx, db, "testdata/base.yml",
+//	    []string{"acme", "globex"}, "tenant_id")
+//
+// A value for tenantColumn in the file is overwritten. Every table of the file
+// must have tenantColumn, and explicit keys in the file must be unique per
+// tenant rather than globally, e.g. a primary key of (tenant_id, id). The
+// queries section is evaluated once, after all tenants are loaded, so it sees
+// the rows of all of them. Otherwise it behaves like LoadScenario.
+func LoadScenarioForTenants(
+	ctx context.Context,
+	db ExecerContext,
+	filename string,
+	tenantIDs []string,
+	tenantColumn string,
+	options ...ScenarioOption,
+) error {
+	if tenantColumn == "" {
+		return errors.New("tenant column must not be empty")
+	}
+	config := &scenarioConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	tables, queries, err := parseScenario(data)
+	if err != nil {
+		return err
+	}
+	for _, tenantID := range tenantIDs {
+		if err := loadTables(ctx, db, filename, stampTenant(tables, tenantColumn, tenantID), config); err != nil {
+			return err
+		}
+	}
+	return checkQueries(ctx, db, filename, queries)
+}
+
+// stampTenant returns a copy of tables with column set to tenantID in every
+// row, added as the first column if the row doesn't have it.
+func stampTenant(tables []scenarioTable, column, tenantID string) []scenarioTable {
+	stamped := make([]scenarioTable, len(tables))
+	for i, table := range tables {
+		table.rows = make([]scenarioRow, len(tables[i].rows))
+		for j, row := range tables[i].rows {
+			table.rows[j] = stampRow(row, column, tenantID)
+		}
+		stamped[i] = table
+	}
+	return stamped
+}
+
+func stampRow(row scenarioRow, column, tenantID string) scenarioRow {
+	for i, c := range row.columns {
+		if c == column {
+			row.values = append([]interface{}(nil), row.values...)
+			row.values[i] = tenantID
+			return row
+		}
+	}
+	row.columns = append([]string{column}, row.columns...)
+	row.values = append([]interface{}{tenantID}, row.values...)
+	row.lines = append([]int{row.line}, row.lines...)
+	return row
+}
@@ -0,0 +1,76 @@
+package sqltestutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadScenarioForTenants(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	query := regexp.QuoteMeta(`INSERT INTO "users" (tenant_id, username, password) VALUES ($1, $2, $3)`)
+	for _, tenant := range []string{"acme", "globex"} {
+		for i := 1; i <= 3; i++ {
+			mock.ExpectExec(query).
+				WithArgs(tenant, fmt.Sprintf("user%d", i), fmt.Sprintf("password%d", i)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+	}
+
+	err = LoadScenarioForTenants(context.Background(), db, "testdata/scenario.yml", []string{"acme", "globex"}, "tenant_id")
+	if err != nil {
+		t.Fatalf("LoadScenarioForTenants() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStampRow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		row         scenarioRow
+		wantColumns []string
+		wantValues  []interface{}
+	}{
+		{
+			name:        "added",
+			row:         scenarioRow{columns: []string{"id"}, values: []interface{}{1}, lines: []int{2}},
+			wantColumns: []string{"tenant_id", "id"},
+			wantValues:  []interface{}{"acme", 1},
+		},
+		{
+			name:        "overwritten",
+			row:         scenarioRow{columns: []string{"id", "tenant_id"}, values: []interface{}{1, "other"}, lines: []int{2, 3}},
+			wantColumns: []string{"id", "tenant_id"},
+			wantValues:  []interface{}{1, "acme"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			original := append([]interface{}(nil), tt.row.values...)
+			got := stampRow(tt.row, "tenant_id", "acme")
+			if !reflect.DeepEqual(got.columns, tt.wantColumns) || !reflect.DeepEqual(got.values, tt.wantValues) {
+				t.Errorf("stampRow() = %v %v, want %v %v", got.columns, got.values, tt.wantColumns, tt.wantValues)
+			}
+			if !reflect.DeepEqual(tt.row.values, original) {
+				t.Errorf("stampRow() modified the original row: %v", tt.row.values)
+			}
+		})
+	}
+}
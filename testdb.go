@@ -0,0 +1,219 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const defaultTestDBVersion = "16"
+
+// dsnEnv names the environment variable with the default for
+// TestDBOptions.DSN.
+const dsnEnv = "SQLTESTUTIL_DSN"
+
+// TestDBOptions configures TestDB.
+type TestDBOptions struct {
+	// Version is the Postgres version of the shared container, see
+	// StartPostgresContainer. Defaults to "16".
+	Version string
+	// Migrations is a directory of migrations to run, see RunMigrations.
+	// Optional.
+	Migrations string
+	// Scenario is a scenario file to load after the migrations, see
+	// LoadScenario. Optional.
+	Scenario string
+	// ContainerOptions are passed to StartPostgresContainer when the shared
+	// container for Version is started. They're ignored if the container is
+	// already running.
+	ContainerOptions []Option
+	// DSN is the URL of an existing Postgres server to create the databases
+	// on instead of a container, see ConnectPostgres. Version is ignored then,
+	// and ContainerOptions are passed to ConnectPostgres. Defaults to the
+	// SQLTESTUTIL_DSN environment variable.
+	DSN string
+}
+
+var sharedContainers = struct {
+	sync.Mutex
+	containers map[string]*PostgresContainer
+	// templates maps a container version and fixture inputs to the name of the
+	// template database prepared for them.
+	templates map[string]string
+}{
+	containers: map[string]*PostgresContainer{},
+	templates:  map[string]string{},
+}
+
+// TestDB returns a connection to a fresh database for the calling test, which
+// covers the most common use of this package in one call:
+//
+//	func TestSomething(t *testing.T) {
+//	    db := sqltestutil.TestDB(t, sqltestutil.TestDBOptions{
+//	        Version:    "16",
+//	        Migrations: "migrations",
+//	        Scenario:   "testdata/base.yml",
+//	    })
+//	    // use db
+//	}
+//
+// One container per Postgres version is started and shared by the whole test
+// process. The migrations and scenario are loaded once into a template
+// database (see PrepareDatabase), and every test gets its own copy of it, so
+// tests can run in parallel and modify data freely. The database is dropped
+// when the test finishes.
+//
+// The shared containers outlive individual tests, so call
+// ShutdownSharedContainers from TestMain once all tests are done.
+//
+// With TestDBOptions.DSN or the SQLTESTUTIL_DSN environment variable set, the
+// databases are created on that server instead, e.g. a managed cloud database
+// in a nightly integration run.
+func TestDB(t testing.TB, opts TestDBOptions) *sql.DB {
+	t.Helper()
+
+	if opts.Version == "" {
+		opts.Version = defaultTestDBVersion
+	}
+	if opts.DSN == "" {
+		opts.DSN = os.Getenv(dsnEnv)
+	}
+
+	ctx := context.Background()
+	pg, template, err := testDBTemplate(ctx, opts)
+	if err != nil {
+		t.Fatalf("could not prepare test database: %v", err)
+	}
+
+	name, err := randomPassword()
+	if err != nil {
+		t.Fatalf("could not generate database name: %v", err)
+	}
+	name = "test_" + strings.ToLower(name[:16])
+
+	admin, err := pg.Open()
+	if err != nil {
+		t.Fatalf("could not open connection: %v", err)
+	}
+	defer admin.Close()
+
+	_, err = admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %q TEMPLATE %q", name, template))
+	if err != nil {
+		t.Fatalf("could not create test database: %v", err)
+	}
+	// tagged, so the destructive helpers may touch it, see AllowDestructive
+	_, err = admin.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %q IS '%s'", name, testDatabaseTag))
+	if err != nil {
+		t.Fatalf("could not tag test database: %v", err)
+	}
+
+	connStr, err := pg.connectionStringFor(name)
+	if err != nil {
+		t.Fatalf("could not build connection string: %v", err)
+	}
+	db, err := pg.open(connStr)
+	if err != nil {
+		t.Fatalf("could not open connection: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("could not close test database: %v", err)
+		}
+		if err := dropDatabase(ctx, pg, name); err != nil {
+			t.Errorf("could not drop test database: %v", err)
+		}
+	})
+
+	return db
+}
+
+// testDBTemplate returns the shared container for opts.Version and the name of
+// the template database for opts, starting and preparing them as needed.
+func testDBTemplate(ctx context.Context, opts TestDBOptions) (*PostgresContainer, string, error) {
+	sharedContainers.Lock()
+	defer sharedContainers.Unlock()
+
+	server := opts.Version
+	if opts.DSN != "" {
+		server = opts.DSN
+	}
+	pg := sharedContainers.containers[server]
+	if pg == nil {
+		var err error
+		if opts.DSN != "" {
+			pg, err = ConnectPostgres(ctx, opts.DSN, opts.ContainerOptions...)
+		} else {
+			pg, err = StartPostgresContainer(ctx, opts.Version, opts.ContainerOptions...)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		sharedContainers.containers[server] = pg
+	}
+
+	var scenarioFiles []string
+	if opts.Scenario != "" {
+		scenarioFiles = append(scenarioFiles, opts.Scenario)
+	}
+	key := strings.Join(append([]string{server, opts.Migrations}, scenarioFiles...), "\x00")
+	template, ok := sharedContainers.templates[key]
+	if !ok {
+		var err error
+		template, err = pg.prepareDatabase(ctx, opts.Migrations, scenarioFiles)
+		if err != nil {
+			return nil, "", err
+		}
+		sharedContainers.templates[key] = template
+	}
+	return pg, template, nil
+}
+
+// ShutdownSharedContainers shuts down the containers started by TestDB. It's
+// meant to be called from TestMain after the tests have run:
+//
+//	func TestMain(m *testing.M) {
+//	    code := m.Run()
+//	    _ = sqltestutil.ShutdownSharedContainers(context.Background())
+//	    os.Exit(code)
+//	}
+func ShutdownSharedContainers(ctx context.Context) error {
+	sharedContainers.Lock()
+	defer sharedContainers.Unlock()
+
+	var errs []error
+	for version, pg := range sharedContainers.containers {
+		if err := pg.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown postgres %s: %w", version, err))
+		}
+		delete(sharedContainers.containers, version)
+	}
+	clear(sharedContainers.templates)
+	return errors.Join(errs...)
+}
+
+func dropDatabase(ctx context.Context, pg *PostgresContainer, name string) error {
+	admin, err := pg.Open()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	return dropTestDatabase(ctx, admin, name)
+}
+
+// dropTestDatabase drops the named database after terminating the
+// connections to it that are still open. Closing a pool doesn't wait for the
+// server to end its backends, and DROP DATABASE fails while any are left.
+// DROP DATABASE ... WITH (FORCE) does the same, but only from Postgres 13 on.
+func dropTestDatabase(ctx context.Context, admin ExecerContext, name string) error {
+	if _, err := admin.ExecContext(ctx, DefaultQueries.TerminateBackends, name); err != nil {
+		return fmt.Errorf("terminate connections error: %w", err)
+	}
+	_, err := admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %q", name))
+	return err
+}
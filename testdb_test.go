@@ -0,0 +1,106 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDropTestDatabase(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("permission denied")
+
+	tests := []struct {
+		name    string
+		expect  func(mock sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "dropped",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SELECT pg_terminate_backend").WithArgs("test_abc").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`DROP DATABASE IF EXISTS "test_abc"`)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "terminate failed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SELECT pg_terminate_backend").WithArgs("test_abc").
+					WillReturnError(failed)
+			},
+			wantErr: failed,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+			tt.expect(mock)
+
+			err = dropTestDatabase(context.Background(), db, "test_abc")
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("dropTestDatabase() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTestDB(t *testing.T) {
+	ctx := context.Background()
+	if err := Preflight(ctx); err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() {
+		if err := ShutdownSharedContainers(ctx); err != nil {
+			t.Errorf("ShutdownSharedContainers() error = %v", err)
+		}
+	})
+
+	var name string
+	t.Run("test", func(t *testing.T) {
+		db := TestDB(t, TestDBOptions{Migrations: "testdata"})
+		if err := db.QueryRowContext(ctx, "SELECT current_database()").Scan(&name); err != nil {
+			t.Fatalf("could not query database: %v", err)
+		}
+		// a connection left open by the test must not keep the database from
+		// being dropped
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("could not get connection: %v", err)
+		}
+		if err := conn.PingContext(ctx); err != nil {
+			t.Fatalf("could not ping database: %v", err)
+		}
+	})
+
+	pg := sharedContainers.containers[defaultTestDBVersion]
+	admin, err := pg.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	var exists bool
+	err = admin.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+	if err != nil {
+		t.Fatalf("could not query databases: %v", err)
+	}
+	if exists {
+		t.Errorf("database %s still exists after the test", name)
+	}
+}
@@ -0,0 +1,107 @@
+package sqltestutil
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	// tidbStartupTimeout is the default StartupTimeout for TiDB.
+	tidbStartupTimeout = time.Minute
+
+	// tidbUser and tidbDatabase are the user and database a fresh TiDB server
+	// comes with. The image has no way to create others on startup.
+	tidbUser     = "root"
+	tidbDatabase = "test"
+)
+
+// TiDBContainer is a Docker container running a standalone TiDB server, which
+// speaks the MySQL protocol. It can be used to start a throwaway TiDB instance
+// for testing.
+type TiDBContainer struct {
+	id      string
+	port    string
+	connStr string
+
+	docker dockerConfig
+}
+
+// StartTiDBContainer starts a new TiDB Docker container from the pingcap/tidb
+// image and waits until its MySQL protocol port accepts connections. The
+// version parameter is the image tag, e.g. "v7.5.0". The options for the
+// Docker client, image pulls, the reaper and the host apply; of those
+// configuring the database only WithStartupTimeout does: the server always has
+// a root user without a password and a database named test.
+//
+// The connection string is meant for the github.com/go-sql-driver/mysql driver,
+// which has to be imported by the caller. Load scenarios with
+// WithDialect(DialectMySQL).
+func StartTiDBContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*TiDBContainer, error) {
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image:  "pingcap/tidb:" + version,
+		port:   "4000/tcp",
+		pull:   config.imagePull(),
+		reaper: config.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host := containerHost(cli, config.HostOverride)
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(tidbStartupTimeout))
+	defer cancel()
+
+	if err := waitUntilMySQLReady(waitCtx, net.JoinHostPort(host, port)); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &TiDBContainer{
+		id:      containerID,
+		port:    port,
+		connStr: mysqlDSN(tidbUser, "", host, port, tidbDatabase),
+		docker:  config.docker(),
+	}, nil
+}
+
+// ConnectionString returns a DSN for the github.com/go-sql-driver/mysql driver
+// that can be used to connect to the running TiDB container.
+func (c *TiDBContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running TiDB container.
+func (c *TiDBContainer) ID() string {
+	return c.id
+}
+
+// User returns the name of the database user, which is always root.
+func (c *TiDBContainer) User() string {
+	return tidbUser
+}
+
+// Database returns the name of the database, which is always test.
+func (c *TiDBContainer) Database() string {
+	return tidbDatabase
+}
+
+// Shutdown cleans up the TiDB container by stopping and removing it.
+func (c *TiDBContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
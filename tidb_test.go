@@ -0,0 +1,31 @@
+package sqltestutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStartTiDBContainer(t *testing.T) {
+	t.Parallel()
+
+	port := fakeMySQL(t)
+	var created container.Config
+	cli, _ := newFakeDocker(t, runRoutes("pingcap/tidb:v7.5.0", "4000/tcp", port, &created))
+
+	c, err := StartTiDBContainer(context.Background(), "v7.5.0", WithDockerClient(cli))
+	if err != nil {
+		t.Fatalf("StartTiDBContainer() error = %v", err)
+	}
+	if created.Image != "pingcap/tidb:v7.5.0" {
+		t.Errorf("image = %s, want pingcap/tidb:v7.5.0", created.Image)
+	}
+	if _, ok := created.ExposedPorts["4000/tcp"]; !ok {
+		t.Errorf("exposed ports = %v, want 4000/tcp", created.ExposedPorts)
+	}
+	want := "root@tcp(127.0.0.1:" + port + ")/test?parseTime=true&multiStatements=true"
+	if got := c.ConnectionString(); got != want {
+		t.Errorf("ConnectionString() = %s, want %s", got, want)
+	}
+}
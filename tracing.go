@@ -0,0 +1,39 @@
+package sqltestutil
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the package.
+const tracerName = "github.com/buildpeak/sqltestutil"
+
+// startSpan starts a span named name with provider, if it's not nil, and
+// returns the context carrying it and a function that ends it with the error
+// of the traced step. Without a provider, ctx is returned as is and ending is a
+// no-op.
+func startSpan(ctx context.Context, provider trace.TracerProvider, name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	if provider == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := provider.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// containerAttrs returns the span attributes of a container.
+func containerAttrs(containerID, image string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("container.image.name", image)}
+	if containerID != "" {
+		attrs = append(attrs, attribute.String("container.id", containerID))
+	}
+	return attrs
+}
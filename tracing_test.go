@@ -0,0 +1,110 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordedSpan struct {
+	trace.Span
+	name   string
+	attrs  []attribute.KeyValue
+	err    error
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) { s.err = err }
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordedSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+type recordingProvider struct {
+	embedded.TracerProvider
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	provider *recordingProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	_, span := noop.NewTracerProvider().Tracer("").Start(ctx, name)
+	config := trace.NewSpanStartConfig(opts...)
+	s := &recordedSpan{Span: span, name: name, attrs: config.Attributes()}
+	t.provider.spans = append(t.provider.spans, s)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func TestStartSpan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{name: "success", wantStatus: codes.Unset},
+		{name: "failure", err: errors.New("boom"), wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &recordingProvider{}
+			ctx, end := startSpan(context.Background(), provider, "sqltestutil.pull", containerAttrs("abc", "postgres:16")...)
+			end(tt.err)
+
+			if len(provider.spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(provider.spans))
+			}
+			span := provider.spans[0]
+			if trace.SpanFromContext(ctx) != span {
+				t.Error("context doesn't carry the span")
+			}
+			if span.name != "sqltestutil.pull" || !span.ended {
+				t.Errorf("got span %q ended=%v", span.name, span.ended)
+			}
+			if len(span.attrs) != 2 {
+				t.Errorf("got attributes %v, want image and id", span.attrs)
+			}
+			if !errors.Is(span.err, tt.err) || span.status != tt.wantStatus {
+				t.Errorf("got err %v status %v, want %v %v", span.err, span.status, tt.err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStartSpanWithoutProvider(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	got, end := startSpan(ctx, nil, "sqltestutil.pull")
+	end(errors.New("boom"))
+	if got != ctx {
+		t.Error("context changed without a provider")
+	}
+}
+
+func TestContainerAttrs(t *testing.T) {
+	t.Parallel()
+
+	if got := containerAttrs("", "postgres:16"); len(got) != 1 {
+		t.Errorf("got %v, want only the image", got)
+	}
+}
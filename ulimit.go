@@ -0,0 +1,55 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/go-units"
+)
+
+// Ulimit is a resource limit of the container, see WithUlimit.
+type Ulimit struct {
+	// Name is the resource, e.g. "nofile" or "nproc".
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// defaultUlimits are the limits Postgres containers get unless overridden with
+// WithUlimit. The open files limit of some CI runners is too low for tests
+// with many connections, each of which is a backend process with files open.
+var defaultUlimits = []Ulimit{
+	{Name: "nofile", Soft: 65536, Hard: 65536},
+}
+
+// ulimits returns the resource limits of the container: defaultUlimits, with
+// those of config overriding them by name, sorted by name.
+func ulimits(config *PostgresContainerConfig) []*units.Ulimit {
+	byName := map[string]Ulimit{}
+	for _, u := range defaultUlimits {
+		byName[u.Name] = u
+	}
+	for _, u := range config.Ulimits {
+		byName[u.Name] = u
+	}
+	limits := make([]*units.Ulimit, 0, len(byName))
+	for _, u := range byName {
+		limits = append(limits, &units.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].Name < limits[j].Name
+	})
+	return limits
+}
+
+// validateUlimit returns the problems with u, see
+// PostgresContainerConfig.Validate.
+func validateUlimit(u Ulimit) error {
+	if u.Name == "" {
+		return fmt.Errorf("ulimit has no Name")
+	}
+	if u.Soft < 0 || u.Soft > u.Hard {
+		return fmt.Errorf("ulimit %s must have 0 <= Soft <= Hard, got %d and %d", u.Name, u.Soft, u.Hard)
+	}
+	return nil
+}
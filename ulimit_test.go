@@ -0,0 +1,49 @@
+package sqltestutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-units"
+)
+
+func TestUlimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    []*units.Ulimit
+	}{
+		{
+			name: "default",
+			want: []*units.Ulimit{{Name: "nofile", Soft: 65536, Hard: 65536}},
+		},
+		{
+			name:    "override",
+			options: []Option{WithUlimit("nofile", 1024, 4096)},
+			want:    []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+		},
+		{
+			name:    "additional",
+			options: []Option{WithUlimit("nproc", 4096, 8192)},
+			want: []*units.Ulimit{
+				{Name: "nofile", Soft: 65536, Hard: 65536},
+				{Name: "nproc", Soft: 4096, Hard: 8192},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := &PostgresContainerConfig{}
+			for _, option := range tt.options {
+				option(config)
+			}
+			if got := ulimits(config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ulimits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
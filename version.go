@@ -0,0 +1,74 @@
+package sqltestutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ServerVersion returns the version reported by the server, e.g. "15.4".
+func (c *PostgresContainer) ServerVersion() string {
+	return c.serverVersion
+}
+
+// queryServerVersion returns the server_version setting of the server at
+// connStr, without the distribution suffix some images add.
+func queryServerVersion(ctx context.Context, connStr string) (string, error) {
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	return serverVersionOf(ctx, db)
+}
+
+// serverVersionOf returns the server_version setting of the server db is
+// connected to, without the distribution suffix some images add.
+func serverVersionOf(ctx context.Context, db *sql.DB) (string, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, DefaultQueries.ServerVersion).Scan(&version); err != nil {
+		return "", fmt.Errorf("query server version error: %w", err)
+	}
+	if fields := strings.Fields(version); len(fields) > 0 {
+		version = fields[0]
+	}
+	return version, nil
+}
+
+// checkServerVersion returns an error if serverVersion doesn't belong to the
+// major version in the requested image tag, e.g. "15", "15.4" or
+// "15-alpine". Tags without a leading version, like "latest", match anything.
+func checkServerVersion(tag, serverVersion string) error {
+	want := majorVersion(tag)
+	if want == "" {
+		return nil
+	}
+	if got := majorVersion(serverVersion); got != want {
+		return fmt.Errorf(
+			"server version %s does not match requested version %s",
+			serverVersion,
+			tag,
+		)
+	}
+	return nil
+}
+
+// majorVersion returns the major version of a Postgres version string. Before
+// Postgres 10 the major version had two components, e.g. "9.6".
+func majorVersion(version string) string {
+	end := strings.IndexFunc(version, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end >= 0 {
+		version = version[:end]
+	}
+	parts := strings.Split(strings.Trim(version, "."), ".")
+	if parts[0] == "" {
+		return ""
+	}
+	if len(parts[0]) == 1 && len(parts) > 1 {
+		return parts[0] + "." + parts[1]
+	}
+	return parts[0]
+}
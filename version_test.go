@@ -0,0 +1,35 @@
+package sqltestutil
+
+import (
+	"testing"
+)
+
+func TestCheckServerVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tag           string
+		serverVersion string
+		wantErr       bool
+	}{
+		{tag: "15", serverVersion: "15.4"},
+		{tag: "15.4", serverVersion: "15.4"},
+		{tag: "15-alpine", serverVersion: "15.6"},
+		{tag: "9.6", serverVersion: "9.6.24"},
+		{tag: "latest", serverVersion: "16.2"},
+		{tag: "15", serverVersion: "16.2", wantErr: true},
+		{tag: "9.5", serverVersion: "9.6.24", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.tag+"/"+tt.serverVersion, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkServerVersion(tt.tag, tt.serverVersion)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkServerVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package sqltestutil
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	// vitessStartupTimeout is the default StartupTimeout for Vitess, which
+	// starts a MySQL instance per shard before vtcombo serves queries.
+	vitessStartupTimeout = 3 * time.Minute
+
+	// vitessKeyspace is the keyspace created in the Vitess container.
+	vitessKeyspace = "test"
+)
+
+// VitessContainer is a Docker container running vtcombo, which bundles the
+// Vitess components into a single process for testing. It serves the MySQL
+// protocol, so it can be used with the same drivers as MariaDB.
+type VitessContainer struct {
+	id      string
+	port    string
+	connStr string
+
+	docker dockerConfig
+}
+
+// StartVitessContainer starts a new Vitess Docker container from the
+// vitess/vttestserver image with a keyspace named test of two shards, and waits
+// until vtcombo accepts MySQL protocol connections. The version parameter is
+// the image tag, e.g. "mysql80". The options for the Docker client, image
+// pulls, the reaper and the host apply; of those configuring the database only
+// WithStartupTimeout does, and it defaults to three minutes.
+//
+// The connection string is meant for the github.com/go-sql-driver/mysql driver
+// and connects to the keyspace as its database. vtcombo doesn't authenticate,
+// so any user is accepted.
+func StartVitessContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*VitessContainer, error) {
+	config := &PostgresContainerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image: "vitess/vttestserver:" + version,
+		env: []string{
+			"PORT=33574",
+			"KEYSPACES=" + vitessKeyspace,
+			"NUM_SHARDS=2",
+			"MYSQL_BIND_HOST=0.0.0.0",
+		},
+		// vttestserver serves MySQL on PORT+3
+		port:   "33577/tcp",
+		pull:   config.imagePull(),
+		reaper: config.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host := containerHost(cli, config.HostOverride)
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(vitessStartupTimeout))
+	defer cancel()
+
+	if err := waitUntilMySQLReady(waitCtx, net.JoinHostPort(host, port)); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &VitessContainer{
+		id:      containerID,
+		port:    port,
+		connStr: mysqlDSN("root", "", host, port, vitessKeyspace),
+		docker:  config.docker(),
+	}, nil
+}
+
+// ConnectionString returns a DSN for the github.com/go-sql-driver/mysql driver
+// that can be used to connect to the test keyspace.
+func (c *VitessContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running Vitess container.
+func (c *VitessContainer) ID() string {
+	return c.id
+}
+
+// Keyspace returns the name of the keyspace, which is always test.
+func (c *VitessContainer) Keyspace() string {
+	return vitessKeyspace
+}
+
+// Shutdown cleans up the Vitess container by stopping and removing it.
+func (c *VitessContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}
@@ -0,0 +1,36 @@
+package sqltestutil
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStartVitessContainer(t *testing.T) {
+	t.Parallel()
+
+	port := fakeMySQL(t)
+	var created container.Config
+	cli, _ := newFakeDocker(t, runRoutes("vitess/vttestserver:mysql80", "33577/tcp", port, &created))
+
+	c, err := StartVitessContainer(context.Background(), "mysql80", WithDockerClient(cli))
+	if err != nil {
+		t.Fatalf("StartVitessContainer() error = %v", err)
+	}
+	if created.Image != "vitess/vttestserver:mysql80" {
+		t.Errorf("image = %s, want vitess/vttestserver:mysql80", created.Image)
+	}
+	wantEnv := []string{"PORT=33574", "KEYSPACES=test", "NUM_SHARDS=2", "MYSQL_BIND_HOST=0.0.0.0"}
+	if !slices.Equal(created.Env, wantEnv) {
+		t.Errorf("env = %v, want %v", created.Env, wantEnv)
+	}
+	if _, ok := created.ExposedPorts["33577/tcp"]; !ok {
+		t.Errorf("exposed ports = %v, want 33577/tcp", created.ExposedPorts)
+	}
+	want := "root@tcp(127.0.0.1:" + port + ")/test?parseTime=true&multiStatements=true"
+	if got := c.ConnectionString(); got != want {
+		t.Errorf("ConnectionString() = %s, want %s", got, want)
+	}
+}
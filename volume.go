@@ -0,0 +1,59 @@
+package sqltestutil
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Volume is a host directory or file, or a named Docker volume, mounted into
+// the container, see WithVolume.
+type Volume struct {
+	// HostPath is a path on the Docker host, relative ones to the working
+	// directory, or the name of a Docker volume, which is created if it
+	// doesn't exist yet.
+	HostPath string
+	// ContainerPath is the absolute path it's mounted on in the container.
+	ContainerPath string
+	ReadOnly      bool
+}
+
+// isNamedVolume reports whether hostPath names a Docker volume rather than a
+// path, the way Docker tells them apart.
+func isNamedVolume(hostPath string) bool {
+	return hostPath != "." && hostPath != ".." && !strings.ContainsAny(hostPath, `/\`)
+}
+
+// volumeBinds returns the bind mounts of volumes.
+func volumeBinds(volumes []Volume) ([]string, error) {
+	var binds []string
+	for _, v := range volumes {
+		source := v.HostPath
+		if !isNamedVolume(source) {
+			abs, err := filepath.Abs(source)
+			if err != nil {
+				return nil, fmt.Errorf("volume path error: %w", err)
+			}
+			source = abs
+		}
+		bind := source + ":" + v.ContainerPath
+		if v.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+	return binds, nil
+}
+
+// validateVolume returns the problems with v, see
+// PostgresContainerConfig.Validate.
+func validateVolume(v Volume) error {
+	if v.HostPath == "" {
+		return fmt.Errorf("volume for %s has no HostPath", v.ContainerPath)
+	}
+	if !path.IsAbs(v.ContainerPath) {
+		return fmt.Errorf("volume ContainerPath %q must be absolute", v.ContainerPath)
+	}
+	return nil
+}
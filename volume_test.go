@@ -0,0 +1,53 @@
+package sqltestutil
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVolumeBinds(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	relative, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		volumes []Volume
+		want    []string
+	}{
+		{name: "none"},
+		{
+			name:    "read-only directory",
+			volumes: []Volume{{HostPath: dir, ContainerPath: "/seed", ReadOnly: true}},
+			want:    []string{dir + ":/seed:ro"},
+		},
+		{
+			name:    "relative path",
+			volumes: []Volume{{HostPath: "./testdata", ContainerPath: "/seed"}},
+			want:    []string{relative + ":/seed"},
+		},
+		{
+			name:    "named volume",
+			volumes: []Volume{{HostPath: "pgdata", ContainerPath: "/var/lib/postgresql/data"}},
+			want:    []string{"pgdata:/var/lib/postgresql/data"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := volumeBinds(tt.volumes)
+			if err != nil {
+				t.Fatalf("volumeBinds() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("volumeBinds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package sqltestutil
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// errExited is returned by the log wait strategies when the container stops
+// before logging what they wait for.
+var errExited = errors.New("container exited")
+
+// waitForLog follows the container logs until message has appeared in them
+// the given number of times.
+func waitForLog(ctx context.Context, cli *client.Client, containerID, message string, occurrences int) error {
+	seen := 0
+	err := followLogs(ctx, cli, containerID, func(line string) bool {
+		if strings.Contains(line, message) {
+			seen++
+		}
+		return seen >= occurrences
+	})
+	if errors.Is(err, errExited) {
+		return fmt.Errorf("%w before logging %q", err, message)
+	}
+	return err
+}
+
+// followLogs follows the container logs until done returns true for a line.
+func followLogs(ctx context.Context, cli *client.Client, containerID string, done func(line string) bool) error {
+	reader, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("error reading container logs: %w", err)
+	}
+	defer reader.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, reader)
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		if done(scanner.Text()) {
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading container logs: %w", err)
+	}
+	return errExited
+}
+
+// postgresReadyMessage is what Postgres logs once it accepts connections.
+const postgresReadyMessage = "database system is ready to accept connections"
+
+// postgresLogReady returns a function that is fed the log lines of a Postgres
+// container and reports whether Postgres is ready. When the image initializes
+// a new database, it starts the server once for the init scripts and then
+// restarts it, so the ready message must appear twice; with an existing data
+// directory it appears once.
+func postgresLogReady() func(line string) bool {
+	want, seen := 2, 0
+	return func(line string) bool {
+		if strings.Contains(line, "Skipping initialization") {
+			want = 1
+		}
+		if strings.Contains(line, postgresReadyMessage) {
+			seen++
+		}
+		return seen >= want
+	}
+}
+
+// WaitStrategy decides when the database in a container started by
+// StartSQLContainer is ready to use.
+type WaitStrategy interface {
+	// WaitUntilReady blocks until the database in c is ready, or returns an
+	// error if it won't be before ctx is done.
+	WaitUntilReady(ctx context.Context, c *SQLContainer) error
+}
+
+// WaitStrategyFunc adapts a function to a WaitStrategy.
+type WaitStrategyFunc func(ctx context.Context, c *SQLContainer) error
+
+// WaitUntilReady calls f.
+func (f WaitStrategyFunc) WaitUntilReady(ctx context.Context, c *SQLContainer) error {
+	return f(ctx, c)
+}
+
+// WaitForLog returns a WaitStrategy that waits until message has appeared in
+// the container logs the given number of times. Some images log their ready
+// message twice, once for a temporary server used during initialization.
+func WaitForLog(message string, occurrences int) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		if c.cli == nil {
+			return errors.New("WaitForLog can only be used by StartSQLContainer")
+		}
+		return waitForLog(ctx, c.cli, c.id, message, occurrences)
+	})
+}
+
+// WaitForPostgresLog returns a WaitStrategy for StartPostgresContainer that
+// waits until Postgres logs that it's ready to accept connections, for the
+// second time if the image initialized a new database, see WithWaitStrategy.
+func WaitForPostgresLog() WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		if c.cli == nil {
+			return errors.New("WaitForPostgresLog can only be used while the container starts")
+		}
+		err := followLogs(ctx, c.cli, c.id, postgresLogReady())
+		if errors.Is(err, errExited) {
+			return fmt.Errorf("%w before Postgres was ready", err)
+		}
+		return err
+	})
+}
+
+// WaitForHealthcheck returns a WaitStrategy that waits until the container's
+// healthcheck passes. It's what StartPostgresContainer waits for by default.
+func WaitForHealthcheck() WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		if c.cli == nil {
+			return errors.New("WaitForHealthcheck can only be used while the container starts")
+		}
+		return waitUntilHealthy(ctx, c.cli, c.id, nil)
+	})
+}
+
+// WaitForAll returns a WaitStrategy that waits for each of strategies in
+// turn.
+func WaitForAll(strategies ...WaitStrategy) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		for _, strategy := range strategies {
+			if err := strategy.WaitUntilReady(ctx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WaitForSQL returns a WaitStrategy that waits until the container's
+// connection string can be pinged with the given database/sql driver, which
+// the caller must have imported.
+func WaitForSQL(driverName string) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		db, err := sql.Open(driverName, c.connStr)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		for {
+			if err := db.PingContext(ctx); err == nil {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(waitInterval):
+			}
+		}
+	})
+}
+
+// WaitForMySQL returns a WaitStrategy that waits until the container greets
+// connections with a MySQL protocol handshake, without needing a driver.
+func WaitForMySQL() WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+		return waitUntilMySQLReady(ctx, net.JoinHostPort(c.Host(), c.port))
+	})
+}
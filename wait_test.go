@@ -0,0 +1,74 @@
+package sqltestutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPostgresLogReady(t *testing.T) {
+	t.Parallel()
+
+	ready := "LOG:  database system is ready to accept connections"
+	tests := []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{name: "empty"},
+		{
+			name:  "init server",
+			lines: []string{"The files belonging to this database system will be owned by user \"postgres\".", ready},
+		},
+		{
+			name:  "after init",
+			lines: []string{ready, "PostgreSQL init process complete; ready for start up.", ready},
+			want:  true,
+		},
+		{
+			name:  "existing data directory",
+			lines: []string{"PostgreSQL Database directory appears to contain a database; Skipping initialization", ready},
+			want:  true,
+		},
+		{
+			name:  "existing data directory starting",
+			lines: []string{"PostgreSQL Database directory appears to contain a database; Skipping initialization"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			done := postgresLogReady()
+			got := false
+			for _, line := range tt.lines {
+				got = done(line)
+			}
+			if got != tt.want {
+				t.Errorf("postgresLogReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForAll(t *testing.T) {
+	t.Parallel()
+
+	errNotReady := errors.New("not ready")
+	var calls []string
+	strategy := func(name string, err error) WaitStrategy {
+		return WaitStrategyFunc(func(ctx context.Context, c *SQLContainer) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+
+	err := WaitForAll(strategy("a", nil), strategy("b", errNotReady), strategy("c", nil)).
+		WaitUntilReady(context.Background(), &SQLContainer{})
+	if !errors.Is(err, errNotReady) {
+		t.Errorf("WaitUntilReady() error = %v, want %v", err, errNotReady)
+	}
+	if len(calls) != 2 || calls[0] != "a" || calls[1] != "b" {
+		t.Errorf("WaitUntilReady() called %v, want [a b]", calls)
+	}
+}
@@ -0,0 +1,135 @@
+package sqltestutil
+
+import (
+	"context"
+	"time"
+)
+
+// yugabyteStartupTimeout is the default StartupTimeout for YugabyteDB, which
+// starts a master and a tablet server before YSQL accepts connections.
+const yugabyteStartupTimeout = 2 * time.Minute
+
+// YugabyteContainer is a Docker container running a single-node YugabyteDB
+// cluster. Its YSQL API speaks the Postgres protocol, so it can be used with
+// the same drivers and helpers as a PostgresContainer.
+type YugabyteContainer struct {
+	id       string
+	user     string
+	password string
+	dbName   string
+	port     string
+	connStr  string
+
+	docker dockerConfig
+}
+
+// StartYugabyteContainer starts a new YugabyteDB Docker container from the
+// yugabytedb/yugabyte image and publishes its YSQL port. The version parameter
+// is the image tag, e.g. "2.20.1.0-b97". The options for the Docker client,
+// image pulls, the reaper and the host apply; of those configuring the
+// database only WithDBName, WithDBUser, WithDBPassword, WithSSLMode and
+// WithStartupTimeout do, and the user and database default to "yugabyte" with
+// a random password.
+//
+// The container is ready once a YSQL connection with the configured user
+// succeeds, which takes longer than for Postgres, so the startup timeout
+// defaults to two minutes.
+func StartYugabyteContainer(
+	ctx context.Context,
+	version string,
+	options ...Option,
+) (*YugabyteContainer, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &PostgresContainerConfig{
+		DBName:     "yugabyte",
+		DBUser:     "yugabyte",
+		DBPassword: password,
+		TimeZone:   "UTC",
+		SSLMode:    "disable",
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	cli, release, err := config.docker().open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	containerID, port, err := runContainer(ctx, cli, containerSpec{
+		image: "yugabytedb/yugabyte:" + version,
+		env: []string{
+			"YSQL_DB=" + config.DBName,
+			"YSQL_USER=" + config.DBUser,
+			"YSQL_PASSWORD=" + config.DBPassword,
+		},
+		cmd:    []string{"bin/yugabyted", "start", "--background=false"},
+		port:   "5433/tcp",
+		pull:   config.imagePull(),
+		reaper: config.Reaper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connStr := config.connectionString(containerHost(cli, config.HostOverride), port)
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.startupTimeout(yugabyteStartupTimeout))
+	defer cancel()
+
+	// yugabyted creates the user and database after YSQL starts listening, so
+	// wait for a connection that uses them
+	if err := waitUntilConnectable(waitCtx, connStr); err != nil {
+		_ = shutdownContainer(ctx, config.docker(), containerID, WithForce())
+		return nil, err
+	}
+
+	return &YugabyteContainer{
+		id:       containerID,
+		user:     config.DBUser,
+		password: config.DBPassword,
+		dbName:   config.DBName,
+		port:     port,
+		connStr:  connStr,
+		docker:   config.docker(),
+	}, nil
+}
+
+// ConnectionString returns a Postgres connection string that can be used to
+// connect to the YSQL API of the running YugabyteDB container.
+func (c *YugabyteContainer) ConnectionString() string {
+	return c.connStr
+}
+
+// ID returns the Docker container ID of the running YugabyteDB container.
+func (c *YugabyteContainer) ID() string {
+	return c.id
+}
+
+// User returns the name of the database user.
+func (c *YugabyteContainer) User() string {
+	return c.user
+}
+
+// Password returns the password of the database user.
+func (c *YugabyteContainer) Password() string {
+	return c.password
+}
+
+// Database returns the name of the database created on startup.
+func (c *YugabyteContainer) Database() string {
+	return c.dbName
+}
+
+// Shutdown cleans up the YugabyteDB container by stopping and removing it.
+func (c *YugabyteContainer) Shutdown(ctx context.Context, options ...ShutdownOption) error {
+	return shutdownContainer(ctx, c.docker, c.id, options...)
+}